@@ -0,0 +1,158 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// Batch collects requests to send to the server in a single round trip, as one JSON-RPC batch. Transports that cannot
+// send batches send the requests one at a time instead.
+//
+//	batch := client.Batch()
+//	london := batch.CallTool("weather", WeatherArgs{City: "London"})
+//	paris := batch.CallTool("weather", WeatherArgs{City: "Paris"})
+//	if err := batch.Send(ctx); err != nil {
+//		return err
+//	}
+//	response, err := london.Result()
+type Batch struct {
+	client   *Client
+	requests []protocol.BatchRequest
+	results  []batchResult
+	sent     bool
+}
+
+// batchResult receives the response to one request of a batch
+type batchResult interface {
+	resolve(response interface{}, err error)
+}
+
+// BatchResult is the outcome of one request of a Batch, available once Batch.Send has returned
+type BatchResult[T any] struct {
+	result *T
+	err    error
+	decode func(response json.RawMessage) (*T, error)
+	// Describes the request in errors
+	action string
+}
+
+// Result returns the response to the request, or the error it failed with
+func (r *BatchResult[T]) Result() (*T, error) {
+	return r.result, r.err
+}
+
+func (r *BatchResult[T]) resolve(response interface{}, err error) {
+	if err != nil {
+		r.err = errors.Wrap(err, "failed to "+r.action)
+		return
+	}
+	responseBytes, ok := response.(json.RawMessage)
+	if !ok {
+		r.err = errors.New("invalid response type")
+		return
+	}
+	r.result, r.err = r.decode(responseBytes)
+}
+
+// Batch starts a new batch of requests
+func (c *Client) Batch() *Batch {
+	return &Batch{client: c}
+}
+
+// CallTool adds a call to a tool with the provided arguments to the batch
+func (b *Batch) CallTool(name string, arguments any) *BatchResult[ToolResponse] {
+	result := &BatchResult[ToolResponse]{action: "call tool", decode: decodeAs[ToolResponse]("tool response")}
+	argumentsJson, err := json.Marshal(arguments)
+	if err != nil {
+		result.err = errors.Wrap(err, "failed to marshal arguments")
+		return result
+	}
+	b.add("tools/call", baseCallToolRequestParams{Name: name, Arguments: argumentsJson}, result)
+	return result
+}
+
+// ListTools adds a request for the list of available tools to the batch
+func (b *Batch) ListTools(cursor *string) *BatchResult[ToolsResponse] {
+	result := &BatchResult[ToolsResponse]{action: "list tools", decode: decodeAs[ToolsResponse]("tools response")}
+	b.add("tools/list", map[string]interface{}{"cursor": cursor}, result)
+	return result
+}
+
+// GetPrompt adds a request for a specific prompt to the batch
+func (b *Batch) GetPrompt(name string, arguments any) *BatchResult[PromptResponse] {
+	result := &BatchResult[PromptResponse]{action: "get prompt", decode: decodeAs[PromptResponse]("prompt response")}
+	argumentsJson, err := json.Marshal(arguments)
+	if err != nil {
+		result.err = errors.Wrap(err, "failed to marshal arguments")
+		return result
+	}
+	b.add("prompts/get", baseGetPromptRequestParamsArguments{Name: name, Arguments: argumentsJson}, result)
+	return result
+}
+
+// ListPrompts adds a request for the list of available prompts to the batch
+func (b *Batch) ListPrompts(cursor *string) *BatchResult[ListPromptsResponse] {
+	result := &BatchResult[ListPromptsResponse]{action: "list prompts", decode: decodeAs[ListPromptsResponse]("prompts response")}
+	b.add("prompts/list", map[string]interface{}{"cursor": cursor}, result)
+	return result
+}
+
+// ReadResource adds a request to read a specific resource to the batch
+func (b *Batch) ReadResource(uri string) *BatchResult[ResourceResponse] {
+	result := &BatchResult[ResourceResponse]{action: "read resource", decode: decodeAs[ResourceResponse]("resource response")}
+	b.add("resources/read", readResourceRequestParams{Uri: uri}, result)
+	return result
+}
+
+// ListResources adds a request for the list of available resources to the batch
+func (b *Batch) ListResources(cursor *string) *BatchResult[ListResourcesResponse] {
+	result := &BatchResult[ListResourcesResponse]{action: "list resources", decode: decodeAs[ListResourcesResponse]("resources response")}
+	b.add("resources/list", map[string]interface{}{"cursor": cursor}, result)
+	return result
+}
+
+// Send sends every request of the batch and waits for their responses, which are then available from the results.
+// It returns an error if the batch could not be sent, in which case every result fails with it too.
+func (b *Batch) Send(ctx context.Context) error {
+	if !b.client.initialized {
+		return errors.New("client not initialized")
+	}
+	if b.sent {
+		return errors.New("batch already sent")
+	}
+	b.sent = true
+	if len(b.requests) == 0 {
+		return nil
+	}
+
+	responses, err := b.client.protocol.RequestBatch(ctx, b.requests, nil)
+	if err != nil {
+		for _, result := range b.results {
+			result.resolve(nil, err)
+		}
+		return errors.Wrap(err, "failed to send batch")
+	}
+	for i, response := range responses {
+		b.results[i].resolve(response.Response, response.Err)
+	}
+	return nil
+}
+
+func (b *Batch) add(method string, params interface{}, result batchResult) {
+	result.resolve(nil, errors.New("batch not sent"))
+	b.requests = append(b.requests, protocol.BatchRequest{Method: method, Params: params})
+	b.results = append(b.results, result)
+}
+
+func decodeAs[T any](description string) func(response json.RawMessage) (*T, error) {
+	return func(response json.RawMessage) (*T, error) {
+		var decoded T
+		if err := json.Unmarshal(response, &decoded); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal "+description)
+		}
+		return &decoded, nil
+	}
+}
@@ -0,0 +1,114 @@
+package mcp_golang
+
+import (
+	"context"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/transport/http"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func registerEcho(t *testing.T, s *Server) {
+	err := s.RegisterTool("echo", "Echo back the input message", func(args echoArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(args.Message)), nil
+	})
+	require.NoError(t, err)
+}
+
+// sendEchoBatch sends a batch of echo calls and a tool listing, and checks every result
+func sendEchoBatch(t *testing.T, client *Client) {
+	batch := client.Batch()
+	var calls []*BatchResult[ToolResponse]
+	for i := 0; i < 5; i++ {
+		calls = append(calls, batch.CallTool("echo", echoArgs{Message: fmt.Sprintf("message %d", i)}))
+	}
+	tools := batch.ListTools(nil)
+	missing := batch.CallTool("missing", echoArgs{Message: "hello"})
+
+	_, err := tools.Result()
+	assert.EqualError(t, err, "failed to list tools: batch not sent")
+
+	require.NoError(t, batch.Send(context.Background()))
+	for i, call := range calls {
+		response, err := call.Result()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("message %d", i), response.Content[0].TextContent.Text)
+	}
+	listed, err := tools.Result()
+	require.NoError(t, err)
+	require.Len(t, listed.Tools, 1)
+	_, err = missing.Result()
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+	assert.Equal(t, ErrorCodeInvalidParams, rpcErr.Code)
+
+	assert.EqualError(t, batch.Send(context.Background()), "batch already sent")
+}
+
+func TestClientBatch(t *testing.T) {
+	t.Run("one round trip", func(t *testing.T) {
+		serverTransport := http.NewHTTPHandler()
+		server := NewServer(serverTransport)
+		registerEcho(t, server)
+		require.NoError(t, server.Serve())
+
+		var posts atomic.Int32
+		httpServer := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			posts.Add(1)
+			serverTransport.ServeHTTP(w, r)
+		}))
+		defer httpServer.Close()
+
+		client := NewClient(http.NewHTTPClientTransport("/").WithBaseURL(httpServer.URL))
+		_, err := client.Initialize(context.Background())
+		require.NoError(t, err)
+		posts.Store(0)
+
+		sendEchoBatch(t, client)
+		assert.Equal(t, int32(1), posts.Load())
+	})
+
+	t.Run("transports without batches send requests one at a time", func(t *testing.T) {
+		client := newInMemoryClient(t, func(s *Server) { registerEcho(t, s) })
+		sendEchoBatch(t, client)
+	})
+
+	t.Run("resources", func(t *testing.T) {
+		client := newInMemoryClient(t, func(s *Server) {
+			require.NoError(t, s.RegisterResource("test://text", "text", "A text resource", "text/plain", func() (*ResourceResponse, error) {
+				return NewResourceResponse(NewTextEmbeddedResource("test://text", "hello", "text/plain")), nil
+			}))
+			require.NoError(t, s.RegisterResource("test://blob", "blob", "A binary resource", "application/octet-stream", func() (*ResourceResponse, error) {
+				return NewResourceResponse(NewBlobEmbeddedResource("test://blob", "aGVsbG8=", "application/octet-stream")), nil
+			}))
+		})
+		batch := client.Batch()
+		text := batch.ReadResource("test://text")
+		blob := batch.ReadResource("test://blob")
+		require.NoError(t, batch.Send(context.Background()))
+
+		response, err := text.Result()
+		require.NoError(t, err)
+		require.Len(t, response.Contents, 1)
+		require.NotNil(t, response.Contents[0].TextResourceContents)
+		assert.Equal(t, "hello", response.Contents[0].TextResourceContents.Text)
+		assert.Equal(t, "test://text", response.Contents[0].TextResourceContents.Uri)
+
+		response, err = blob.Result()
+		require.NoError(t, err)
+		require.Len(t, response.Contents, 1)
+		require.NotNil(t, response.Contents[0].BlobResourceContents)
+		assert.Equal(t, "aGVsbG8=", response.Contents[0].BlobResourceContents.Blob)
+	})
+
+	t.Run("uninitialized client", func(t *testing.T) {
+		client := NewClient(http.NewHTTPClientTransport("/"))
+		assert.EqualError(t, client.Batch().Send(context.Background()), "client not initialized")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/metoro-io/mcp-golang/internal/datastructures"
 	"github.com/metoro-io/mcp-golang/internal/protocol"
 	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/pkg/errors"
@@ -14,15 +15,22 @@ type Client struct {
 	transport    transport.Transport
 	protocol     *protocol.Protocol
 	capabilities *ServerCapabilities
-	initialized  bool
+	// The capabilities advertised to the server in the initialize request
+	clientCapabilities ClientCapabilities
+	initialized        bool
+	// Callbacks of the resources subscribed to, by uri
+	resourceSubscriptions *datastructures.SyncMap[string, func(uri string)]
 }
 
 // NewClient creates a new MCP client with the specified transport
 func NewClient(transport transport.Transport) *Client {
-	return &Client{
-		transport: transport,
-		protocol:  protocol.NewProtocol(nil),
+	client := &Client{
+		transport:             transport,
+		protocol:              protocol.NewProtocol(nil),
+		resourceSubscriptions: new(datastructures.SyncMap[string, func(uri string)]),
 	}
+	client.protocol.SetNotificationHandler("notifications/resources/updated", client.handleResourceUpdated)
+	return client
 }
 
 // Initialize connects to the server and retrieves its capabilities
@@ -37,7 +45,9 @@ func (c *Client) Initialize(ctx context.Context) (*InitializeResponse, error) {
 	}
 
 	// Make initialize request to server
-	response, err := c.protocol.Request(ctx, "initialize", map[string]interface{}{}, nil)
+	response, err := c.protocol.Request(ctx, "initialize", map[string]interface{}{
+		"capabilities": c.clientCapabilities,
+	}, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to initialize")
 	}
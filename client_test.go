@@ -0,0 +1,392 @@
+package mcp_golang
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/metoro-io/mcp-golang/transport/http"
+	"github.com/metoro-io/mcp-golang/transport/inmemory"
+	"github.com/metoro-io/mcp-golang/transport/socket"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type echoArgs struct {
+	Message string `json:"message" jsonschema:"required,description=Message to echo back"`
+}
+
+// newInMemoryClient connects a client to server in the same process
+func newInMemoryClient(t *testing.T, server func(s *Server)) *Client {
+	clientTransport, serverTransport := inmemory.NewPair()
+	s := NewServer(serverTransport)
+	server(s)
+	require.NoError(t, s.Serve())
+
+	client := NewClient(clientTransport)
+	t.Cleanup(func() { clientTransport.Close() })
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+	return client
+}
+
+func TestClientServerInMemory(t *testing.T) {
+	client := newInMemoryClient(t, func(s *Server) {
+		err := s.RegisterTool("echo", "Echo back the input message", func(args echoArgs) (*ToolResponse, error) {
+			return NewToolResponse(NewTextContent(args.Message)), nil
+		})
+		require.NoError(t, err)
+	})
+
+	tools, err := client.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	assert.Equal(t, "echo", tools.Tools[0].Name)
+
+	response, err := client.CallTool(context.Background(), "echo", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	require.Len(t, response.Content, 1)
+	require.NotNil(t, response.Content[0].TextContent)
+	assert.Equal(t, "hello", response.Content[0].TextContent.Text)
+}
+
+func TestClientRPCErrors(t *testing.T) {
+	client := newInMemoryClient(t, func(s *Server) {
+		registerEcho(t, s)
+		require.NoError(t, s.RegisterTool("weather", "Returns the weather in a city", func(args echoArgs) (*ToolResponse, error) {
+			if args.Message == "Atlantis" {
+				return nil, errors.Wrap(NewRPCError(-32001, "unknown city").WithData(args.Message), "failed to look up city")
+			}
+			return nil, errors.New("weather service is down")
+		}))
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		_, err := client.CallTool(context.Background(), "missing", echoArgs{Message: "hi"})
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+		assert.Equal(t, ErrorCodeInvalidParams, rpcErr.Code)
+		assert.Equal(t, "unknown tool: missing", rpcErr.Message)
+	})
+
+	t.Run("unknown prompt", func(t *testing.T) {
+		_, err := client.GetPrompt(context.Background(), "missing", echoArgs{Message: "hi"})
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+		assert.Equal(t, ErrorCodeInvalidParams, rpcErr.Code)
+	})
+
+	t.Run("handlers can return an RPCError", func(t *testing.T) {
+		_, err := client.CallTool(context.Background(), "weather", echoArgs{Message: "Atlantis"})
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+		assert.Equal(t, -32001, rpcErr.Code)
+		assert.Equal(t, "unknown city", rpcErr.Message)
+		assert.Equal(t, "Atlantis", rpcErr.Data)
+	})
+
+	t.Run("other handler errors are error results", func(t *testing.T) {
+		response, err := client.CallTool(context.Background(), "weather", echoArgs{Message: "London"})
+		require.NoError(t, err)
+		assert.Contains(t, response.Content[0].TextContent.Text, "weather service is down")
+	})
+}
+
+func TestServerLogger(t *testing.T) {
+	var logs bytes.Buffer
+	server := NewServer(nil, WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))))
+	registerEcho(t, server)
+	clientTransport, serverTransport := inmemory.NewPair()
+	require.NoError(t, server.ServeSession(serverTransport))
+	defer clientTransport.Close()
+
+	client := NewClient(clientTransport)
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+	_, err = client.CallTool(context.Background(), "missing", echoArgs{Message: "hello"})
+	require.Error(t, err)
+	require.NoError(t, server.Shutdown(context.Background()))
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, "request failed", record["msg"])
+	assert.Equal(t, "tools/call", record["method"])
+	assert.Equal(t, "1", record["session"])
+	assert.Equal(t, "RPC error -32602: unknown tool: missing", record["error"])
+}
+
+func TestServerMiddleware(t *testing.T) {
+	server := NewServer(nil)
+	registerEcho(t, server)
+	var calls []string
+	server.Use(func(next Handler) Handler {
+		return func(ctx context.Context, request *Request) (transport.JsonRpcBody, error) {
+			if request.Method == "tools/call" && strings.Contains(string(request.Params), "secret") {
+				return nil, NewRPCError(-32001, "forbidden")
+			}
+			result, err := next(ctx, request)
+			calls = append(calls, fmt.Sprintf("%s %s %t", request.Session, request.Method, err == nil))
+			return result, err
+		}
+	})
+	clientTransport, serverTransport := inmemory.NewPair()
+	require.NoError(t, server.ServeSession(serverTransport))
+	defer clientTransport.Close()
+
+	client := NewClient(clientTransport)
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+	_, err = client.CallTool(context.Background(), "echo", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+
+	_, err = client.CallTool(context.Background(), "echo", echoArgs{Message: "secret"})
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+	assert.Equal(t, -32001, rpcErr.Code)
+
+	assert.Equal(t, []string{"1 initialize true", "1 tools/call true"}, calls)
+}
+
+func TestServeListener(t *testing.T) {
+	listener, err := socket.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := NewServer(nil)
+	err = server.RegisterTool("echo", "Echo back the input message", func(args echoArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(args.Message)), nil
+	})
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- server.ServeListener(listener) }()
+
+	// Every client gets its own session, so the request ids of the clients can overlap
+	var clients []*Client
+	for i := 0; i < 2; i++ {
+		clientTransport := socket.NewSocketClientTransport("tcp", listener.Addr().String())
+		client := NewClient(clientTransport)
+		_, err := client.Initialize(context.Background())
+		require.NoError(t, err)
+		defer clientTransport.Close()
+		clients = append(clients, client)
+	}
+	for i, client := range clients {
+		message := fmt.Sprintf("hello from client %d", i)
+		response, err := client.CallTool(context.Background(), "echo", echoArgs{Message: message})
+		require.NoError(t, err)
+		assert.Equal(t, message, response.Content[0].TextContent.Text)
+	}
+
+	// List changed notifications go to every session
+	notifications := make(chan string, 1)
+	observer := socket.NewSocketClientTransport("tcp", listener.Addr().String())
+	observer.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type == transport.BaseMessageTypeJSONRPCNotificationType {
+			notifications <- message.JsonRpcNotification.Method
+		}
+	})
+	require.NoError(t, observer.Start(context.Background()))
+	assert.Eventually(t, func() bool { return countSessions(server) == 3 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, server.DeregisterTool("echo"))
+	assert.Equal(t, "notifications/tools/list_changed", <-notifications)
+
+	// Closed connections end their session
+	require.NoError(t, observer.Close())
+	assert.Eventually(t, func() bool { return countSessions(server) == 2 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, listener.Close())
+	assert.NoError(t, <-served)
+}
+
+func TestServeListenerStreamableHTTP(t *testing.T) {
+	listener := http.NewStreamableHTTPTransport("/mcp")
+	server := NewServer(nil)
+	err := server.RegisterTool("whoami", "Returns the session id", func(ctx context.Context, args echoArgs) (*ToolResponse, error) {
+		sessionId, _ := http.SessionIDFromContext(ctx)
+		return NewToolResponse(NewTextContent(sessionId)), nil
+	})
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- server.ServeListener(listener) }()
+	httpServer := httptest.NewServer(listener)
+	defer httpServer.Close()
+	assert.Eventually(t, func() bool { return countSessions(server) == 0 && listenerCount(server) == 1 }, time.Second, time.Millisecond)
+
+	// Each client is served in its own session with its own protocol state
+	for i := 0; i < 2; i++ {
+		clientTransport := http.NewStreamableHTTPClientTransport(httpServer.URL)
+		client := NewClient(clientTransport)
+		_, err := client.Initialize(context.Background())
+		require.NoError(t, err)
+		defer clientTransport.Close()
+
+		response, err := client.CallTool(context.Background(), "whoami", echoArgs{Message: "hi"})
+		require.NoError(t, err)
+		assert.Equal(t, clientTransport.SessionID(), response.Content[0].TextContent.Text)
+	}
+	assert.Equal(t, 2, countSessions(server))
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+	assert.Equal(t, 0, countSessions(server))
+}
+
+func listenerCount(s *Server) int {
+	n := 0
+	s.listeners.Range(func(transport.Listener, struct{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestServerShutdown(t *testing.T) {
+	t.Run("in-flight requests finish", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var server *Server
+		client := newInMemoryClient(t, func(s *Server) {
+			server = s
+			require.NoError(t, s.RegisterTool("slow", "Waits to be released", func(args echoArgs) (*ToolResponse, error) {
+				close(started)
+				<-release
+				return NewToolResponse(NewTextContent(args.Message)), nil
+			}))
+			require.NoError(t, s.RegisterTool("echo", "Echo back the input message", func(args echoArgs) (*ToolResponse, error) {
+				return NewToolResponse(NewTextContent(args.Message)), nil
+			}))
+		})
+
+		slowResult := make(chan *ToolResponse, 1)
+		go func() {
+			response, err := client.CallTool(context.Background(), "slow", echoArgs{Message: "done"})
+			assert.NoError(t, err)
+			slowResult <- response
+		}()
+		<-started
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- server.Shutdown(context.Background()) }()
+
+		// New requests are rejected while the slow one is still running
+		assert.Eventually(t, func() bool {
+			_, err := client.CallTool(context.Background(), "echo", echoArgs{Message: "late"})
+			return err != nil && strings.Contains(err.Error(), "shutting down")
+		}, time.Second, 10*time.Millisecond)
+
+		close(release)
+		assert.Equal(t, "done", (<-slowResult).Content[0].TextContent.Text)
+		assert.NoError(t, <-shutdown)
+		assert.Error(t, server.Serve())
+	})
+
+	t.Run("handlers are cancelled at the deadline", func(t *testing.T) {
+		started := make(chan struct{})
+		handlerErr := make(chan error, 1)
+		var server *Server
+		client := newInMemoryClient(t, func(s *Server) {
+			server = s
+			require.NoError(t, s.RegisterTool("stuck", "Runs until cancelled", func(ctx context.Context, args echoArgs) (*ToolResponse, error) {
+				close(started)
+				<-ctx.Done()
+				handlerErr <- ctx.Err()
+				return NewToolResponse(NewTextContent("too late")), nil
+			}))
+		})
+
+		callErr := make(chan error, 1)
+		go func() {
+			_, err := client.CallTool(context.Background(), "stuck", echoArgs{Message: "hello"})
+			callErr <- err
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+		assert.ErrorIs(t, <-handlerErr, context.Canceled)
+		err := <-callErr
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "server is shutting down")
+	})
+
+	t.Run("http transport", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		server := NewServer(http.NewHTTPTransport("/mcp").WithAddr(addr))
+		require.NoError(t, server.RegisterTool("slow", "Waits to be released", func(args echoArgs) (*ToolResponse, error) {
+			close(started)
+			<-release
+			return NewToolResponse(NewTextContent(args.Message)), nil
+		}))
+		served := make(chan error, 1)
+		go func() { served <- server.Serve() }()
+		select {
+		case err := <-served:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Serve blocked on the HTTP transport")
+		}
+
+		client := NewClient(http.NewHTTPClientTransport("/mcp").WithBaseURL("http://" + addr))
+		_, err = client.Initialize(context.Background())
+		require.NoError(t, err)
+		slowResult := make(chan *ToolResponse, 1)
+		go func() {
+			response, err := client.CallTool(context.Background(), "slow", echoArgs{Message: "done"})
+			assert.NoError(t, err)
+			slowResult <- response
+		}()
+		<-started
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- server.Shutdown(context.Background()) }()
+		close(release)
+		assert.Equal(t, "done", (<-slowResult).Content[0].TextContent.Text)
+		assert.NoError(t, <-shutdown)
+
+		// The transport no longer accepts requests
+		_, err = client.CallTool(context.Background(), "slow", echoArgs{Message: "late"})
+		assert.Error(t, err)
+	})
+
+	t.Run("registering during shutdown is safe", func(t *testing.T) {
+		var server *Server
+		newInMemoryClient(t, func(s *Server) { server = s })
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 500; i++ {
+				_ = server.RegisterTool(fmt.Sprintf("tool-%d", i), "Registered during shutdown", func(args echoArgs) (*ToolResponse, error) {
+					return NewToolResponse(NewTextContent(args.Message)), nil
+				})
+			}
+		}()
+		assert.NoError(t, server.Shutdown(context.Background()))
+		<-done
+	})
+}
+
+func countSessions(s *Server) int {
+	n := 0
+	s.sessions.Range(func(*serverSession, struct{}) bool {
+		n++
+		return true
+	})
+	return n
+}
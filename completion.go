@@ -0,0 +1,199 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// maxCompletionValues is the maximum number of values of a completion response, as set by the spec
+const maxCompletionValues = 100
+
+// CompletionProvider suggests values for an argument of a prompt or a variable of a resource template. Partial is the
+// value typed so far, and context holds the values the client already chose for the other arguments, when it sends
+// them. Only the first 100 values are sent to the client, along with the total number of values.
+type CompletionProvider func(ctx context.Context, partial string, context map[string]string) ([]string, error)
+
+// Completions maps the names of the arguments of a prompt, or of the variables of a resource template, to the
+// providers that complete them. Names are matched like encoding/json does, preferring an exact match to a
+// case-insensitive one:
+//
+//	server.RegisterPrompt("review", "Review code", handler, mcp_golang.Completions{
+//		"language": func(ctx context.Context, partial string, _ map[string]string) ([]string, error) {
+//			return languagesWithPrefix(partial), nil
+//		},
+//	})
+type Completions map[string]CompletionProvider
+
+// provider returns the provider of an argument
+func (c Completions) provider(argument string) (CompletionProvider, bool) {
+	if provider, ok := c[argument]; ok {
+		return provider, true
+	}
+	for name, provider := range c {
+		if strings.EqualFold(name, argument) {
+			return provider, true
+		}
+	}
+	return nil, false
+}
+
+// mergeCompletions merges the completions given at registration, checking that they complete known arguments
+func mergeCompletions(arguments []string, completions []Completions) (Completions, error) {
+	merged := make(Completions)
+	for _, c := range completions {
+		for argument, provider := range c {
+			known := false
+			for _, name := range arguments {
+				known = known || strings.EqualFold(name, argument)
+			}
+			if !known {
+				return nil, fmt.Errorf("unknown argument %s", argument)
+			}
+			if provider == nil {
+				return nil, fmt.Errorf("nil completion provider for argument %s", argument)
+			}
+			merged[argument] = provider
+		}
+	}
+	return merged, nil
+}
+
+// CompletionReference is what a completion request completes: a prompt or a resource template
+type CompletionReference struct {
+	// ref/prompt or ref/resource
+	Type string `json:"type" yaml:"type" mapstructure:"type"`
+	// The name of the prompt, for ref/prompt
+	Name string `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name,omitempty"`
+	// The URI template of the resource template, for ref/resource
+	Uri string `json:"uri,omitempty" yaml:"uri,omitempty" mapstructure:"uri,omitempty"`
+}
+
+// NewPromptReference references the prompt with the given name in a completion request
+func NewPromptReference(name string) CompletionReference {
+	return CompletionReference{Type: "ref/prompt", Name: name}
+}
+
+// NewResourceTemplateReference references the resource template with the given URI template in a completion request
+func NewResourceTemplateReference(uriTemplate string) CompletionReference {
+	return CompletionReference{Type: "ref/resource", Uri: uriTemplate}
+}
+
+type completionArgument struct {
+	Name  string `json:"name" yaml:"name" mapstructure:"name"`
+	Value string `json:"value" yaml:"value" mapstructure:"value"`
+}
+
+type completionContext struct {
+	Arguments map[string]string `json:"arguments,omitempty" yaml:"arguments,omitempty" mapstructure:"arguments,omitempty"`
+}
+
+type completeRequestParams struct {
+	Ref      CompletionReference `json:"ref" yaml:"ref" mapstructure:"ref"`
+	Argument completionArgument  `json:"argument" yaml:"argument" mapstructure:"argument"`
+	Context  *completionContext  `json:"context,omitempty" yaml:"context,omitempty" mapstructure:"context,omitempty"`
+}
+
+// CompletionResult holds the values suggested for an argument
+type CompletionResult struct {
+	// At most 100 values
+	Values []string `json:"values" yaml:"values" mapstructure:"values"`
+	// The total number of values, which can exceed the number of values sent
+	Total *int `json:"total,omitempty" yaml:"total,omitempty" mapstructure:"total,omitempty"`
+	// Whether there are more values than the ones sent
+	HasMore *bool `json:"hasMore,omitempty" yaml:"hasMore,omitempty" mapstructure:"hasMore,omitempty"`
+}
+
+// The server's response to a completion/complete request
+type CompleteResponse struct {
+	Completion CompletionResult `json:"completion" yaml:"completion" mapstructure:"completion"`
+}
+
+func (s *Server) handleComplete(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	var params completeRequestParams
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
+	}
+
+	var completions Completions
+	switch params.Ref.Type {
+	case "ref/prompt":
+		p, ok := s.prompts.Load(params.Ref.Name)
+		if !ok {
+			return nil, NewRPCError(ErrorCodeInvalidParams, fmt.Sprintf("unknown prompt: %s", params.Ref.Name))
+		}
+		completions = p.completions
+	case "ref/resource":
+		t, ok := s.resourceTemplates.Load(params.Ref.Uri)
+		if !ok {
+			return nil, NewRPCError(ErrorCodeInvalidParams, fmt.Sprintf("unknown resource template: %s", params.Ref.Uri))
+		}
+		completions = t.completions
+	default:
+		return nil, NewRPCError(ErrorCodeInvalidParams, fmt.Sprintf("unknown reference type: %s", params.Ref.Type))
+	}
+
+	// Arguments without a provider have no suggestions
+	values := make([]string, 0)
+	if provider, ok := completions.provider(params.Argument.Name); ok {
+		var arguments map[string]string
+		if params.Context != nil {
+			arguments = params.Context.Arguments
+		}
+		if arguments == nil {
+			arguments = map[string]string{}
+		}
+		suggested, err := provider(ctx, params.Argument.Value, arguments)
+		if err != nil {
+			return nil, err
+		}
+		if suggested != nil {
+			values = suggested
+		}
+	}
+
+	total := len(values)
+	hasMore := total > maxCompletionValues
+	if hasMore {
+		values = values[:maxCompletionValues]
+	}
+	return CompleteResponse{
+		Completion: CompletionResult{Values: values, Total: &total, HasMore: &hasMore},
+	}, nil
+}
+
+// Complete asks the server for the values of an argument of a prompt, or of a variable of a resource template, that
+// complete the partial value. Context holds the values already chosen for the other arguments, it can be nil.
+func (c *Client) Complete(ctx context.Context, ref CompletionReference, argument string, partial string, context map[string]string) (*CompletionResult, error) {
+	if !c.initialized {
+		return nil, errors.New("client not initialized")
+	}
+
+	params := completeRequestParams{
+		Ref:      ref,
+		Argument: completionArgument{Name: argument, Value: partial},
+	}
+	if context != nil {
+		params.Context = &completionContext{Arguments: context}
+	}
+	response, err := c.protocol.Request(ctx, "completion/complete", params, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to complete argument")
+	}
+
+	responseBytes, ok := response.(json.RawMessage)
+	if !ok {
+		return nil, errors.New("invalid response type")
+	}
+	var completeResponse CompleteResponse
+	if err := json.Unmarshal(responseBytes, &completeResponse); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal completion response")
+	}
+
+	return &completeResponse.Completion, nil
+}
@@ -0,0 +1,92 @@
+package mcp_golang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/transport/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletions(t *testing.T) {
+	server := NewServer(nil)
+	ctx := context.Background()
+
+	type reviewArgs struct {
+		Language string `json:"language"`
+		Style    string `json:"style"`
+	}
+	languages := []string{"go", "haskell", "java", "javascript", "python"}
+	require.NoError(t, server.RegisterPrompt("review", "Review code", func(args reviewArgs) (*PromptResponse, error) {
+		return NewPromptResponse("review", NewPromptMessage(NewTextContent("review"), RoleUser)), nil
+	}, Completions{
+		"language": func(ctx context.Context, partial string, _ map[string]string) ([]string, error) {
+			var values []string
+			for _, language := range languages {
+				if strings.HasPrefix(language, partial) {
+					values = append(values, language)
+				}
+			}
+			return values, nil
+		},
+	}))
+	require.NoError(t, server.RegisterResourceTemplateHandler("db://{table}/{id}", "row", "A row", "application/json", nil, Completions{
+		"id": func(ctx context.Context, partial string, context map[string]string) ([]string, error) {
+			if context["table"] == "" {
+				return nil, NewRPCError(ErrorCodeInvalidParams, "table is required")
+			}
+			values := make([]string, 250)
+			for i := range values {
+				values[i] = fmt.Sprintf("%s-%d", context["table"], i)
+			}
+			return values, nil
+		},
+	}))
+
+	clientTransport, serverTransport := inmemory.NewPair()
+	defer clientTransport.Close()
+	require.NoError(t, server.ServeSession(serverTransport))
+	client := NewClient(clientTransport)
+	initialized, err := client.Initialize(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, initialized.Capabilities.Completions)
+
+	result, err := client.Complete(ctx, NewPromptReference("review"), "language", "ja", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"java", "javascript"}, result.Values)
+	assert.Equal(t, 2, *result.Total)
+	assert.False(t, *result.HasMore)
+
+	// An argument without a provider has no suggestions
+	result, err = client.Complete(ctx, NewPromptReference("review"), "style", "", nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Values)
+
+	// Values are capped at 100
+	result, err = client.Complete(ctx, NewResourceTemplateReference("db://{table}/{id}"), "id", "", map[string]string{"table": "users"})
+	require.NoError(t, err)
+	assert.Len(t, result.Values, 100)
+	assert.Equal(t, "users-0", result.Values[0])
+	assert.Equal(t, 250, *result.Total)
+	assert.True(t, *result.HasMore)
+
+	for _, ref := range []CompletionReference{
+		NewResourceTemplateReference("db://{table}/{id}"),
+		NewPromptReference("missing"),
+		{Type: "ref/tool", Name: "review"},
+	} {
+		_, err = client.Complete(ctx, ref, "id", "", nil)
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr), ref)
+		assert.Equal(t, ErrorCodeInvalidParams, rpcErr.Code, ref)
+	}
+
+	// Completions must complete known arguments
+	assert.Error(t, server.RegisterResourceTemplateHandler("db://{table}", "table", "", "", nil, Completions{
+		"id": func(context.Context, string, map[string]string) ([]string, error) { return nil, nil },
+	}))
+}
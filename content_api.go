@@ -95,6 +95,24 @@ func (c EmbeddedResource) MarshalJSON() ([]byte, error) {
 	}
 }
 
+// Custom JSON unmarshaling for EmbeddedResource, contents with a blob are binary and the others text
+func (c *EmbeddedResource) UnmarshalJSON(b []byte) error {
+	var contents struct {
+		Blob *string `json:"blob"`
+	}
+	if err := json.Unmarshal(b, &contents); err != nil {
+		return err
+	}
+	if contents.Blob != nil {
+		c.EmbeddedResourceType = embeddedResourceTypeBlob
+		c.BlobResourceContents = &BlobResourceContents{}
+		return json.Unmarshal(b, c.BlobResourceContents)
+	}
+	c.EmbeddedResourceType = embeddedResourceTypeText
+	c.TextResourceContents = &TextResourceContents{}
+	return json.Unmarshal(b, c.TextResourceContents)
+}
+
 type ContentType string
 
 const (
@@ -117,6 +135,8 @@ func (c *Content) UnmarshalJSON(b []byte) error {
 		Type             ContentType       `json:"type" yaml:"type" mapstructure:"type"`
 		Text             *string           `json:"text" yaml:"text" mapstructure:"text"`
 		Image            *string           `json:"image" yaml:"image" mapstructure:"image"`
+		Data             string            `json:"data" yaml:"data" mapstructure:"data"`
+		MimeType         string            `json:"mimeType" yaml:"mimeType" mapstructure:"mimeType"`
 		Annotations      *Annotations      `json:"annotations" yaml:"annotations" mapstructure:"annotations"`
 		EmbeddedResource *EmbeddedResource `json:"resource" yaml:"resource" mapstructure:"resource"`
 	}
@@ -139,6 +159,8 @@ func (c *Content) UnmarshalJSON(b []byte) error {
 	switch c.Type {
 	case ContentTypeText:
 		c.TextContent = &TextContent{Text: *tw.Text}
+	case ContentTypeImage:
+		c.ImageContent = &ImageContent{Data: tw.Data, MimeType: tw.MimeType}
 	default:
 		return fmt.Errorf("unknown content type: %s", c.Type)
 	}
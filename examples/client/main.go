@@ -1,33 +1,18 @@
 package main
 
 import (
-	"log"
-	"os/exec"
-
 	"context"
+	"log"
 
 	mcp_golang "github.com/metoro-io/mcp-golang"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 )
 
 func main() {
-	// Start the server process
-	cmd := exec.Command("go", "run", "./server/main.go")
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		log.Fatalf("Failed to get stdin pipe: %v", err)
-	}
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		log.Fatalf("Failed to get stdout pipe: %v", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
-	defer cmd.Process.Kill()
+	// Start the server process and connect to it over its stdin and stdout
+	clientTransport := stdio.NewStdioClientTransport("go", []string{"run", "./server/main.go"}, nil, "")
+	defer clientTransport.Close()
 
-	clientTransport := stdio.NewStdioServerTransportWithIO(stdout, stdin)
 	client := mcp_golang.NewClient(clientTransport)
 
 	if _, err := client.Initialize(context.Background()); err != nil {
@@ -31,5 +31,10 @@ func main() {
 
 	// Start the server
 	log.Println("Starting HTTP server on :8081...")
-	server.Serve()
+	if err := server.Serve(); err != nil {
+		panic(err)
+	}
+
+	// Keep the server running
+	select {}
 }
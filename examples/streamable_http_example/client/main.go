@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport/http"
+)
+
+func main() {
+	// Create a Streamable HTTP transport that connects to the server
+	transport := http.NewStreamableHTTPClientTransport("http://localhost:8081/mcp")
+	defer transport.Close()
+
+	// Create a new client with the transport
+	client := mcp_golang.NewClient(transport)
+
+	// Initialize the client, this also starts the session
+	if _, err := client.Initialize(context.Background()); err != nil {
+		log.Fatalf("Failed to initialize client: %v", err)
+	}
+	log.Printf("Initialized session %s", transport.SessionID())
+
+	// List available tools
+	tools, err := client.ListTools(context.Background(), nil)
+	if err != nil {
+		log.Fatalf("Failed to list tools: %v", err)
+	}
+	for _, tool := range tools.Tools {
+		desc := ""
+		if tool.Description != nil {
+			desc = *tool.Description
+		}
+		log.Printf("Tool: %s. Description: %s", tool.Name, desc)
+	}
+
+	// Call the time tool
+	response, err := client.CallTool(context.Background(), "time", map[string]interface{}{
+		"format": time.RFC1123,
+	})
+	if err != nil {
+		log.Fatalf("Failed to call time tool: %v", err)
+	}
+	if len(response.Content) > 0 && response.Content[0].TextContent != nil {
+		log.Printf("Time: %s", response.Content[0].TextContent.Text)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport/http"
+)
+
+// TimeArgs defines the arguments for the time tool
+type TimeArgs struct {
+	Format string `json:"format" jsonschema:"description=The time format to use"`
+}
+
+func main() {
+	// Create a Streamable HTTP transport that listens on the /mcp endpoint
+	transport := http.NewStreamableHTTPTransport("/mcp").WithAddr(":8081")
+
+	// Create a new server with the transport
+	server := mcp_golang.NewServer(transport, mcp_golang.WithName("mcp-golang-streamable-http-example"), mcp_golang.WithVersion("0.0.1"))
+
+	// Register a simple tool
+	err := server.RegisterTool("time", "Returns the current time in the specified format", func(args TimeArgs) (*mcp_golang.ToolResponse, error) {
+		format := args.Format
+		if format == "" {
+			format = time.RFC3339
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(time.Now().Format(format))), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Start the server
+	log.Println("Starting Streamable HTTP server on :8081...")
+	if err := server.Serve(); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+
+	// Unlike the stateless HTTP transport, the server can notify connected clients when its tools change
+	time.Sleep(10 * time.Second)
+	err = server.RegisterTool("uptime", "Returns how long the server has been running", func(args struct{}) (*mcp_golang.ToolResponse, error) {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("a while")), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	select {}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport/websocket"
+)
+
+func main() {
+	// Create a WebSocket transport that connects to the server
+	transport := websocket.NewWebSocketClientTransport("ws://localhost:8082/ws")
+	defer transport.Close()
+
+	// Create a new client with the transport
+	client := mcp_golang.NewClient(transport)
+
+	// Initialize the client, this also opens the connection
+	if _, err := client.Initialize(context.Background()); err != nil {
+		log.Fatalf("Failed to initialize client: %v", err)
+	}
+
+	// Call the time tool
+	response, err := client.CallTool(context.Background(), "time", map[string]interface{}{
+		"format": time.RFC1123,
+	})
+	if err != nil {
+		log.Fatalf("Failed to call time tool: %v", err)
+	}
+	if len(response.Content) > 0 && response.Content[0].TextContent != nil {
+		log.Printf("Time: %s", response.Content[0].TextContent.Text)
+	}
+}
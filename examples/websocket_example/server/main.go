@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport/websocket"
+)
+
+// TimeArgs defines the arguments for the time tool
+type TimeArgs struct {
+	Format string `json:"format" jsonschema:"description=The time format to use"`
+}
+
+func main() {
+	// Every WebSocket connection gets its own server
+	handler := websocket.NewWebSocketHandler(func(transport *websocket.WebSocketTransport) {
+		server := mcp_golang.NewServer(transport, mcp_golang.WithName("mcp-golang-websocket-example"), mcp_golang.WithVersion("0.0.1"))
+
+		err := server.RegisterTool("time", "Returns the current time in the specified format", func(args TimeArgs) (*mcp_golang.ToolResponse, error) {
+			format := args.Format
+			if format == "" {
+				format = time.RFC3339
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(time.Now().Format(format))), nil
+		})
+		if err != nil {
+			log.Printf("Failed to register tool: %v", err)
+			return
+		}
+
+		if err := server.Serve(); err != nil {
+			log.Printf("Server error: %v", err)
+		}
+	})
+
+	http.Handle("/ws", handler)
+	log.Println("Starting WebSocket server on :8082...")
+	log.Fatal(http.ListenAndServe(":8082", nil))
+}
@@ -142,7 +142,7 @@ func TestServerIntegration(t *testing.T) {
 			Jsonrpc: "2.0",
 			Method:  method,
 			Params:  json.RawMessage(paramsBytes),
-			Id:      transport.RequestId(i),
+			Id:      transport.NewRequestId(int64(i)),
 		}
 		i++
 
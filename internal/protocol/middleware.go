@@ -0,0 +1,55 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// Request is a request or a notification received by the protocol, as seen by middleware. Middleware can change the
+// params before calling the next handler; the handler of the method is chosen before middleware runs.
+type Request struct {
+	Method string
+	Params json.RawMessage
+	// The id of a request, nil for a notification
+	Id *transport.RequestId
+	// The session the message was received on, see ProtocolOptions.Session
+	Session string
+}
+
+// IsNotification reports whether the message is a notification, whose result is discarded
+func (r *Request) IsNotification() bool {
+	return r.Id == nil
+}
+
+// Handler handles a request or a notification and returns the result to answer it with
+type Handler func(ctx context.Context, request *Request) (transport.JsonRpcBody, error)
+
+// Middleware wraps the handling of every request and notification. It can inspect or change the request before calling
+// next, inspect the result or error that next returns, or answer without calling next, for example with a
+// *transport.RPCError.
+type Middleware func(next Handler) Handler
+
+// Use adds middleware around the handlers of every request and notification, including requests for unknown methods.
+// The first middleware is the outermost one.
+func (p *Protocol) Use(middleware ...Middleware) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.middleware = append(p.middleware, middleware...)
+}
+
+// chain wraps handler in the middleware of the protocol, called with the lock held
+func (p *Protocol) chain(handler Handler) Handler {
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		handler = p.middleware[i](handler)
+	}
+	return handler
+}
+
+func (p *Protocol) session() string {
+	if p.options == nil {
+		return ""
+	}
+	return p.options.Session
+}
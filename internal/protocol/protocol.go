@@ -68,7 +68,10 @@ package protocol
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"runtime/debug"
 	"sync"
 	"time"
 
@@ -77,6 +80,9 @@ import (
 
 const DefaultRequestTimeoutMsec = 60000
 
+// shutdownFlushTimeout is how long Shutdown gives the transport to deliver the responses sent at the deadline
+const shutdownFlushTimeout = time.Second
+
 // Progress represents a progress update
 type Progress struct {
 	Progress int64 `json:"progress"`
@@ -91,6 +97,14 @@ type ProtocolOptions struct {
 	// Whether to restrict emitted requests to only those that the remote side has indicated
 	// that they can handle, through their advertised capabilities.
 	EnforceStrictCapabilities bool
+	// Logger receives the diagnostics of the protocol, such as failed requests and dropped responses. Nothing is logged
+	// if it is nil.
+	Logger *slog.Logger
+	// Session identifies the connection of the protocol to middleware, when a server serves several of them
+	Session string
+	// OnPanic is called with the value and stack trace of panics recovered from request and notification handlers,
+	// after they have been logged. Requests whose handler panicked are answered with an internal error.
+	OnPanic func(recovered interface{}, stack []byte)
 }
 
 // RequestOptions contains options that can be given per request
@@ -115,16 +129,27 @@ type RequestHandlerExtra struct {
 type Protocol struct {
 	transport transport.Transport
 	options   *ProtocolOptions
+	logger    *slog.Logger
 
-	requestMessageID transport.RequestId
+	requestMessageID int64
 	mu               sync.RWMutex
 
 	// Maps method name to request handler
 	requestHandlers map[string]func(context.Context, *transport.BaseJSONRPCRequest, RequestHandlerExtra) (transport.JsonRpcBody, error) // Result or error
-	// Maps request ID to cancellation function
-	requestCancellers map[transport.RequestId]context.CancelFunc
+	// Maps request ID to the request being handled
+	requestCancellers map[transport.RequestId]*inflightRequest
+	// Requests being handled, waited for by Shutdown
+	inflight sync.WaitGroup
+	// Set by Shutdown, new requests are rejected
+	draining bool
+	// Wraps the handling of every request and notification, see Use
+	middleware []Middleware
 	// Maps method name to notification handler
 	notificationHandlers map[string]func(notification *transport.BaseJSONRPCNotification) error
+	// Notifications waiting to be handled, in the order they arrived, and whether a goroutine is handling them
+	notificationQueue []func()
+	notifying         bool
+	notificationMu    sync.Mutex
 	// Maps message ID to response handler
 	responseHandlers map[transport.RequestId]chan *responseEnvelope
 	// Maps message ID to progress handler
@@ -140,6 +165,12 @@ type Protocol struct {
 	FallbackNotificationHandler func(notification *transport.BaseJSONRPCNotification) error
 }
 
+// inflightRequest is a request whose handler is running
+type inflightRequest struct {
+	cancel    context.CancelFunc
+	responded sync.Once
+}
+
 type responseEnvelope struct {
 	response interface{}
 	err      error
@@ -147,10 +178,15 @@ type responseEnvelope struct {
 
 // NewProtocol creates a new Protocol instance
 func NewProtocol(options *ProtocolOptions) *Protocol {
+	logger := slog.New(discardHandler{})
+	if options != nil && options.Logger != nil {
+		logger = options.Logger
+	}
 	p := &Protocol{
 		options:              options,
+		logger:               logger,
 		requestHandlers:      make(map[string]func(context.Context, *transport.BaseJSONRPCRequest, RequestHandlerExtra) (transport.JsonRpcBody, error)),
-		requestCancellers:    make(map[transport.RequestId]context.CancelFunc),
+		requestCancellers:    make(map[transport.RequestId]*inflightRequest),
 		notificationHandlers: make(map[string]func(*transport.BaseJSONRPCNotification) error),
 		responseHandlers:     make(map[transport.RequestId]chan *responseEnvelope),
 		progressHandlers:     make(map[transport.RequestId]ProgressCallback),
@@ -180,7 +216,7 @@ func (p *Protocol) Connect(tr transport.Transport) error {
 		case m == transport.BaseMessageTypeJSONRPCRequestType:
 			p.handleRequest(ctx, message.JsonRpcRequest)
 		case m == transport.BaseMessageTypeJSONRPCNotificationType:
-			p.handleNotification(message.JsonRpcNotification)
+			p.handleNotification(ctx, message.JsonRpcNotification)
 		case m == transport.BaseMessageTypeJSONRPCResponseType:
 			p.handleResponse(message.JsonRpcResponse, nil)
 		case m == transport.BaseMessageTypeJSONRPCErrorType:
@@ -200,10 +236,10 @@ func (p *Protocol) handleClose() {
 	p.notificationHandlers = make(map[string]func(notification *transport.BaseJSONRPCNotification) error)
 
 	// Cancel all pending requests
-	for _, cancel := range p.requestCancellers {
-		cancel()
+	for _, request := range p.requestCancellers {
+		request.cancel()
 	}
-	p.requestCancellers = make(map[transport.RequestId]context.CancelFunc)
+	p.requestCancellers = make(map[transport.RequestId]*inflightRequest)
 
 	// Close all response channels with error
 	for id, ch := range p.responseHandlers {
@@ -225,21 +261,58 @@ func (p *Protocol) handleError(err error) {
 	}
 }
 
-func (p *Protocol) handleNotification(notification *transport.BaseJSONRPCNotification) {
+func (p *Protocol) handleNotification(ctx context.Context, notification *transport.BaseJSONRPCNotification) {
 	p.mu.RLock()
 	handler := p.notificationHandlers[notification.Method]
 	if handler == nil {
 		handler = p.FallbackNotificationHandler
 	}
+	if handler == nil && len(p.middleware) == 0 {
+		p.mu.RUnlock()
+		return
+	}
+	chained := p.chain(func(ctx context.Context, request *Request) (transport.JsonRpcBody, error) {
+		if handler == nil {
+			return nil, nil
+		}
+		return nil, handler(&transport.BaseJSONRPCNotification{Jsonrpc: "2.0", Method: request.Method, Params: request.Params})
+	})
 	p.mu.RUnlock()
 
-	if handler == nil {
+	// Notifications are handled after the transport has delivered them, so only the values of ctx are kept
+	ctx = context.WithoutCancel(ctx)
+	p.queueNotification(func() {
+		_, err := p.call(ctx, chained, &Request{Method: notification.Method, Params: notification.Params, Session: p.session()})
+		if err != nil {
+			p.logger.Warn("notification handler failed", "method", notification.Method, "error", err)
+			p.handleError(fmt.Errorf("notification handler error: %w", err))
+		}
+	})
+}
+
+// queueNotification handles a notification after the ones received before it. Notifications are handled one at a time
+// on a goroutine of their own, so the transport keeps reading while they are.
+func (p *Protocol) queueNotification(handle func()) {
+	p.notificationMu.Lock()
+	defer p.notificationMu.Unlock()
+	p.notificationQueue = append(p.notificationQueue, handle)
+	if p.notifying {
 		return
 	}
-
+	p.notifying = true
 	go func() {
-		if err := handler(notification); err != nil {
-			p.handleError(fmt.Errorf("notification handler error: %w", err))
+		for {
+			p.notificationMu.Lock()
+			if len(p.notificationQueue) == 0 {
+				p.notifying = false
+				p.notificationMu.Unlock()
+				return
+			}
+			handle := p.notificationQueue[0]
+			p.notificationQueue[0] = nil
+			p.notificationQueue = p.notificationQueue[1:]
+			p.notificationMu.Unlock()
+			handle()
 		}
 	}()
 }
@@ -252,49 +325,99 @@ func (p *Protocol) handleRequest(ctx context.Context, request *transport.BaseJSO
 			if p.FallbackRequestHandler != nil {
 				return p.FallbackRequestHandler(ctx, req)
 			}
-			println("no handler for method and no default handler:", req.Method)
-			return nil, fmt.Errorf("method not found: %s", req.Method)
+			p.logger.Warn("no handler for method", "method", req.Method, "request_id", req.Id)
+			return nil, transport.NewRPCError(transport.ErrorCodeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method))
 		}
 	}
+	chained := p.chain(func(ctx context.Context, r *Request) (transport.JsonRpcBody, error) {
+		return handler(ctx, &transport.BaseJSONRPCRequest{Jsonrpc: "2.0", Id: request.Id, Method: r.Method, Params: r.Params}, RequestHandlerExtra{Context: ctx})
+	})
 	p.mu.RUnlock()
 
 	ctx, cancel := context.WithCancel(ctx)
+	inflight := &inflightRequest{cancel: cancel}
 	p.mu.Lock()
-	p.requestCancellers[request.Id] = cancel
+	if p.draining {
+		p.mu.Unlock()
+		cancel()
+		p.logger.Debug("request rejected while shutting down", "method", request.Method, "request_id", request.Id)
+		p.sendErrorResponse(request.Id, fmt.Errorf("server is shutting down"))
+		return
+	}
+	p.requestCancellers[request.Id] = inflight
+	p.inflight.Add(1)
 	p.mu.Unlock()
 
 	go func() {
 		defer func() {
 			p.mu.Lock()
-			delete(p.requestCancellers, request.Id)
+			if p.requestCancellers[request.Id] == inflight {
+				delete(p.requestCancellers, request.Id)
+			}
 			p.mu.Unlock()
 			cancel()
+			p.inflight.Done()
 		}()
 
-		result, err := handler(ctx, request, RequestHandlerExtra{Context: ctx})
-		if err != nil {
-			println("error:", err.Error())
-			p.sendErrorResponse(request.Id, err)
-			return
+		start := time.Now()
+		id := request.Id
+		result, err := p.call(ctx, chained, &Request{Method: request.Method, Params: request.Params, Id: &id, Session: p.session()})
+		logger := p.logger.With("method", request.Method, "request_id", request.Id, "duration", time.Since(start))
+		// Shutdown may have answered the request already
+		responded := false
+		inflight.responded.Do(func() {
+			responded = true
+			if err != nil {
+				logger.Warn("request failed", "error", err)
+				p.sendErrorResponse(request.Id, err)
+				return
+			}
+
+			jsonResult, err := json.Marshal(result)
+			if err != nil {
+				logger.Error("failed to marshal result", "error", err)
+				p.sendErrorResponse(request.Id, fmt.Errorf("failed to marshal result: %w", err))
+				return
+			}
+			response := &transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      request.Id,
+				Result:  jsonResult,
+			}
+
+			if err := p.transport.Send(ctx, transport.NewBaseMessageResponse(response)); err != nil {
+				logger.Error("failed to send response", "error", err)
+				p.handleError(fmt.Errorf("failed to send response: %w", err))
+				return
+			}
+			logger.Debug("request handled")
+		})
+		if !responded {
+			logger.Warn("response dropped, the request was already answered while shutting down", "error", err)
 		}
+	}()
+}
 
-		jsonResult, err := json.Marshal(result)
-		if err != nil {
-			println("error:", err.Error())
-			p.sendErrorResponse(request.Id, fmt.Errorf("failed to marshal result: %w", err))
+// call runs handler, turning a panic into an internal error
+func (p *Protocol) call(ctx context.Context, handler Handler, request *Request) (result transport.JsonRpcBody, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
 			return
 		}
-		response := &transport.BaseJSONRPCResponse{
-			Jsonrpc: "2.0",
-			Id:      request.Id,
-			Result:  jsonResult,
+		stack := debug.Stack()
+		logger := p.logger.With("method", request.Method)
+		if request.Id != nil {
+			logger = logger.With("request_id", *request.Id)
 		}
-
-		if err := p.transport.Send(ctx, transport.NewBaseMessageResponse(response)); err != nil {
-			println("error:", err.Error())
-			p.handleError(fmt.Errorf("failed to send response: %w", err))
+		logger.Error("handler panicked", "panic", recovered, "stack", string(stack))
+		if p.options != nil && p.options.OnPanic != nil {
+			p.options.OnPanic(recovered, stack)
 		}
+		result = nil
+		err = transport.NewRPCError(transport.ErrorCodeInternalError, fmt.Sprintf("handler panicked: %v", recovered))
 	}()
+	return handler(ctx, request)
 }
 
 func (p *Protocol) handleProgressNotification(notification *transport.BaseJSONRPCNotification) error {
@@ -333,26 +456,27 @@ func (p *Protocol) handleCancelledNotification(notification *transport.BaseJSONR
 	}
 
 	p.mu.RLock()
-	cancel := p.requestCancellers[params.RequestId]
+	request := p.requestCancellers[params.RequestId]
 	p.mu.RUnlock()
 
-	if cancel != nil {
-		cancel()
+	if request != nil {
+		request.cancel()
 	}
 
 	return nil
 }
 
 func (p *Protocol) handleResponse(response *transport.BaseJSONRPCResponse, errResp *transport.BaseJSONRPCError) {
-	var id = response.Id
+	var id transport.RequestId
 	var result interface{}
 	var err error
 
 	if errResp != nil {
 		id = errResp.Id
-		err = fmt.Errorf("RPC error %d: %s", errResp.Error.Code, errResp.Error.Message)
+		err = &transport.RPCError{Code: errResp.Error.Code, Message: errResp.Error.Message, Data: errResp.Error.Data}
 	} else {
 		// Parse the response
+		id = response.Id
 		result = response.Result
 	}
 
@@ -360,11 +484,13 @@ func (p *Protocol) handleResponse(response *transport.BaseJSONRPCResponse, errRe
 	ch := p.responseHandlers[id]
 	p.mu.RUnlock()
 
-	if ch != nil {
-		ch <- &responseEnvelope{
-			response: result,
-			err:      err,
-		}
+	if ch == nil {
+		p.logger.Warn("response dropped, no request is waiting for it", "request_id", id)
+		return
+	}
+	ch <- &responseEnvelope{
+		response: result,
+		err:      err,
 	}
 }
 
@@ -376,12 +502,161 @@ func (p *Protocol) Close() error {
 	return nil
 }
 
+// Shutdown gracefully closes the connection. New requests are answered with an error straight away while the requests
+// being handled run to completion and their responses are sent. If ctx is done first, the remaining requests are
+// cancelled through their contexts and answered with an error, and ctx.Err() is returned once the transport is closed.
+//
+// Transports that implement transport.Shutdowner are shut down with ctx, or given a short grace period to deliver the
+// error responses if ctx is already done. Other transports are closed.
+func (p *Protocol) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	flushCtx := ctx
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		p.abortInflightRequests()
+		var cancel context.CancelFunc
+		flushCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), shutdownFlushTimeout)
+		defer cancel()
+	}
+
+	if p.transport == nil {
+		return err
+	}
+	var closeErr error
+	if shutdowner, ok := p.transport.(transport.Shutdowner); ok {
+		closeErr = shutdowner.Shutdown(flushCtx)
+	} else {
+		closeErr = p.transport.Close()
+	}
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// abortInflightRequests cancels every request that is being handled and answers it with an error.
+// Responses the handlers send afterwards are dropped.
+func (p *Protocol) abortInflightRequests() {
+	p.mu.RLock()
+	requests := make(map[transport.RequestId]*inflightRequest, len(p.requestCancellers))
+	for id, request := range p.requestCancellers {
+		requests[id] = request
+	}
+	p.mu.RUnlock()
+
+	for id, request := range requests {
+		request.cancel()
+		request.responded.Do(func() {
+			p.sendErrorResponse(id, fmt.Errorf("request cancelled, server is shutting down"))
+		})
+	}
+}
+
+// BatchRequest is one of the requests sent by RequestBatch
+type BatchRequest struct {
+	Method string
+	Params interface{}
+}
+
+// BatchResponse is the outcome of one of the requests sent by RequestBatch
+type BatchResponse struct {
+	Response interface{}
+	Err      error
+}
+
+// pendingRequest is a request that is waiting for its response
+type pendingRequest struct {
+	id       transport.RequestId
+	response chan *responseEnvelope
+	message  *transport.BaseJsonRpcMessage
+}
+
 // Request sends a request and waits for a response
 func (p *Protocol) Request(ctx context.Context, method string, params interface{}, opts *RequestOptions) (interface{}, error) {
 	if p.transport == nil {
 		return nil, fmt.Errorf("not connected")
 	}
 
+	opts = withRequestDefaults(ctx, opts)
+	request, err := p.newRequest(method, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer p.forgetRequest(request)
+
+	if err := p.transport.Send(ctx, request.message); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return p.awaitResponse(request, opts)
+}
+
+// RequestBatch sends requests as one JSON-RPC batch if the transport implements transport.BatchSender, and one at a
+// time otherwise, then waits for all of their responses. opts apply to every request.
+// It returns an error if the requests could not be sent, and the outcome of each request in order otherwise.
+func (p *Protocol) RequestBatch(ctx context.Context, requests []BatchRequest, opts *RequestOptions) ([]BatchResponse, error) {
+	if p.transport == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	opts = withRequestDefaults(ctx, opts)
+	pending := make([]*pendingRequest, 0, len(requests))
+	defer func() {
+		for _, request := range pending {
+			p.forgetRequest(request)
+		}
+	}()
+	messages := make([]*transport.BaseJsonRpcMessage, 0, len(requests))
+	for _, r := range requests {
+		request, err := p.newRequest(r.Method, r.Params, opts)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, request)
+		messages = append(messages, request.message)
+	}
+
+	if batchSender, ok := p.transport.(transport.BatchSender); ok {
+		if err := batchSender.SendBatch(ctx, messages); err != nil {
+			return nil, fmt.Errorf("failed to send batch: %w", err)
+		}
+	} else {
+		for _, message := range messages {
+			if err := p.transport.Send(ctx, message); err != nil {
+				return nil, fmt.Errorf("failed to send request: %w", err)
+			}
+		}
+	}
+
+	responses := make([]BatchResponse, len(pending))
+	var wg sync.WaitGroup
+	for i, request := range pending {
+		wg.Add(1)
+		go func(i int, request *pendingRequest) {
+			defer wg.Done()
+			response, err := p.awaitResponse(request, opts)
+			responses[i] = BatchResponse{Response: response, Err: err}
+		}(i, request)
+	}
+	wg.Wait()
+	return responses, nil
+}
+
+func withRequestDefaults(ctx context.Context, opts *RequestOptions) *RequestOptions {
 	if opts == nil {
 		opts = &RequestOptions{}
 	}
@@ -393,9 +668,13 @@ func (p *Protocol) Request(ctx context.Context, method string, params interface{
 	if opts.Timeout == 0 {
 		opts.Timeout = time.Duration(DefaultRequestTimeoutMsec) * time.Millisecond
 	}
+	return opts
+}
 
+// newRequest assigns an id to a request and registers it for its response
+func (p *Protocol) newRequest(method string, params interface{}, opts *RequestOptions) (*pendingRequest, error) {
 	p.mu.Lock()
-	id := p.requestMessageID
+	id := transport.NewRequestId(p.requestMessageID)
 	p.requestMessageID++
 	ch := make(chan *responseEnvelope, 1)
 	p.responseHandlers[id] = ch
@@ -403,13 +682,7 @@ func (p *Protocol) Request(ctx context.Context, method string, params interface{
 		p.progressHandlers[id] = opts.OnProgress
 	}
 	p.mu.Unlock()
-
-	defer func() {
-		p.mu.Lock()
-		delete(p.responseHandlers, id)
-		delete(p.progressHandlers, id)
-		p.mu.Unlock()
-	}()
+	pending := &pendingRequest{id: id, response: ch}
 
 	// Create request with meta information if needed
 	requestParams := params
@@ -425,37 +698,47 @@ func (p *Protocol) Request(ctx context.Context, method string, params interface{
 			paramsMap["_meta"] = meta
 			requestParams = paramsMap
 		} else {
+			p.forgetRequest(pending)
 			return nil, fmt.Errorf("params must be nil or map[string]interface{} when using progress")
 		}
 	}
 
 	marshalledParams, err := json.Marshal(requestParams)
 	if err != nil {
+		p.forgetRequest(pending)
 		return nil, fmt.Errorf("failed to marshal params: %w", err)
 	}
 
-	request := &transport.BaseJSONRPCRequest{
+	pending.message = transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
 		Jsonrpc: "2.0",
 		Method:  method,
 		Params:  marshalledParams,
 		Id:      id,
-	}
+	})
+	return pending, nil
+}
 
-	if err := p.transport.Send(ctx, transport.NewBaseMessageRequest(request)); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
+func (p *Protocol) forgetRequest(request *pendingRequest) {
+	p.mu.Lock()
+	delete(p.responseHandlers, request.id)
+	delete(p.progressHandlers, request.id)
+	p.mu.Unlock()
+}
 
+// awaitResponse waits for the response to a request that has been sent, cancelling it if the context is done or it
+// times out
+func (p *Protocol) awaitResponse(request *pendingRequest, opts *RequestOptions) (interface{}, error) {
 	select {
-	case envelope := <-ch:
+	case envelope := <-request.response:
 		if envelope.err != nil {
 			return nil, envelope.err
 		}
 		return envelope.response, nil
 	case <-opts.Context.Done():
-		p.sendCancelNotification(id, opts.Context.Err().Error())
+		p.sendCancelNotification(request.id, opts.Context.Err().Error())
 		return nil, opts.Context.Err()
 	case <-time.After(opts.Timeout):
-		p.sendCancelNotification(id, "request timeout")
+		p.sendCancelNotification(request.id, "request timeout")
 		return nil, fmt.Errorf("request timeout after %v", opts.Timeout)
 	}
 }
@@ -482,18 +765,26 @@ func (p *Protocol) sendCancelNotification(requestID transport.RequestId, reason
 	return nil
 }
 
+// sendErrorResponse answers a request with err. An *transport.RPCError in the chain of err keeps its code, message and
+// data, any other error is sent as an internal error.
 func (p *Protocol) sendErrorResponse(requestID transport.RequestId, err error) error {
+	inner := transport.BaseJSONRPCErrorInner{
+		Code:    transport.ErrorCodeInternalError,
+		Message: err.Error(),
+	}
+	var rpcErr *transport.RPCError
+	if errors.As(err, &rpcErr) {
+		inner = transport.BaseJSONRPCErrorInner{Code: rpcErr.Code, Message: rpcErr.Message, Data: rpcErr.Data}
+	}
 	response := &transport.BaseJSONRPCError{
 		Jsonrpc: "2.0",
 		Id:      requestID,
-		Error: transport.BaseJSONRPCErrorInner{
-			Code:    -32000, // Internal error
-			Message: err.Error(),
-		},
+		Error:   inner,
 	}
 	ctx := context.Background()
 
 	if err := p.transport.Send(ctx, transport.NewBaseMessageError(response)); err != nil {
+		p.logger.Error("failed to send error response", "request_id", requestID, "error", err)
 		p.handleError(fmt.Errorf("failed to send error response: %w", err))
 	}
 	return nil
@@ -501,6 +792,12 @@ func (p *Protocol) sendErrorResponse(requestID transport.RequestId, err error) e
 
 // Notification emits a notification, which is a one-way message that does not expect a response
 func (p *Protocol) Notification(method string, params interface{}) error {
+	return p.NotificationContext(context.Background(), method, params)
+}
+
+// NotificationContext emits a notification with ctx, which transports can use to send it along with the request being
+// handled
+func (p *Protocol) NotificationContext(ctx context.Context, method string, params interface{}) error {
 	if p.transport == nil {
 		return fmt.Errorf("not connected")
 	}
@@ -515,7 +812,6 @@ func (p *Protocol) Notification(method string, params interface{}) error {
 		Method:  method,
 		Params:  marshalled,
 	}
-	ctx := context.Background()
 
 	return p.transport.Send(ctx, transport.NewBaseMessageNotification(notification))
 }
@@ -535,6 +831,7 @@ func (p *Protocol) RemoveRequestHandler(method string) {
 }
 
 // SetNotificationHandler registers a handler to invoke when this protocol object receives a notification with the given method
+// Notification handlers run one at a time, in the order the notifications were received.
 func (p *Protocol) SetNotificationHandler(method string, handler func(notification *transport.BaseJSONRPCNotification) error) {
 	p.mu.Lock()
 	p.notificationHandlers[method] = handler
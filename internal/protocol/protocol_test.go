@@ -1,9 +1,14 @@
 package protocol
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -240,6 +245,58 @@ func TestProtocol_NotificationHandler(t *testing.T) {
 	}
 }
 
+// TestProtocol_NotificationOrder tests that notifications are handled one at a time, in the order they were received,
+// without blocking the transport.
+func TestProtocol_NotificationOrder(t *testing.T) {
+	p := NewProtocol(nil)
+	tr := testingutils.NewMockTransport()
+
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	const count = 100
+	received := make(chan int, count)
+	var running atomic.Int32
+	p.SetNotificationHandler("test_notification", func(notification *transport.BaseJSONRPCNotification) error {
+		if running.Add(1) > 1 {
+			t.Error("Notification handlers ran concurrently")
+		}
+		defer running.Add(-1)
+		var params struct {
+			N int `json:"n"`
+		}
+		if err := json.Unmarshal(notification.Params, &params); err != nil {
+			return err
+		}
+		time.Sleep(time.Millisecond)
+		received <- params.N
+		return nil
+	})
+
+	for i := 0; i < count; i++ {
+		tr.SimulateMessage(&transport.BaseJsonRpcMessage{
+			Type: transport.BaseMessageTypeJSONRPCNotificationType,
+			JsonRpcNotification: &transport.BaseJSONRPCNotification{
+				Jsonrpc: "2.0",
+				Method:  "test_notification",
+				Params:  json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)),
+			},
+		})
+	}
+
+	for i := 0; i < count; i++ {
+		select {
+		case n := <-received:
+			if n != i {
+				t.Fatalf("Expected notification %d, got %d", i, n)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for notification %d", i)
+		}
+	}
+}
+
 // TestProtocol_Progress tests the progress tracking functionality.
 // Progress tracking is essential for long-running operations.
 // The test covers:
@@ -359,3 +416,358 @@ func TestProtocol_ErrorHandling(t *testing.T) {
 		t.Error("Error not received")
 	}
 }
+
+// TestProtocol_StringRequestIds verifies that string ids from the peer are used for responses, cancellation and
+// progress, and are never confused with numeric ids of the same value.
+func TestProtocol_StringRequestIds(t *testing.T) {
+	p := NewProtocol(nil)
+	tr := testingutils.NewMockTransport()
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	cancelled := make(chan string, 2)
+	p.SetRequestHandler("wait", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		select {
+		case <-ctx.Done():
+			cancelled <- req.Id.String()
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+			return map[string]interface{}{"id": req.Id}, nil
+		}
+	})
+
+	for _, id := range []transport.RequestId{transport.NewStringRequestId("1"), transport.NewRequestId(1)} {
+		tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  "wait",
+			Id:      id,
+		}))
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// Only the request with the string id is cancelled
+	tr.SimulateMessage(transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  json.RawMessage(`{"requestId":"1"}`),
+	}))
+	select {
+	case id := <-cancelled:
+		if id != `"1"` {
+			t.Errorf("Expected the request with id \"1\" to be cancelled, got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Request was not cancelled")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	var answered []string
+	for _, msg := range tr.GetMessages() {
+		if msg.Type == transport.BaseMessageTypeJSONRPCResponseType {
+			answered = append(answered, msg.JsonRpcResponse.Id.String())
+		}
+	}
+	if len(answered) != 1 || answered[0] != "1" {
+		t.Errorf("Expected only the numeric request to be answered, got %v", answered)
+	}
+}
+
+// TestProtocol_ErrorCodes verifies that error responses carry the code of an *transport.RPCError returned by a handler,
+// standard codes otherwise, and that received error responses are returned as an *transport.RPCError.
+func TestProtocol_ErrorCodes(t *testing.T) {
+	p := NewProtocol(nil)
+	tr := testingutils.NewMockTransport()
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	p.SetRequestHandler("typed", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		return nil, fmt.Errorf("wrapped: %w", transport.NewRPCError(transport.ErrorCodeInvalidParams, "bad city").WithData("London"))
+	})
+	p.SetRequestHandler("untyped", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		return nil, errors.New("boom")
+	})
+
+	expected := map[string]transport.BaseJSONRPCErrorInner{
+		"typed":   {Code: transport.ErrorCodeInvalidParams, Message: "bad city", Data: "London"},
+		"untyped": {Code: transport.ErrorCodeInternalError, Message: "boom"},
+		"missing": {Code: transport.ErrorCodeMethodNotFound, Message: "method not found: missing"},
+	}
+	ids := make(map[transport.RequestId]string)
+	var n int64
+	for method := range expected {
+		n++
+		ids[transport.NewRequestId(n)] = method
+		tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  method,
+			Id:      transport.NewRequestId(n),
+		}))
+	}
+
+	deadline := time.Now().Add(time.Second)
+	var errs []*transport.BaseJSONRPCError
+	for len(errs) < len(expected) && time.Now().Before(deadline) {
+		errs = nil
+		for _, msg := range tr.GetMessages() {
+			if msg.Type == transport.BaseMessageTypeJSONRPCErrorType {
+				errs = append(errs, msg.JsonRpcError)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d error responses, got %d", len(expected), len(errs))
+	}
+	for _, errResp := range errs {
+		method := ids[errResp.Id]
+		if errResp.Error != expected[method] {
+			t.Errorf("Expected error %+v for %s, got %+v", expected[method], method, errResp.Error)
+		}
+	}
+
+	// Error responses to our requests are returned as an *transport.RPCError
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		for _, msg := range tr.GetMessages() {
+			if msg.Type == transport.BaseMessageTypeJSONRPCRequestType && msg.JsonRpcRequest.Method == "test" {
+				p.handleResponse(nil, &transport.BaseJSONRPCError{
+					Jsonrpc: "2.0",
+					Id:      msg.JsonRpcRequest.Id,
+					Error:   transport.BaseJSONRPCErrorInner{Code: -32001, Message: "custom", Data: map[string]interface{}{"retry": true}},
+				})
+			}
+		}
+	}()
+	_, err := p.Request(context.Background(), "test", nil, nil)
+	var rpcErr *transport.RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("Expected an RPCError, got %v", err)
+	}
+	if rpcErr.Code != -32001 || rpcErr.Message != "custom" || rpcErr.Data.(map[string]interface{})["retry"] != true {
+		t.Errorf("Unexpected error %+v", rpcErr)
+	}
+}
+
+// TestProtocol_Logger verifies that failed requests, unknown methods and dropped responses are logged with the method,
+// the request id and the duration of the request.
+func TestProtocol_Logger(t *testing.T) {
+	var logs syncBuffer
+	p := NewProtocol(&ProtocolOptions{Logger: slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))})
+	tr := testingutils.NewMockTransport()
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	p.SetRequestHandler("fail", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		return nil, errors.New("boom")
+	})
+
+	for i, method := range []string{"fail", "missing"} {
+		tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  method,
+			Id:      transport.NewStringRequestId(fmt.Sprintf("request-%d", i)),
+		}))
+	}
+	p.handleResponse(&transport.BaseJSONRPCResponse{Jsonrpc: "2.0", Id: transport.NewRequestId(42)}, nil)
+
+	var records []map[string]interface{}
+	deadline := time.Now().Add(time.Second)
+	for len(records) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		records = logs.records(t)
+	}
+
+	find := func(msg string, method string) map[string]interface{} {
+		for _, record := range records {
+			if record["msg"] == msg && (method == "" || record["method"] == method) {
+				return record
+			}
+		}
+		t.Fatalf("No %q record for %q in %v", msg, method, records)
+		return nil
+	}
+	failed := find("request failed", "fail")
+	if failed["level"] != "WARN" || failed["request_id"] != "request-0" || failed["error"] != "boom" {
+		t.Errorf("Unexpected record %v", failed)
+	}
+	if _, ok := failed["duration"]; !ok {
+		t.Errorf("Expected the duration of the request in %v", failed)
+	}
+	if missing := find("no handler for method", "missing"); missing["request_id"] != "request-1" {
+		t.Errorf("Unexpected record %v", missing)
+	}
+	if dropped := find("response dropped, no request is waiting for it", ""); dropped["request_id"] != float64(42) {
+		t.Errorf("Unexpected record %v", dropped)
+	}
+}
+
+// syncBuffer collects the output of a JSON slog handler from several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) records(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(b.buf.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Invalid log record %s: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestProtocol_Middleware verifies that middleware runs around every request and notification in order, sees their
+// results, and can answer requests itself.
+func TestProtocol_Middleware(t *testing.T) {
+	p := NewProtocol(&ProtocolOptions{Session: "session-1"})
+	tr := testingutils.NewMockTransport()
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	p.SetRequestHandler("echo", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		return req.Params, nil
+	})
+	notified := make(chan string, 1)
+	p.SetNotificationHandler("notify", func(notification *transport.BaseJSONRPCNotification) error {
+		notified <- string(notification.Params)
+		return nil
+	})
+
+	var mu sync.Mutex
+	var seen []string
+	record := func(entry string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, entry)
+	}
+	p.Use(func(next Handler) Handler {
+		return func(ctx context.Context, request *Request) (transport.JsonRpcBody, error) {
+			record(fmt.Sprintf("outer %s %s notification=%t", request.Session, request.Method, request.IsNotification()))
+			result, err := next(ctx, request)
+			record(fmt.Sprintf("outer result %s %v", request.Method, err))
+			return result, err
+		}
+	}, func(next Handler) Handler {
+		return func(ctx context.Context, request *Request) (transport.JsonRpcBody, error) {
+			if request.Method == "forbidden" {
+				return nil, transport.NewRPCError(-32001, "forbidden")
+			}
+			request.Params = json.RawMessage(`"changed"`)
+			return next(ctx, request)
+		}
+	})
+
+	for i, method := range []string{"echo", "forbidden"} {
+		tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  method,
+			Id:      transport.NewRequestId(int64(i)),
+			Params:  json.RawMessage(`"original"`),
+		}))
+		time.Sleep(20 * time.Millisecond)
+	}
+	tr.SimulateMessage(transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  "notify",
+		Params:  json.RawMessage(`"original"`),
+	}))
+	select {
+	case params := <-notified:
+		if params != `"changed"` {
+			t.Errorf("Expected the notification params to be changed, got %s", params)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Notification was not handled")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var answers []string
+	for _, msg := range tr.GetMessages() {
+		switch msg.Type {
+		case transport.BaseMessageTypeJSONRPCResponseType:
+			answers = append(answers, string(msg.JsonRpcResponse.Result))
+		case transport.BaseMessageTypeJSONRPCErrorType:
+			answers = append(answers, fmt.Sprint(msg.JsonRpcError.Error.Code))
+		}
+	}
+	if fmt.Sprint(answers) != `["changed" -32001]` {
+		t.Errorf("Unexpected answers %v", answers)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{
+		"outer session-1 echo notification=false",
+		"outer result echo <nil>",
+		"outer session-1 forbidden notification=false",
+		"outer result forbidden RPC error -32001: forbidden",
+		"outer session-1 notify notification=true",
+		"outer result notify <nil>",
+	}
+	if fmt.Sprint(seen) != fmt.Sprint(expected) {
+		t.Errorf("Expected middleware calls %v, got %v", expected, seen)
+	}
+}
+
+// TestProtocol_PanicRecovery verifies that panics in handlers are recovered, reported, and answered with an internal
+// error.
+func TestProtocol_PanicRecovery(t *testing.T) {
+	panics := make(chan interface{}, 2)
+	p := NewProtocol(&ProtocolOptions{OnPanic: func(recovered interface{}, stack []byte) {
+		if len(stack) == 0 {
+			t.Error("Expected a stack trace")
+		}
+		panics <- recovered
+	}})
+	tr := testingutils.NewMockTransport()
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	p.SetRequestHandler("panic", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		panic("request boom")
+	})
+	p.SetNotificationHandler("panic", func(notification *transport.BaseJSONRPCNotification) error {
+		panic("notification boom")
+	})
+
+	tr.SimulateMessage(transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{Jsonrpc: "2.0", Method: "panic"}))
+	tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{Jsonrpc: "2.0", Method: "panic", Id: transport.NewRequestId(1)}))
+
+	recovered := map[interface{}]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case value := <-panics:
+			recovered[value] = true
+		case <-time.After(time.Second):
+			t.Fatal("Panic was not reported")
+		}
+	}
+	if !recovered["request boom"] || !recovered["notification boom"] {
+		t.Errorf("Unexpected panics %v", recovered)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	messages := tr.GetMessages()
+	if len(messages) != 1 || messages[0].Type != transport.BaseMessageTypeJSONRPCErrorType {
+		t.Fatalf("Expected one error response, got %v", messages)
+	}
+	if inner := messages[0].JsonRpcError.Error; inner.Code != transport.ErrorCodeInternalError || inner.Message != "handler panicked: request boom" {
+		t.Errorf("Unexpected error %+v", inner)
+	}
+}
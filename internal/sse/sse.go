@@ -0,0 +1,132 @@
+// Package sse implements reading and writing of the text/event-stream format used by Server-Sent Events.
+// It is shared by the transports that stream JSON-RPC messages over SSE.
+//
+// See https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+package sse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single Server-Sent Event
+type Event struct {
+	// ID is the event id. Clients send the last id they saw in the Last-Event-ID header when reconnecting.
+	ID string
+	// Event is the event type. An empty type means the default "message" type.
+	Event string
+	// Data is the event payload. Multiple data lines are joined with "\n".
+	Data string
+	// Retry is the reconnection delay requested by the server, zero if not set.
+	Retry time.Duration
+}
+
+// WriteEvent writes an event to w in the text/event-stream format.
+// The caller is responsible for flushing w.
+func WriteEvent(w io.Writer, event Event) error {
+	var b strings.Builder
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", event.ID)
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event.Event)
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", event.Retry.Milliseconds())
+	}
+	for _, line := range strings.Split(event.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteComment writes a comment line to w. Comments are ignored by clients and are used to keep idle connections open.
+// The caller is responsible for flushing w.
+func WriteComment(w io.Writer, comment string) error {
+	_, err := fmt.Fprintf(w, ": %s\n\n", comment)
+	return err
+}
+
+// Reader reads events from a text/event-stream
+type Reader struct {
+	reader *bufio.Reader
+	lastID string
+	retry  time.Duration
+}
+
+// NewReader creates a new Reader reading from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		reader: bufio.NewReader(r),
+	}
+}
+
+// LastEventID returns the id of the most recent event that set one
+func (r *Reader) LastEventID() string {
+	return r.lastID
+}
+
+// Retry returns the most recent reconnection delay requested by the server, zero if none was requested
+func (r *Reader) Retry() time.Duration {
+	return r.retry
+}
+
+// Next blocks until the next event has been read from the stream.
+// It returns io.EOF once the stream has ended; an event that was not terminated by a blank line is discarded.
+func (r *Reader) Next() (*Event, error) {
+	var event Event
+	var data []string
+	hasData := false
+
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil {
+			// A trailing line without a newline and any event still in progress are discarded
+			return nil, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			// A blank line dispatches the event, events without data are not dispatched
+			if !hasData {
+				event = Event{}
+				continue
+			}
+			event.ID = r.lastID
+			event.Data = strings.Join(data, "\n")
+			return &event, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			// Comment
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			// Ids containing NULL are ignored as required by the spec
+			if !strings.Contains(value, "\x00") {
+				r.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				event.Retry = time.Duration(ms) * time.Millisecond
+				r.retry = event.Retry
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sse
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteEvent(&buf, Event{ID: "7", Event: "message", Data: "line one\nline two"})
+	require.NoError(t, err)
+	assert.Equal(t, "id: 7\nevent: message\ndata: line one\ndata: line two\n\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteComment(&buf, "keepalive"))
+	assert.Equal(t, ": keepalive\n\n", buf.String())
+}
+
+func TestReader(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteEvent(&buf, Event{Event: "endpoint", Data: "/messages?sessionId=abc"}))
+		require.NoError(t, WriteComment(&buf, "keepalive"))
+		require.NoError(t, WriteEvent(&buf, Event{ID: "2", Data: `{"jsonrpc":"2.0"}`, Retry: time.Second}))
+
+		reader := NewReader(&buf)
+		event, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "endpoint", event.Event)
+		assert.Equal(t, "/messages?sessionId=abc", event.Data)
+		assert.Equal(t, "", event.ID)
+
+		event, err = reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "", event.Event)
+		assert.Equal(t, `{"jsonrpc":"2.0"}`, event.Data)
+		assert.Equal(t, "2", event.ID)
+		assert.Equal(t, time.Second, event.Retry)
+		assert.Equal(t, "2", reader.LastEventID())
+
+		_, err = reader.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("crlf line endings and multi-line data", func(t *testing.T) {
+		reader := NewReader(strings.NewReader("data:a\r\ndata: b\r\n\r\n"))
+		event, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "a\nb", event.Data)
+	})
+
+	t.Run("events without data are not dispatched", func(t *testing.T) {
+		reader := NewReader(strings.NewReader("id: 1\n\nevent: ping\n\ndata: x\n\n"))
+		event, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "x", event.Data)
+		assert.Equal(t, "", event.Event)
+		assert.Equal(t, "1", event.ID)
+	})
+
+	t.Run("retry applies without an event", func(t *testing.T) {
+		reader := NewReader(strings.NewReader("retry: 250\n\ndata: x\n\n"))
+		event, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), event.Retry)
+		assert.Equal(t, 250*time.Millisecond, reader.Retry())
+	})
+
+	t.Run("unterminated event is discarded", func(t *testing.T) {
+		reader := NewReader(strings.NewReader("data: partial\n"))
+		_, err := reader.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+}
@@ -0,0 +1,330 @@
+// Package uritemplate implements the expansion of RFC 6570 URI templates up to level 3, and its reverse: matching a
+// URI against a template to recover the values of its variables.
+//
+// Matching is not always unambiguous. Values are matched greedily from left to right, and the variables of an
+// expression with several unnamed variables, such as {x,y}, are taken as defined in order, so {x,y} matching "a"
+// defines x only. Variables of the named operators ; ? and & are matched by name, in any order.
+package uritemplate
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Template is a parsed URI template
+type Template struct {
+	raw   string
+	parts []part
+	// Matches a whole URI, compiled from the parts
+	pattern *regexp.Regexp
+	// The number of literal characters, used to rank templates that match the same URI
+	literals int
+}
+
+// part is a literal or an expression of a template
+type part struct {
+	literal    string
+	expression *expression
+}
+
+type expression struct {
+	operator  operator
+	variables []string
+	// The index of the first submatch of each variable in the pattern, or of the whole expression for named operators
+	group int
+}
+
+// operator describes how the variables of an expression are expanded, see RFC 6570 appendix A
+type operator struct {
+	first         string
+	separator     string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+var operators = map[byte]operator{
+	'+': {first: "", separator: ",", allowReserved: true},
+	'#': {first: "#", separator: ",", allowReserved: true},
+	'.': {first: ".", separator: "."},
+	'/': {first: "/", separator: "/"},
+	';': {first: ";", separator: ";", named: true},
+	'?': {first: "?", separator: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", separator: "&", named: true, ifEmpty: "="},
+}
+
+var simple = operator{first: "", separator: ","}
+
+const (
+	unreservedSymbols = "-._~"
+	reservedSymbols   = ":/?#[]@!$&'()*+,;="
+)
+
+var variableName = regexp.MustCompile(`^(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2})(?:\.?(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2}))*$`)
+
+// valuePattern returns a regular expression matching the expansion of a value, without the characters of exclude
+func valuePattern(allowReserved bool, exclude string) string {
+	symbols := unreservedSymbols
+	if allowReserved {
+		symbols += reservedSymbols
+	}
+	var class strings.Builder
+	class.WriteString("A-Za-z0-9")
+	for _, c := range symbols {
+		if strings.ContainsRune(exclude, c) {
+			continue
+		}
+		if c == '-' {
+			class.WriteString(`\-`)
+		} else {
+			class.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return `(?:[` + class.String() + `]|%[0-9A-Fa-f]{2})*`
+}
+
+// Parse parses a URI template. Level 4 modifiers, the prefix :n and explode *, are not supported.
+func Parse(template string) (*Template, error) {
+	t := &Template{raw: template}
+	rest := template
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			start = len(rest)
+		}
+		if end := strings.IndexByte(rest[:start], '}'); end >= 0 {
+			return nil, fmt.Errorf("invalid uri template %q: unexpected }", template)
+		}
+		if start > 0 {
+			t.parts = append(t.parts, part{literal: rest[:start]})
+			t.literals += start
+		}
+		if start == len(rest) {
+			break
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid uri template %q: unclosed expression", template)
+		}
+		expression, err := parseExpression(rest[start+1 : start+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid uri template %q: %w", template, err)
+		}
+		t.parts = append(t.parts, part{expression: expression})
+		rest = rest[start+end+1:]
+	}
+
+	t.compile()
+	return t, nil
+}
+
+func parseExpression(body string) (*expression, error) {
+	op := simple
+	if body != "" {
+		if o, ok := operators[body[0]]; ok {
+			op = o
+			body = body[1:]
+		} else if strings.ContainsRune("=,!@|", rune(body[0])) {
+			return nil, fmt.Errorf("reserved operator %c", body[0])
+		}
+	}
+	if body == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	e := &expression{operator: op}
+	for _, name := range strings.Split(body, ",") {
+		if strings.ContainsAny(name, ":*") {
+			return nil, fmt.Errorf("level 4 modifiers are not supported: %s", name)
+		}
+		if !variableName.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable name %q", name)
+		}
+		e.variables = append(e.variables, name)
+	}
+	return e, nil
+}
+
+// compile builds the regular expression that matches the expansions of the template
+func (t *Template) compile() {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	group := 1
+	for _, p := range t.parts {
+		if p.expression == nil {
+			pattern.WriteString(regexp.QuoteMeta(p.literal))
+			continue
+		}
+
+		e := p.expression
+		e.group = group
+		op := e.operator
+		// The values of an expression with several variables cannot contain the separator, so that they can be told apart
+		exclude := ""
+		if len(e.variables) > 1 {
+			exclude = op.separator
+		}
+		value := valuePattern(op.allowReserved, exclude)
+
+		if op.named {
+			// One group for the whole expression, its name=value pairs are split after matching
+			names := make([]string, len(e.variables))
+			for i, name := range e.variables {
+				names[i] = regexp.QuoteMeta(name)
+			}
+			pair := `(?:` + strings.Join(names, "|") + `)(?:=` + value + `)?`
+			fmt.Fprintf(&pattern, `(?:%s(%s(?:%s%s)*))?`, regexp.QuoteMeta(op.first), pair, regexp.QuoteMeta(op.separator), pair)
+			group++
+			continue
+		}
+
+		// {x,y,z} is matched as x, optionally followed by y, optionally followed by z
+		var inner string
+		for i := len(e.variables) - 1; i >= 0; i-- {
+			separator := op.separator
+			if i == 0 {
+				separator = op.first
+			}
+			inner = `(?:` + regexp.QuoteMeta(separator) + `(` + value + `)` + inner + `)?`
+		}
+		pattern.WriteString(inner)
+		group += len(e.variables)
+	}
+	pattern.WriteString("$")
+	t.pattern = regexp.MustCompile(pattern.String())
+}
+
+// String returns the template as it was parsed
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Literals returns the number of literal characters of the template, outside of its expressions. Among the templates
+// that match a URI, the one with the most literal characters is the most specific.
+func (t *Template) Literals() int {
+	return t.literals
+}
+
+// Variables returns the names of the variables of the template, in order of appearance
+func (t *Template) Variables() []string {
+	var variables []string
+	for _, p := range t.parts {
+		if p.expression != nil {
+			variables = append(variables, p.expression.variables...)
+		}
+	}
+	return variables
+}
+
+// Expand expands the template with the given values. Variables without a value are undefined and left out.
+func (t *Template) Expand(values map[string]string) string {
+	var expanded strings.Builder
+	for _, p := range t.parts {
+		if p.expression == nil {
+			expanded.WriteString(p.literal)
+			continue
+		}
+
+		op := p.expression.operator
+		first := true
+		for _, name := range p.expression.variables {
+			value, ok := values[name]
+			if !ok {
+				continue
+			}
+			if first {
+				expanded.WriteString(op.first)
+				first = false
+			} else {
+				expanded.WriteString(op.separator)
+			}
+			if op.named {
+				expanded.WriteString(name)
+				if value == "" {
+					expanded.WriteString(op.ifEmpty)
+					continue
+				}
+				expanded.WriteString("=")
+			}
+			expanded.WriteString(encode(value, op.allowReserved))
+		}
+	}
+	return expanded.String()
+}
+
+// Match reports whether uri is an expansion of the template, and returns the values of the variables defined in it
+func (t *Template) Match(uri string) (map[string]string, bool) {
+	submatches := t.pattern.FindStringSubmatchIndex(uri)
+	if submatches == nil {
+		return nil, false
+	}
+
+	values := make(map[string]string)
+	for _, p := range t.parts {
+		e := p.expression
+		if e == nil {
+			continue
+		}
+
+		if !e.operator.named {
+			for i, name := range e.variables {
+				start, end := submatches[2*(e.group+i)], submatches[2*(e.group+i)+1]
+				if start < 0 {
+					continue
+				}
+				value, err := url.PathUnescape(uri[start:end])
+				if err != nil {
+					return nil, false
+				}
+				values[name] = value
+			}
+			continue
+		}
+
+		start, end := submatches[2*e.group], submatches[2*e.group+1]
+		if start < 0 {
+			continue
+		}
+		for _, pair := range strings.Split(uri[start:end], e.operator.separator) {
+			name, value, _ := strings.Cut(pair, "=")
+			value, err := url.PathUnescape(value)
+			if err != nil {
+				return nil, false
+			}
+			values[name] = value
+		}
+	}
+	return values, true
+}
+
+// encode percent-encodes the characters of value that are not allowed in an expansion. Reserved characters and
+// percent-encoded triplets are kept when allowReserved is set.
+func encode(value string, allowReserved bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case isUnreserved(c):
+			encoded.WriteByte(c)
+		case allowReserved && strings.IndexByte(reservedSymbols, c) >= 0:
+			encoded.WriteByte(c)
+		case allowReserved && c == '%' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]):
+			encoded.WriteString(value[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte(unreservedSymbols, c) >= 0
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
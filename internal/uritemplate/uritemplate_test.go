@@ -0,0 +1,115 @@
+package uritemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The examples of RFC 6570 section 3.2 for levels 1 to 3
+var rfcValues = map[string]string{
+	"var":   "value",
+	"hello": "Hello World!",
+	"path":  "/foo/bar",
+	"empty": "",
+	"x":     "1024",
+	"y":     "768",
+}
+
+var rfcExamples = []struct {
+	template string
+	expanded string
+}{
+	{"{var}", "value"},
+	{"{hello}", "Hello%20World%21"},
+	{"{+var}", "value"},
+	{"{+hello}", "Hello%20World!"},
+	{"{+path}/here", "/foo/bar/here"},
+	{"here?ref={+path}", "here?ref=/foo/bar"},
+	{"X{#var}", "X#value"},
+	{"X{#hello}", "X#Hello%20World!"},
+	{"map?{x,y}", "map?1024,768"},
+	{"{x,hello,y}", "1024,Hello%20World%21,768"},
+	{"{+x,hello,y}", "1024,Hello%20World!,768"},
+	{"{+path,x}/here", "/foo/bar,1024/here"},
+	{"{#x,hello,y}", "#1024,Hello%20World!,768"},
+	{"{#path,x}/here", "#/foo/bar,1024/here"},
+	{"X{.var}", "X.value"},
+	{"X{.x,y}", "X.1024.768"},
+	{"{/var}", "/value"},
+	{"{/var,x}/here", "/value/1024/here"},
+	{"{;x,y}", ";x=1024;y=768"},
+	{"{;x,y,empty}", ";x=1024;y=768;empty"},
+	{"{?x,y}", "?x=1024&y=768"},
+	{"{?x,y,empty}", "?x=1024&y=768&empty="},
+	{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+	{"{&x,y,empty}", "&x=1024&y=768&empty="},
+	{"{var}{undef}", "value"},
+	{"{?undef}", ""},
+}
+
+func TestExpand(t *testing.T) {
+	for _, example := range rfcExamples {
+		template, err := Parse(example.template)
+		require.NoError(t, err, example.template)
+		assert.Equal(t, example.expanded, template.Expand(rfcValues), example.template)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	t.Run("expansions match their template", func(t *testing.T) {
+		for _, example := range rfcExamples {
+			template, err := Parse(example.template)
+			require.NoError(t, err)
+			values, ok := template.Match(example.expanded)
+			require.True(t, ok, example.template)
+			assert.Equal(t, example.expanded, template.Expand(values), example.template)
+			for name, value := range values {
+				assert.Equal(t, rfcValues[name], value, "%s in %s", name, example.template)
+			}
+		}
+	})
+
+	tests := []struct {
+		template string
+		uri      string
+		values   map[string]string
+	}{
+		{"db://{table}/{id}", "db://users/42", map[string]string{"table": "users", "id": "42"}},
+		{"file:///{path}", "file:///notes.txt", map[string]string{"path": "notes.txt"}},
+		{"file:///{path}", "file:///dir/notes.txt", nil},
+		{"file:///{+path}", "file:///dir/notes.txt", map[string]string{"path": "dir/notes.txt"}},
+		{"file:///{name}", "file:///my%20notes", map[string]string{"name": "my notes"}},
+		{"search{?q,lang}", "search?lang=en&q=mcp", map[string]string{"q": "mcp", "lang": "en"}},
+		{"search{?q,lang}", "search?q=mcp", map[string]string{"q": "mcp"}},
+		{"search{?q}", "search?other=1", nil},
+		{"search{?q}{&page}", "search?q=mcp&page=2", map[string]string{"q": "mcp", "page": "2"}},
+		{"logs{/service,day}", "logs/api", map[string]string{"service": "api"}},
+		{"logs://{service}", "metrics://api", nil},
+	}
+	for _, test := range tests {
+		template, err := Parse(test.template)
+		require.NoError(t, err)
+		values, ok := template.Match(test.uri)
+		if test.values == nil {
+			assert.False(t, ok, "%s should not match %s", test.template, test.uri)
+			continue
+		}
+		require.True(t, ok, "%s should match %s", test.template, test.uri)
+		assert.Equal(t, test.values, values)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, invalid := range []string{"{", "}", "{}", "{var", "{=var}", "{var:3}", "{list*}", "{a b}"} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	template, err := Parse("db://{table}/{id}")
+	require.NoError(t, err)
+	assert.Equal(t, "db://{table}/{id}", template.String())
+	assert.Equal(t, 6, template.Literals())
+	assert.Equal(t, []string{"table", "id"}, template.Variables())
+}
@@ -0,0 +1,188 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/metoro-io/mcp-golang/internal/datastructures"
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// LoggingLevel is the severity of a log message sent by the server to the client, as defined by RFC 5424
+type LoggingLevel string
+
+const (
+	LoggingLevelDebug     LoggingLevel = "debug"
+	LoggingLevelInfo      LoggingLevel = "info"
+	LoggingLevelNotice    LoggingLevel = "notice"
+	LoggingLevelWarning   LoggingLevel = "warning"
+	LoggingLevelError     LoggingLevel = "error"
+	LoggingLevelCritical  LoggingLevel = "critical"
+	LoggingLevelAlert     LoggingLevel = "alert"
+	LoggingLevelEmergency LoggingLevel = "emergency"
+)
+
+// loggingLevels are the logging levels from the least to the most severe
+var loggingLevels = []LoggingLevel{
+	LoggingLevelDebug,
+	LoggingLevelInfo,
+	LoggingLevelNotice,
+	LoggingLevelWarning,
+	LoggingLevelError,
+	LoggingLevelCritical,
+	LoggingLevelAlert,
+	LoggingLevelEmergency,
+}
+
+// severity returns the position of the level in loggingLevels, or -1 if it is not a valid level
+func (l LoggingLevel) severity() int32 {
+	for i, level := range loggingLevels {
+		if level == l {
+			return int32(i)
+		}
+	}
+	return -1
+}
+
+// LogMessage is a log message sent by the server to the client with notifications/message
+type LogMessage struct {
+	Level LoggingLevel `json:"level" yaml:"level" mapstructure:"level"`
+	// The name of the logger that sent the message, optional
+	Logger string `json:"logger,omitempty" yaml:"logger,omitempty" mapstructure:"logger,omitempty"`
+	// Any JSON serializable data, such as a string or an object
+	Data interface{} `json:"data" yaml:"data" mapstructure:"data"`
+}
+
+type clientLoggerContextKey struct{}
+
+func (s *Server) handleSetLevel(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	var params struct {
+		Level LoggingLevel `json:"level"`
+	}
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
+	}
+	if params.Level.severity() < 0 {
+		return nil, NewRPCError(ErrorCodeInvalidParams, fmt.Sprintf("unknown logging level: %s", params.Level))
+	}
+	s.loggingLevels.Store(ClientLoggerFromContext(ctx).client, params.Level)
+	return map[string]interface{}{}, nil
+}
+
+// withLogging gives the handler of a request a ClientLogger for the client of pr that sent it
+func (s *Server) withLogging(pr *protocol.Protocol, handler func(context.Context, *transport.BaseJSONRPCRequest, protocol.RequestHandlerExtra) (transport.JsonRpcBody, error)) func(context.Context, *transport.BaseJSONRPCRequest, protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		logger := &ClientLogger{levels: s.loggingLevels, client: clientSessionFromContext(pr, ctx), ctx: ctx}
+		return handler(context.WithValue(ctx, clientLoggerContextKey{}, logger), request, extra)
+	}
+}
+
+// forgetLoggingLevels forgets the logging levels set by the clients of pr, once it is closed
+func (s *Server) forgetLoggingLevels(pr *protocol.Protocol) {
+	s.loggingLevels.Range(func(client clientSession, _ LoggingLevel) bool {
+		if client.protocol == pr {
+			s.loggingLevels.Delete(client)
+		}
+		return true
+	})
+}
+
+// ClientLogger sends log messages to the client with notifications/message. Messages less severe than the level the
+// client set with logging/setLevel are dropped. Every client of a transport that serves several clients at once, such
+// as SSE, has its own level.
+type ClientLogger struct {
+	// The logging levels set by the clients of the server, nil for a logger that drops every message
+	levels *datastructures.SyncMap[clientSession, LoggingLevel]
+	client clientSession
+	ctx    context.Context
+	name   string
+}
+
+// ClientLoggerFromContext returns the logger of the client that sent the request handled with ctx, in tool, prompt and
+// resource handlers. Outside of a handler it returns a logger that drops every message.
+func ClientLoggerFromContext(ctx context.Context) *ClientLogger {
+	if logger, ok := ctx.Value(clientLoggerContextKey{}).(*ClientLogger); ok {
+		return logger
+	}
+	return &ClientLogger{ctx: ctx}
+}
+
+// Named returns a logger that sends its messages with the given logger name
+func (l *ClientLogger) Named(name string) *ClientLogger {
+	return &ClientLogger{levels: l.levels, client: l.client, ctx: l.ctx, name: name}
+}
+
+// Log sends data to the client at the given level. Data can be any JSON serializable value.
+func (l *ClientLogger) Log(level LoggingLevel, data interface{}) error {
+	severity := level.severity()
+	if severity < 0 {
+		return errors.Errorf("unknown logging level: %s", level)
+	}
+	if l.levels == nil {
+		return nil
+	}
+	// Every message is sent until the client sets a level
+	if minimum, ok := l.levels.Load(l.client); ok && severity < minimum.severity() {
+		return nil
+	}
+	ctx := l.ctx
+	if l.client.session != "" {
+		ctx = transport.WithSession(ctx, l.client.session)
+	}
+	err := l.client.protocol.NotificationContext(ctx, "notifications/message", LogMessage{Level: level, Logger: l.name, Data: data})
+	return errors.Wrap(err, "failed to send log message")
+}
+
+// Debug sends data to the client at the debug level
+func (l *ClientLogger) Debug(data interface{}) error { return l.Log(LoggingLevelDebug, data) }
+
+// Info sends data to the client at the info level
+func (l *ClientLogger) Info(data interface{}) error { return l.Log(LoggingLevelInfo, data) }
+
+// Notice sends data to the client at the notice level
+func (l *ClientLogger) Notice(data interface{}) error { return l.Log(LoggingLevelNotice, data) }
+
+// Warning sends data to the client at the warning level
+func (l *ClientLogger) Warning(data interface{}) error { return l.Log(LoggingLevelWarning, data) }
+
+// Error sends data to the client at the error level
+func (l *ClientLogger) Error(data interface{}) error { return l.Log(LoggingLevelError, data) }
+
+// Critical sends data to the client at the critical level
+func (l *ClientLogger) Critical(data interface{}) error { return l.Log(LoggingLevelCritical, data) }
+
+// Alert sends data to the client at the alert level
+func (l *ClientLogger) Alert(data interface{}) error { return l.Log(LoggingLevelAlert, data) }
+
+// Emergency sends data to the client at the emergency level
+func (l *ClientLogger) Emergency(data interface{}) error { return l.Log(LoggingLevelEmergency, data) }
+
+// SetLoggingLevel asks the server to only send log messages at level or more severe
+func (c *Client) SetLoggingLevel(ctx context.Context, level LoggingLevel) error {
+	if !c.initialized {
+		return errors.New("client not initialized")
+	}
+
+	_, err := c.protocol.Request(ctx, "logging/setLevel", map[string]interface{}{"level": level}, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to set logging level")
+	}
+
+	return nil
+}
+
+// OnLogMessage sets the callback for the log messages sent by the server. It can be set before Initialize.
+// The callback is called for one message at a time, in the order the server sent them.
+func (c *Client) OnLogMessage(handler func(message *LogMessage)) {
+	c.protocol.SetNotificationHandler("notifications/message", func(notification *transport.BaseJSONRPCNotification) error {
+		var message LogMessage
+		if err := json.Unmarshal(notification.Params, &message); err != nil {
+			return errors.Wrap(err, "failed to unmarshal log message")
+		}
+		handler(&message)
+		return nil
+	})
+}
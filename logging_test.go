@@ -0,0 +1,139 @@
+package mcp_golang
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport/inmemory"
+	"github.com/metoro-io/mcp-golang/transport/sse"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientLogging(t *testing.T) {
+	clientTransport, serverTransport := inmemory.NewPair()
+	server := NewServer(serverTransport)
+	require.NoError(t, server.RegisterTool("work", "Logs its progress", func(ctx context.Context, args echoArgs) (*ToolResponse, error) {
+		logger := ClientLoggerFromContext(ctx).Named("work")
+		for _, level := range []LoggingLevel{LoggingLevelDebug, LoggingLevelWarning, LoggingLevelError} {
+			if err := logger.Log(level, map[string]interface{}{"message": args.Message}); err != nil {
+				return nil, err
+			}
+		}
+		return NewToolResponse(NewTextContent("done")), nil
+	}))
+	require.NoError(t, server.Serve())
+
+	messages := make(chan *LogMessage, 10)
+	client := NewClient(clientTransport)
+	client.OnLogMessage(func(message *LogMessage) {
+		messages <- message
+	})
+	t.Cleanup(func() { clientTransport.Close() })
+	initialized, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, initialized.Capabilities.Logging)
+
+	receive := func(count int) []LoggingLevel {
+		var levels []LoggingLevel
+		for i := 0; i < count; i++ {
+			select {
+			case message := <-messages:
+				assert.Equal(t, "work", message.Logger)
+				assert.Equal(t, map[string]interface{}{"message": "hello"}, message.Data)
+				levels = append(levels, message.Level)
+			case <-time.After(time.Second):
+				t.Fatalf("Expected %d log messages, got %d", count, i)
+			}
+		}
+		return levels
+	}
+
+	// Every message is sent until the client sets a level
+	_, err = client.CallTool(context.Background(), "work", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, []LoggingLevel{LoggingLevelDebug, LoggingLevelWarning, LoggingLevelError}, receive(3))
+
+	require.NoError(t, client.SetLoggingLevel(context.Background(), LoggingLevelWarning))
+	_, err = client.CallTool(context.Background(), "work", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, []LoggingLevel{LoggingLevelWarning, LoggingLevelError}, receive(2))
+	select {
+	case message := <-messages:
+		t.Errorf("Unexpected log message %+v", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	err = client.SetLoggingLevel(context.Background(), "verbose")
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+	assert.Equal(t, ErrorCodeInvalidParams, rpcErr.Code)
+
+	// Outside of handlers, messages are dropped
+	assert.NoError(t, ClientLoggerFromContext(context.Background()).Error("dropped"))
+	assert.Error(t, ClientLoggerFromContext(context.Background()).Log("verbose", "invalid"))
+}
+
+func TestClientLoggingSSESessions(t *testing.T) {
+	serverTransport := sse.NewSSEServerTransport("/messages")
+	server := NewServer(serverTransport)
+	require.NoError(t, server.RegisterTool("work", "Logs its progress", func(ctx context.Context, args echoArgs) (*ToolResponse, error) {
+		logger := ClientLoggerFromContext(ctx)
+		for _, level := range []LoggingLevel{LoggingLevelDebug, LoggingLevelError} {
+			if err := logger.Log(level, args.Message); err != nil {
+				return nil, err
+			}
+		}
+		return NewToolResponse(NewTextContent("done")), nil
+	}))
+	require.NoError(t, server.Serve())
+	httpServer := httptest.NewServer(serverTransport)
+	// Cleanups run last in first out, so the event streams of the clients end before the server closes
+	t.Cleanup(httpServer.Close)
+	ctx := context.Background()
+
+	// Both clients share the main transport of the server, each with its own SSE session
+	connect := func(level LoggingLevel) (*Client, chan *LogMessage) {
+		clientTransport := sse.NewSSEClientTransport(httpServer.URL + "/sse")
+		t.Cleanup(func() { clientTransport.Close() })
+		client := NewClient(clientTransport)
+		messages := make(chan *LogMessage, 10)
+		client.OnLogMessage(func(message *LogMessage) {
+			messages <- message
+		})
+		_, err := client.Initialize(ctx)
+		require.NoError(t, err)
+		require.NoError(t, client.SetLoggingLevel(ctx, level))
+		return client, messages
+	}
+	receive := func(messages chan *LogMessage, count int) []LoggingLevel {
+		var levels []LoggingLevel
+		for i := 0; i < count; i++ {
+			select {
+			case message := <-messages:
+				levels = append(levels, message.Level)
+			case <-time.After(time.Second):
+				t.Fatalf("Expected %d log messages, got %d", count, i)
+			}
+		}
+		select {
+		case message := <-messages:
+			t.Errorf("Unexpected log message %+v", message)
+		case <-time.After(50 * time.Millisecond):
+		}
+		return levels
+	}
+
+	// The second client sets its level last, it must not change the level of the first one
+	debug, debugMessages := connect(LoggingLevelDebug)
+	errorsOnly, errorMessages := connect(LoggingLevelError)
+	for _, client := range []*Client{debug, errorsOnly} {
+		_, err := client.CallTool(ctx, "work", echoArgs{Message: "hello"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []LoggingLevel{LoggingLevelDebug, LoggingLevelError}, receive(debugMessages, 2))
+	assert.Equal(t, []LoggingLevel{LoggingLevelError}, receive(errorMessages, 1))
+}
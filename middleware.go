@@ -0,0 +1,31 @@
+package mcp_golang
+
+import "github.com/metoro-io/mcp-golang/internal/protocol"
+
+// Request is a request or a notification received by the server, as seen by middleware. Session is empty for messages
+// received on the server's main transport.
+type Request = protocol.Request
+
+// Handler handles a request or a notification and returns the result to answer it with
+type Handler = protocol.Handler
+
+// Middleware wraps the handling of every request and notification received by the server. It sees the method, params
+// and session of the message and the result or error of the handler, and can answer without calling next, for
+// example with an *RPCError:
+//
+//	server.Use(func(next mcp_golang.Handler) mcp_golang.Handler {
+//		return func(ctx context.Context, request *mcp_golang.Request) (transport.JsonRpcBody, error) {
+//			if !authorized(ctx) {
+//				return nil, mcp_golang.NewRPCError(-32001, "unauthorized")
+//			}
+//			return next(ctx, request)
+//		}
+//	})
+type Middleware = protocol.Middleware
+
+// Use adds middleware around the handling of every request and notification, on the main transport and every session.
+// The first middleware is the outermost one. Use should be called before the server is served.
+func (s *Server) Use(middleware ...Middleware) {
+	s.middleware = append(s.middleware, middleware...)
+	s.protocol.Use(middleware...)
+}
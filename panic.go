@@ -0,0 +1,49 @@
+package mcp_golang
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// WithOnPanic sets a hook that is called with the value and stack trace of every panic recovered from a handler, after
+// it has been logged, for example to send it to an error tracker. A panic in a tool is answered with an error result,
+// a panic anywhere else with an internal JSON-RPC error.
+// A protocol given with WithProtocol keeps its own hook.
+func WithOnPanic(hook func(recovered any, stack []byte)) ServerOptions {
+	return func(s *Server) {
+		s.onPanic = hook
+	}
+}
+
+// toolPanic is the error of a tool call whose handler or interceptors panicked
+type toolPanic struct {
+	tool      string
+	recovered any
+	stack     []byte
+}
+
+func (p *toolPanic) Error() string {
+	return fmt.Sprintf("tool %s panicked: %v", p.tool, p.recovered)
+}
+
+// callTool runs handler, turning a panic into a *toolPanic error
+func callTool(ctx context.Context, handler ToolHandler, call *ToolCall) (response *ToolResponse, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			response = nil
+			err = &toolPanic{tool: call.Name, recovered: recovered, stack: debug.Stack()}
+		}
+	}()
+	return handler(ctx, call)
+}
+
+// reportToolPanic logs a panic recovered from a tool and passes it to the panic hook
+func (s *Server) reportToolPanic(p *toolPanic) {
+	if s.logger != nil {
+		s.logger.Error("tool panicked", "method", "tools/call", "tool", p.tool, "panic", p.recovered, "stack", string(p.stack))
+	}
+	if s.onPanic != nil {
+		s.onPanic(p.recovered, p.stack)
+	}
+}
@@ -0,0 +1,60 @@
+package mcp_golang
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/transport/inmemory"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	var mu sync.Mutex
+	var panics []any
+	clientTransport, serverTransport := inmemory.NewPair()
+	server := NewServer(serverTransport, WithOnPanic(func(recovered any, stack []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.NotEmpty(t, stack)
+		panics = append(panics, recovered)
+	}))
+	registerEcho(t, server)
+	require.NoError(t, server.RegisterTool("panic", "Panics", func(args echoArgs) (*ToolResponse, error) {
+		panic(args.Message)
+	}))
+	require.NoError(t, server.RegisterPrompt("panic", "Panics", func(args echoArgs) (*PromptResponse, error) {
+		panic(args.Message)
+	}))
+	require.NoError(t, server.Serve())
+	client := NewClient(clientTransport)
+	t.Cleanup(func() { clientTransport.Close() })
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+
+	t.Run("tools answer with an error result", func(t *testing.T) {
+		response, err := client.CallTool(context.Background(), "panic", echoArgs{Message: "tool boom"})
+		require.NoError(t, err)
+		assert.Equal(t, "tool panic panicked: tool boom", response.Content[0].TextContent.Text)
+	})
+
+	t.Run("other handlers answer with an internal error", func(t *testing.T) {
+		_, err := client.GetPrompt(context.Background(), "panic", echoArgs{Message: "prompt boom"})
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+		assert.Equal(t, ErrorCodeInternalError, rpcErr.Code)
+		assert.Equal(t, "handler panicked: prompt boom", rpcErr.Message)
+	})
+
+	t.Run("the server keeps serving", func(t *testing.T) {
+		response, err := client.CallTool(context.Background(), "echo", echoArgs{Message: "still here"})
+		require.NoError(t, err)
+		assert.Equal(t, "still here", response.Content[0].TextContent.Text)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []any{"tool boom", "prompt boom"}, panics)
+}
@@ -0,0 +1,163 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+type resourceSubscriptionParams struct {
+	Uri string `json:"uri" yaml:"uri" mapstructure:"uri"`
+}
+
+// resourceSubscriptions tracks the sessions subscribed to each resource
+type resourceSubscriptions struct {
+	mu          sync.Mutex
+	subscribers map[string]map[clientSession]struct{}
+}
+
+func newResourceSubscriptions() *resourceSubscriptions {
+	return &resourceSubscriptions{subscribers: make(map[string]map[clientSession]struct{})}
+}
+
+func (r *resourceSubscriptions) subscribe(uri string, subscriber clientSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.subscribers[uri] == nil {
+		r.subscribers[uri] = make(map[clientSession]struct{})
+	}
+	r.subscribers[uri][subscriber] = struct{}{}
+}
+
+func (r *resourceSubscriptions) unsubscribe(uri string, subscriber clientSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subscribers[uri], subscriber)
+	if len(r.subscribers[uri]) == 0 {
+		delete(r.subscribers, uri)
+	}
+}
+
+// unsubscribeAll removes every subscription of the sessions that match, when they end
+func (r *resourceSubscriptions) unsubscribeAll(match func(subscriber clientSession) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uri, subscribers := range r.subscribers {
+		for subscriber := range subscribers {
+			if match(subscriber) {
+				delete(subscribers, subscriber)
+			}
+		}
+		if len(subscribers) == 0 {
+			delete(r.subscribers, uri)
+		}
+	}
+}
+
+// unsubscribeProtocol removes every subscription of the sessions served with pr
+func (r *resourceSubscriptions) unsubscribeProtocol(pr *protocol.Protocol) {
+	r.unsubscribeAll(func(subscriber clientSession) bool { return subscriber.protocol == pr })
+}
+
+// unsubscribeSession removes every subscription of a transport session served with pr
+func (r *resourceSubscriptions) unsubscribeSession(pr *protocol.Protocol, session string) {
+	r.unsubscribeAll(func(subscriber clientSession) bool {
+		return subscriber.protocol == pr && subscriber.session == session
+	})
+}
+
+func (r *resourceSubscriptions) subscribersOf(uri string) []clientSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscribers := make([]clientSession, 0, len(r.subscribers[uri]))
+	for subscriber := range r.subscribers[uri] {
+		subscribers = append(subscribers, subscriber)
+	}
+	return subscribers
+}
+
+// handleResourceSubscription returns the handler of resources/subscribe or resources/unsubscribe for the sessions
+// served with pr, which applies update to the subscriptions of the session that sent the request
+func handleResourceSubscription(pr *protocol.Protocol, update func(uri string, subscriber clientSession)) func(context.Context, *transport.BaseJSONRPCRequest, protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		var params resourceSubscriptionParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
+		}
+		if params.Uri == "" {
+			return nil, NewRPCError(ErrorCodeInvalidParams, "missing resource uri")
+		}
+		update(params.Uri, clientSessionFromContext(pr, ctx))
+		return map[string]interface{}{}, nil
+	}
+}
+
+// NotifyResourceUpdated tells the clients subscribed to the resource with the given uri that it changed, with a
+// notifications/resources/updated notification. Every subscriber is notified even if sending to one fails, the first
+// error is returned.
+func (s *Server) NotifyResourceUpdated(uri string) error {
+	var firstErr error
+	for _, subscriber := range s.subscriptions.subscribersOf(uri) {
+		ctx := context.Background()
+		if subscriber.session != "" {
+			ctx = transport.WithSession(ctx, subscriber.session)
+		}
+		if err := subscriber.protocol.NotificationContext(ctx, "notifications/resources/updated", resourceSubscriptionParams{Uri: uri}); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SubscribeResource subscribes to updates of the resource with the given uri. The callback is called with the uri
+// every time the server reports that the resource changed, until UnsubscribeResource is called.
+func (c *Client) SubscribeResource(ctx context.Context, uri string, callback func(uri string)) error {
+	if !c.initialized {
+		return errors.New("client not initialized")
+	}
+
+	// The callback is registered first so that no update sent before the response is missed
+	previous, hadPrevious := c.resourceSubscriptions.Load(uri)
+	c.resourceSubscriptions.Store(uri, callback)
+	_, err := c.protocol.Request(ctx, "resources/subscribe", resourceSubscriptionParams{Uri: uri}, nil)
+	if err != nil {
+		if hadPrevious {
+			c.resourceSubscriptions.Store(uri, previous)
+		} else {
+			c.resourceSubscriptions.Delete(uri)
+		}
+		return errors.Wrap(err, "failed to subscribe to resource")
+	}
+
+	return nil
+}
+
+// UnsubscribeResource stops the updates of the resource with the given uri
+func (c *Client) UnsubscribeResource(ctx context.Context, uri string) error {
+	if !c.initialized {
+		return errors.New("client not initialized")
+	}
+
+	_, err := c.protocol.Request(ctx, "resources/unsubscribe", resourceSubscriptionParams{Uri: uri}, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to unsubscribe from resource")
+	}
+	c.resourceSubscriptions.Delete(uri)
+
+	return nil
+}
+
+func (c *Client) handleResourceUpdated(notification *transport.BaseJSONRPCNotification) error {
+	var params resourceSubscriptionParams
+	if err := json.Unmarshal(notification.Params, &params); err != nil {
+		return errors.Wrap(err, "failed to unmarshal resource update")
+	}
+	if callback, ok := c.resourceSubscriptions.Load(params.Uri); ok {
+		callback(params.Uri)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package mcp_golang
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport/inmemory"
+	"github.com/metoro-io/mcp-golang/transport/sse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceSubscriptions(t *testing.T) {
+	server := NewServer(nil)
+	ctx := context.Background()
+
+	connect := func() (*Client, *inmemory.InMemoryTransport) {
+		clientTransport, serverTransport := inmemory.NewPair()
+		require.NoError(t, server.ServeSession(serverTransport))
+		client := NewClient(clientTransport)
+		initialized, err := client.Initialize(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, initialized.Capabilities.Resources.Subscribe)
+		assert.True(t, *initialized.Capabilities.Resources.Subscribe)
+		return client, clientTransport
+	}
+	logs, logsTransport := connect()
+	metrics, metricsTransport := connect()
+	defer metricsTransport.Close()
+
+	logUpdates := make(chan string, 10)
+	metricUpdates := make(chan string, 10)
+	require.NoError(t, logs.SubscribeResource(ctx, "file:///var/log/app.log", func(uri string) { logUpdates <- uri }))
+	require.NoError(t, metrics.SubscribeResource(ctx, "metrics://cpu", func(uri string) { metricUpdates <- uri }))
+
+	expectUpdate := func(updates chan string, uri string) {
+		select {
+		case updated := <-updates:
+			assert.Equal(t, uri, updated)
+		case <-time.After(time.Second):
+			t.Fatalf("No update of %s", uri)
+		}
+	}
+	expectNoUpdate := func(updates chan string) {
+		select {
+		case updated := <-updates:
+			t.Errorf("Unexpected update of %s", updated)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// Only subscribers are notified
+	require.NoError(t, server.NotifyResourceUpdated("file:///var/log/app.log"))
+	expectUpdate(logUpdates, "file:///var/log/app.log")
+	expectNoUpdate(metricUpdates)
+
+	require.NoError(t, server.NotifyResourceUpdated("metrics://cpu"))
+	expectUpdate(metricUpdates, "metrics://cpu")
+	expectNoUpdate(logUpdates)
+
+	require.NoError(t, metrics.UnsubscribeResource(ctx, "metrics://cpu"))
+	require.NoError(t, server.NotifyResourceUpdated("metrics://cpu"))
+	expectNoUpdate(metricUpdates)
+
+	// The subscriptions of a session end with it
+	require.NoError(t, logsTransport.Close())
+	assert.Eventually(t, func() bool {
+		return len(server.subscriptions.subscribersOf("file:///var/log/app.log")) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestResourceSubscriptionsSSESessions(t *testing.T) {
+	serverTransport := sse.NewSSEServerTransport("/messages")
+	server := NewServer(serverTransport)
+	require.NoError(t, server.Serve())
+	httpServer := httptest.NewServer(serverTransport)
+	defer httpServer.Close()
+	ctx := context.Background()
+
+	// Both clients share the main transport of the server, each with its own SSE session
+	connect := func() (*Client, *sse.SSEClientTransport) {
+		clientTransport := sse.NewSSEClientTransport(httpServer.URL + "/sse")
+		client := NewClient(clientTransport)
+		_, err := client.Initialize(ctx)
+		require.NoError(t, err)
+		return client, clientTransport
+	}
+	subscriber, subscriberTransport := connect()
+	other, otherTransport := connect()
+	defer otherTransport.Close()
+
+	subscriberUpdates := make(chan string, 10)
+	otherUpdates := make(chan string, 10)
+	require.NoError(t, subscriber.SubscribeResource(ctx, "file:///var/log/app.log", func(uri string) { subscriberUpdates <- uri }))
+	// A callback that the server must never trigger, since this client did not subscribe
+	other.resourceSubscriptions.Store("file:///var/log/app.log", func(uri string) { otherUpdates <- uri })
+
+	require.NoError(t, server.NotifyResourceUpdated("file:///var/log/app.log"))
+	select {
+	case updated := <-subscriberUpdates:
+		assert.Equal(t, "file:///var/log/app.log", updated)
+	case <-time.After(time.Second):
+		t.Fatal("No update for the subscribed session")
+	}
+	select {
+	case updated := <-otherUpdates:
+		t.Errorf("Unexpected update of %s in a session that did not subscribe", updated)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The subscriptions of a session end with its event stream
+	require.NoError(t, subscriberTransport.Close())
+	assert.Eventually(t, func() bool {
+		return len(server.subscriptions.subscribersOf("file:///var/log/app.log")) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,195 @@
+package mcp_golang
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// createWrappedResourceTemplateHandler returns a handler that fills the argument struct of userHandler with the values
+// of the template variables, then calls it
+func createWrappedResourceTemplateHandler(userHandler any) func(ctx context.Context, uri string, values map[string]string) *resourceResponseSent {
+	handlerValue := reflect.ValueOf(userHandler)
+	handlerType := handlerValue.Type()
+	argumentType := handlerType.In(handlerType.NumIn() - 1)
+	return func(ctx context.Context, uri string, values map[string]string) *resourceResponseSent {
+		arguments := reflect.New(argumentType).Elem()
+		if err := fillTemplateArguments(arguments, values); err != nil {
+			return &resourceResponseSent{Uri: uri, Error: NewRPCError(ErrorCodeInvalidParams, err.Error())}
+		}
+
+		args := []reflect.Value{arguments}
+		if handlerType.NumIn() == 2 {
+			args = []reflect.Value{reflect.ValueOf(ctx), arguments}
+		}
+		output := handlerValue.Call(args)
+
+		if err, ok := output[1].Interface().(error); ok && err != nil {
+			return &resourceResponseSent{Uri: uri, Error: err}
+		}
+		response, _ := output[0].Interface().(*ResourceResponse)
+		if response == nil {
+			return &resourceResponseSent{Uri: uri, Error: fmt.Errorf("handler returned a nil response")}
+		}
+		return &resourceResponseSent{Response: response, Uri: uri}
+	}
+}
+
+// validateResourceTemplateHandler checks that handler takes an optional context.Context and an argument struct, and
+// returns a *ResourceResponse and an error
+func validateResourceTemplateHandler(handler any) error {
+	handlerType := reflect.TypeOf(handler)
+	if handlerType == nil || handlerType.Kind() != reflect.Func {
+		return fmt.Errorf("handler must be a function, got %T", handler)
+	}
+	if handlerType.NumIn() != 1 && handlerType.NumIn() != 2 {
+		return fmt.Errorf("handler must take one or two arguments, got %d", handlerType.NumIn())
+	}
+	if handlerType.NumIn() == 2 && handlerType.In(0) != reflect.TypeOf((*context.Context)(nil)).Elem() {
+		return fmt.Errorf("when a handler has 2 arguments, the first one must be context.Context, got %s", handlerType.In(0))
+	}
+	argumentType := handlerType.In(handlerType.NumIn() - 1)
+	if argumentType.Kind() != reflect.Struct {
+		return fmt.Errorf("the arguments of the handler must be a struct, got %s", argumentType)
+	}
+	for i := 0; i < argumentType.NumField(); i++ {
+		field := argumentType.Field(i)
+		if !field.IsExported() || templateVariableName(field) == "" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("unsupported type %s for template variable %s", field.Type, field.Name)
+		}
+	}
+
+	if handlerType.NumOut() != 2 {
+		return fmt.Errorf("handler must return exactly two values, got %d", handlerType.NumOut())
+	}
+	if handlerType.Out(0) != reflect.TypeOf((*ResourceResponse)(nil)) {
+		return fmt.Errorf("handler must return *ResourceResponse, got %s", handlerType.Out(0))
+	}
+	if handlerType.Out(1) != reflect.TypeOf((*error)(nil)).Elem() {
+		return fmt.Errorf("handler must return error, got %s", handlerType.Out(1))
+	}
+	return nil
+}
+
+// templateVariableName returns the name of the template variable that fills a field: the name of its json tag, or
+// the name of the field without a tag. Fields tagged with json:"-" are not filled.
+func templateVariableName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fillTemplateArguments sets the fields of arguments to the values of the template variables. Names are matched like
+// encoding/json does, preferring an exact match to a case-insensitive one. Fields of undefined variables keep their
+// zero value.
+func fillTemplateArguments(arguments reflect.Value, values map[string]string) error {
+	for i := 0; i < arguments.NumField(); i++ {
+		field := arguments.Type().Field(i)
+		name := templateVariableName(field)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		value, ok := values[name]
+		if !ok {
+			for variable, v := range values {
+				if strings.EqualFold(variable, name) {
+					value, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		target := arguments.Field(i)
+		if target.Kind() == reflect.Pointer {
+			target.Set(reflect.New(target.Type().Elem()))
+			target = target.Elem()
+		}
+		if err := setTemplateValue(target, value); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", value, name, err)
+		}
+	}
+	return nil
+}
+
+func setTemplateValue(target reflect.Value, value string) error {
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
+
+// matchResourceTemplate returns the template with a handler that matches uri, and the values of its variables. When
+// several templates match, the one with the most literal characters wins, then the first by URI template.
+func (s *Server) matchResourceTemplate(uri string) (*resourceTemplate, map[string]string) {
+	var matches []*resourceTemplate
+	s.resourceTemplates.Range(func(k string, t *resourceTemplate) bool {
+		if t.Handler != nil {
+			if _, ok := t.template.Match(uri); ok {
+				matches = append(matches, t)
+			}
+		}
+		return true
+	})
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].template.Literals() != matches[j].template.Literals() {
+			return matches[i].template.Literals() > matches[j].template.Literals()
+		}
+		return matches[i].UriTemplate < matches[j].UriTemplate
+	})
+	values, _ := matches[0].template.Match(uri)
+	return matches[0], values
+}
@@ -0,0 +1,91 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceTemplates(t *testing.T) {
+	server := NewServer(nil)
+	ctx := context.Background()
+
+	type rowArguments struct {
+		Table string `json:"table"`
+		Id    int    `json:"id"`
+	}
+	require.NoError(t, server.RegisterResourceTemplateHandler("db://{table}/{id}", "row", "A row of a table", "application/json", func(ctx context.Context, arguments rowArguments) (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource(fmt.Sprintf("db://%s/%d", arguments.Table, arguments.Id), fmt.Sprintf("%s %d", arguments.Table, arguments.Id), "text/plain")), nil
+	}))
+	require.NoError(t, server.RegisterResourceTemplateHandler("db://users/{id}", "user", "A user", "application/json", func(arguments rowArguments) (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("db://users", fmt.Sprintf("user %d", arguments.Id), "text/plain")), nil
+	}))
+	require.NoError(t, server.RegisterResourceTemplateHandler("file:///{+path}", "file", "A file", "text/plain", func(arguments struct{ Path string }) (*ResourceResponse, error) {
+		if arguments.Path == "secret" {
+			return nil, errors.New("access denied")
+		}
+		return NewResourceResponse(NewTextEmbeddedResource("file:///"+arguments.Path, arguments.Path, "text/plain")), nil
+	}))
+	require.NoError(t, server.RegisterResourceTemplate("docs://{page}", "docs", "Listed only", "text/plain"))
+	require.NoError(t, server.RegisterResource("db://users/admin", "admin", "The admin", "text/plain", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("db://users/admin", "admin", "text/plain")), nil
+	}))
+
+	read := func(uri string) (string, error) {
+		response, err := server.handleResourceCalls(ctx, &transport.BaseJSONRPCRequest{
+			Params: json.RawMessage(fmt.Sprintf(`{"uri":%q}`, uri)),
+		}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			return "", err
+		}
+		marshaled, err := json.Marshal(response)
+		require.NoError(t, err)
+		var result struct {
+			Contents []struct {
+				Text string `json:"text"`
+			} `json:"contents"`
+		}
+		require.NoError(t, json.Unmarshal(marshaled, &result))
+		require.Len(t, result.Contents, 1)
+		return result.Contents[0].Text, nil
+	}
+
+	tests := []struct {
+		uri  string
+		text string
+	}{
+		// Template variables fill the argument struct
+		{"db://orders/7", "orders 7"},
+		// The template with the most literal characters wins
+		{"db://users/42", "user 42"},
+		// Exact resources come before templates
+		{"db://users/admin", "admin"},
+		{"file:///var/log/app.log", "var/log/app.log"},
+		{"file:///secret", "access denied"},
+	}
+	for _, test := range tests {
+		text, err := read(test.uri)
+		require.NoError(t, err, test.uri)
+		assert.Equal(t, test.text, text, test.uri)
+	}
+
+	// A value that cannot be converted is invalid params, a template without a handler is not read
+	for _, uri := range []string{"db://orders/first", "docs://intro", "unknown://x"} {
+		_, err := read(uri)
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr), uri)
+		assert.Equal(t, ErrorCodeInvalidParams, rpcErr.Code, uri)
+	}
+
+	// Invalid templates and handlers are rejected
+	assert.Error(t, server.RegisterResourceTemplate("db://{table", "broken", "", ""))
+	assert.Error(t, server.RegisterResourceTemplateHandler("db://{table}", "broken", "", "", func(table string) (*ResourceResponse, error) { return nil, nil }))
+	assert.Error(t, server.RegisterResourceTemplateHandler("db://{table}", "broken", "", "", func(arguments struct{ Table []string }) (*ResourceResponse, error) { return nil, nil }))
+}
@@ -0,0 +1,33 @@
+package mcp_golang
+
+import (
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// RPCError is a JSON-RPC error with a code, a message and optional data.
+//
+// Tool, prompt and resource handlers can return an *RPCError, possibly wrapped, to fail the request with a JSON-RPC
+// error instead of an error result. Client calls that fail with an error response return it as an *RPCError, which can
+// be retrieved with errors.As.
+type RPCError = transport.RPCError
+
+// Standard JSON-RPC error codes
+const (
+	ErrorCodeParseError     = transport.ErrorCodeParseError
+	ErrorCodeInvalidRequest = transport.ErrorCodeInvalidRequest
+	ErrorCodeMethodNotFound = transport.ErrorCodeMethodNotFound
+	ErrorCodeInvalidParams  = transport.ErrorCodeInvalidParams
+	ErrorCodeInternalError  = transport.ErrorCodeInternalError
+)
+
+// NewRPCError creates an error with the given code and message
+func NewRPCError(code int, message string) *RPCError {
+	return transport.NewRPCError(code, message)
+}
+
+// isRPCError reports whether err is or wraps an *RPCError
+func isRPCError(err error) bool {
+	var rpcErr *RPCError
+	return err != nil && errors.As(err, &rpcErr)
+}
@@ -0,0 +1,196 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// ErrSamplingNotSupported is returned by Sampler.CreateMessage when the client did not advertise the sampling
+// capability, or outside of a handler
+var ErrSamplingNotSupported = errors.New("the client does not support sampling")
+
+// IncludeContext is the context of MCP servers that the client should include in the prompt of a sampling request
+type IncludeContext string
+
+const (
+	IncludeContextNone       IncludeContext = "none"
+	IncludeContextThisServer IncludeContext = "thisServer"
+	IncludeContextAllServers IncludeContext = "allServers"
+)
+
+// Reasons for the client to stop sampling
+const (
+	StopReasonEndTurn      = "endTurn"
+	StopReasonStopSequence = "stopSequence"
+	StopReasonMaxTokens    = "maxTokens"
+)
+
+// SamplingMessage is a message of the conversation sampled by the client
+type SamplingMessage struct {
+	Role Role `json:"role" yaml:"role" mapstructure:"role"`
+	// Text or image content
+	Content *Content `json:"content" yaml:"content" mapstructure:"content"`
+}
+
+// NewSamplingMessage creates a message for a sampling request
+func NewSamplingMessage(content *Content, role Role) *SamplingMessage {
+	return &SamplingMessage{Role: role, Content: content}
+}
+
+// ModelHint suggests a model for sampling. The client may map it to a different model from another provider.
+type ModelHint struct {
+	// A full or partial model name, such as "claude-3-5-sonnet" or "sonnet"
+	Name string `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name,omitempty"`
+}
+
+// ModelPreferences are the preferences of the server for the model the client samples. Priorities go from 0 to 1, and
+// the client is free to ignore them.
+type ModelPreferences struct {
+	// Models to consider, in order of preference
+	Hints                []ModelHint `json:"hints,omitempty" yaml:"hints,omitempty" mapstructure:"hints,omitempty"`
+	CostPriority         *float64    `json:"costPriority,omitempty" yaml:"costPriority,omitempty" mapstructure:"costPriority,omitempty"`
+	SpeedPriority        *float64    `json:"speedPriority,omitempty" yaml:"speedPriority,omitempty" mapstructure:"speedPriority,omitempty"`
+	IntelligencePriority *float64    `json:"intelligencePriority,omitempty" yaml:"intelligencePriority,omitempty" mapstructure:"intelligencePriority,omitempty"`
+}
+
+// CreateMessageRequest asks the client to sample its LLM, with sampling/createMessage. The client may modify or
+// reject the request, usually after asking the user.
+type CreateMessageRequest struct {
+	Messages         []*SamplingMessage `json:"messages" yaml:"messages" mapstructure:"messages"`
+	ModelPreferences *ModelPreferences  `json:"modelPreferences,omitempty" yaml:"modelPreferences,omitempty" mapstructure:"modelPreferences,omitempty"`
+	SystemPrompt     string             `json:"systemPrompt,omitempty" yaml:"systemPrompt,omitempty" mapstructure:"systemPrompt,omitempty"`
+	IncludeContext   IncludeContext     `json:"includeContext,omitempty" yaml:"includeContext,omitempty" mapstructure:"includeContext,omitempty"`
+	Temperature      *float64           `json:"temperature,omitempty" yaml:"temperature,omitempty" mapstructure:"temperature,omitempty"`
+	// The maximum number of tokens to sample, required
+	MaxTokens     int      `json:"maxTokens" yaml:"maxTokens" mapstructure:"maxTokens"`
+	StopSequences []string `json:"stopSequences,omitempty" yaml:"stopSequences,omitempty" mapstructure:"stopSequences,omitempty"`
+	// Provider specific metadata passed to the LLM
+	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty" mapstructure:"metadata,omitempty"`
+}
+
+// CreateMessageResult is the message sampled by the client
+type CreateMessageResult struct {
+	Role    Role     `json:"role" yaml:"role" mapstructure:"role"`
+	Content *Content `json:"content" yaml:"content" mapstructure:"content"`
+	// The name of the model that sampled the message
+	Model string `json:"model" yaml:"model" mapstructure:"model"`
+	// Why sampling stopped, such as StopReasonEndTurn, if known
+	StopReason string `json:"stopReason,omitempty" yaml:"stopReason,omitempty" mapstructure:"stopReason,omitempty"`
+}
+
+// Sampler sends sampling requests to the client that sent the request being handled. Every client of a transport that
+// serves several clients at once, such as SSE, has its own sampling capability.
+type Sampler struct {
+	client clientSession
+	// Whether the client advertised the sampling capability in its initialize request
+	supported bool
+}
+
+type samplerContextKey struct{}
+
+// SamplerFromContext returns the sampler of the client that sent the request handled with ctx, in tool, prompt and
+// resource handlers. Outside of a handler it returns a sampler that fails with ErrSamplingNotSupported.
+func SamplerFromContext(ctx context.Context) *Sampler {
+	if sampler, ok := ctx.Value(samplerContextKey{}).(*Sampler); ok {
+		return sampler
+	}
+	return &Sampler{}
+}
+
+// Supported reports whether the client advertised the sampling capability
+func (s *Sampler) Supported() bool {
+	return s.client.protocol != nil && s.supported
+}
+
+// CreateMessage asks the client to sample its LLM and waits for the sampled message. It returns
+// ErrSamplingNotSupported if the client did not advertise the sampling capability.
+func (s *Sampler) CreateMessage(ctx context.Context, request *CreateMessageRequest) (*CreateMessageResult, error) {
+	if !s.Supported() {
+		return nil, ErrSamplingNotSupported
+	}
+	if request.MaxTokens <= 0 {
+		return nil, errors.Errorf("maxTokens must be positive, got %d", request.MaxTokens)
+	}
+
+	// The request goes to the transport session of the client, even if ctx is not the context of its handler
+	if s.client.session != "" {
+		ctx = transport.WithSession(ctx, s.client.session)
+	}
+	response, err := s.client.protocol.Request(ctx, "sampling/createMessage", request, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create message")
+	}
+
+	responseBytes, ok := response.(json.RawMessage)
+	if !ok {
+		return nil, errors.New("invalid response type")
+	}
+	var result CreateMessageResult
+	if err := json.Unmarshal(responseBytes, &result); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal sampling result")
+	}
+
+	return &result, nil
+}
+
+// withClientCapabilities records the capabilities a client of pr sent in its initialize request, then initializes
+func (s *Server) withClientCapabilities(pr *protocol.Protocol, handler func(context.Context, *transport.BaseJSONRPCRequest, protocol.RequestHandlerExtra) (transport.JsonRpcBody, error)) func(context.Context, *transport.BaseJSONRPCRequest, protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		var params struct {
+			Capabilities ClientCapabilities `json:"capabilities"`
+		}
+		// Clients that send no or malformed capabilities are served without the optional features
+		_ = json.Unmarshal(request.Params, &params)
+		client := clientSessionFromContext(pr, ctx)
+		if params.Capabilities.Sampling != nil {
+			s.samplingClients.Store(client, struct{}{})
+		} else {
+			s.samplingClients.Delete(client)
+		}
+		return handler(ctx, request, extra)
+	}
+}
+
+// withSampler gives the handler of a request a Sampler for the client of pr that sent it
+func (s *Server) withSampler(pr *protocol.Protocol, handler func(context.Context, *transport.BaseJSONRPCRequest, protocol.RequestHandlerExtra) (transport.JsonRpcBody, error)) func(context.Context, *transport.BaseJSONRPCRequest, protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		client := clientSessionFromContext(pr, ctx)
+		_, supported := s.samplingClients.Load(client)
+		return handler(context.WithValue(ctx, samplerContextKey{}, &Sampler{client: client, supported: supported}), request, extra)
+	}
+}
+
+// forgetSamplingClients forgets the sampling capability of the clients of pr, once it is closed
+func (s *Server) forgetSamplingClients(pr *protocol.Protocol) {
+	s.samplingClients.Range(func(client clientSession, _ struct{}) bool {
+		if client.protocol == pr {
+			s.samplingClients.Delete(client)
+		}
+		return true
+	})
+}
+
+// OnSamplingRequest sets the handler of the sampling/createMessage requests of the server, which typically asks the
+// user to approve the request, then samples the LLM of the host. It must be set before Initialize, for the client to
+// advertise the sampling capability. An error of the handler, such as an *RPCError, is returned to the server.
+func (c *Client) OnSamplingRequest(handler func(ctx context.Context, request *CreateMessageRequest) (*CreateMessageResult, error)) {
+	c.clientCapabilities.Sampling = &ClientCapabilitiesSampling{}
+	c.protocol.SetRequestHandler("sampling/createMessage", func(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		var params CreateMessageRequest
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
+		}
+		result, err := handler(ctx, &params)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			return nil, errors.New("sampling handler returned a nil result")
+		}
+		return result, nil
+	})
+}
@@ -0,0 +1,142 @@
+package mcp_golang
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/transport/inmemory"
+	"github.com/metoro-io/mcp-golang/transport/sse"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampling(t *testing.T) {
+	server := NewServer(nil)
+	ctx := context.Background()
+	require.NoError(t, server.RegisterTool("summarise", "Summarises a message with the LLM of the client", func(ctx context.Context, args echoArgs) (*ToolResponse, error) {
+		result, err := SamplerFromContext(ctx).CreateMessage(ctx, &CreateMessageRequest{
+			Messages:         []*SamplingMessage{NewSamplingMessage(NewTextContent(args.Message), RoleUser)},
+			ModelPreferences: &ModelPreferences{Hints: []ModelHint{{Name: "small"}}},
+			SystemPrompt:     "Summarise the message",
+			MaxTokens:        100,
+			StopSequences:    []string{"\n"},
+		})
+		if errors.Is(err, ErrSamplingNotSupported) {
+			return NewToolResponse(NewTextContent("sampling not supported")), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewToolResponse(NewTextContent(result.Model + ": " + result.Content.TextContent.Text)), nil
+	}))
+
+	connect := func(sampling bool) *Client {
+		clientTransport, serverTransport := inmemory.NewPair()
+		t.Cleanup(func() { clientTransport.Close() })
+		require.NoError(t, server.ServeSession(serverTransport))
+		client := NewClient(clientTransport)
+		if sampling {
+			client.OnSamplingRequest(func(ctx context.Context, request *CreateMessageRequest) (*CreateMessageResult, error) {
+				assert.Equal(t, "Summarise the message", request.SystemPrompt)
+				assert.Equal(t, 100, request.MaxTokens)
+				assert.Equal(t, []string{"\n"}, request.StopSequences)
+				assert.Equal(t, "small", request.ModelPreferences.Hints[0].Name)
+				require.Len(t, request.Messages, 1)
+				assert.Equal(t, RoleUser, request.Messages[0].Role)
+				if request.Messages[0].Content.TextContent.Text == "reject" {
+					return nil, NewRPCError(-1, "rejected by the user")
+				}
+				return &CreateMessageResult{
+					Role:       RoleAssistant,
+					Content:    NewTextContent("short " + request.Messages[0].Content.TextContent.Text),
+					Model:      "small-1",
+					StopReason: StopReasonEndTurn,
+				}, nil
+			})
+		}
+		_, err := client.Initialize(ctx)
+		require.NoError(t, err)
+		return client
+	}
+
+	text := func(response *ToolResponse) string {
+		require.Len(t, response.Content, 1)
+		return response.Content[0].TextContent.Text
+	}
+
+	sampling := connect(true)
+	response, err := sampling.CallTool(ctx, "summarise", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "small-1: short hello", text(response))
+
+	// A JSON-RPC error of the client is returned by the tool like any *RPCError
+	_, err = sampling.CallTool(ctx, "summarise", echoArgs{Message: "reject"})
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, "rejected by the user", rpcErr.Message)
+
+	// Sampling fails cleanly for clients without the capability
+	response, err = connect(false).CallTool(ctx, "summarise", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "sampling not supported", text(response))
+
+	_, err = SamplerFromContext(ctx).CreateMessage(ctx, &CreateMessageRequest{MaxTokens: 10})
+	assert.ErrorIs(t, err, ErrSamplingNotSupported)
+}
+
+func TestSamplingSSESessions(t *testing.T) {
+	serverTransport := sse.NewSSEServerTransport("/messages")
+	server := NewServer(serverTransport)
+	require.NoError(t, server.RegisterTool("summarise", "Summarises a message with the LLM of the client", func(ctx context.Context, args echoArgs) (*ToolResponse, error) {
+		result, err := SamplerFromContext(ctx).CreateMessage(ctx, &CreateMessageRequest{
+			Messages:  []*SamplingMessage{NewSamplingMessage(NewTextContent(args.Message), RoleUser)},
+			MaxTokens: 100,
+		})
+		if errors.Is(err, ErrSamplingNotSupported) {
+			return NewToolResponse(NewTextContent("sampling not supported")), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewToolResponse(NewTextContent(result.Content.TextContent.Text)), nil
+	}))
+	require.NoError(t, server.Serve())
+	httpServer := httptest.NewServer(serverTransport)
+	// Cleanups run last in first out, so the event streams of the clients end before the server closes
+	t.Cleanup(httpServer.Close)
+	ctx := context.Background()
+
+	// Both clients share the main transport of the server, each with its own SSE session
+	connect := func(sampling bool) *Client {
+		clientTransport := sse.NewSSEClientTransport(httpServer.URL + "/sse")
+		t.Cleanup(func() { clientTransport.Close() })
+		client := NewClient(clientTransport)
+		if sampling {
+			client.OnSamplingRequest(func(ctx context.Context, request *CreateMessageRequest) (*CreateMessageResult, error) {
+				return &CreateMessageResult{
+					Role:    RoleAssistant,
+					Content: NewTextContent("short " + request.Messages[0].Content.TextContent.Text),
+					Model:   "small-1",
+				}, nil
+			})
+		}
+		_, err := client.Initialize(ctx)
+		require.NoError(t, err)
+		return client
+	}
+	// The client without sampling initializes last, it must not change the capability of the other one
+	sampling := connect(true)
+	other := connect(false)
+
+	response, err := sampling.CallTool(ctx, "summarise", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	require.Len(t, response.Content, 1)
+	assert.Equal(t, "short hello", response.Content[0].TextContent.Text)
+
+	response, err = other.CallTool(ctx, "summarise", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	require.Len(t, response.Content, 1)
+	assert.Equal(t, "sampling not supported", response.Content[0].TextContent.Text)
+}
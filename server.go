@@ -5,13 +5,17 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/invopop/jsonschema"
 	"github.com/metoro-io/mcp-golang/internal/datastructures"
 	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/internal/uritemplate"
 	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/pkg/errors"
 )
@@ -100,17 +104,48 @@ func (c promptResponseSent) MarshalJSON() ([]byte, error) {
 }
 
 type Server struct {
-	isRunning          bool
+	isRunning          atomic.Bool
 	transport          transport.Transport
 	protocol           *protocol.Protocol
+	sessions           *datastructures.SyncMap[*serverSession, struct{}]
+	sessionCount       atomic.Int64
+	listeners          *datastructures.SyncMap[transport.Listener, struct{}]
+	shutdown           atomic.Bool
 	paginationLimit    *int
 	tools              *datastructures.SyncMap[string, *tool]
 	prompts            *datastructures.SyncMap[string, *prompt]
 	resources          *datastructures.SyncMap[string, *resource]
 	resourceTemplates  *datastructures.SyncMap[string, *resourceTemplate]
+	subscriptions      *resourceSubscriptions
+	samplingClients    *datastructures.SyncMap[clientSession, struct{}]
+	loggingLevels      *datastructures.SyncMap[clientSession, LoggingLevel]
 	serverInstructions *string
 	serverName         string
 	serverVersion      string
+	logger             *slog.Logger
+	middleware         []Middleware
+	toolInterceptors   []ToolInterceptor
+	onPanic            func(recovered any, stack []byte)
+}
+
+// serverSession is a connection served with its own protocol alongside the server's main transport, see ServeSession
+type serverSession struct {
+	protocol  *protocol.Protocol
+	transport transport.Transport
+	closed    atomic.Bool
+}
+
+// clientSession identifies a client of the server: the protocol it is served with, and its transport session for
+// transports that serve several clients at once, see transport.WithSession
+type clientSession struct {
+	protocol *protocol.Protocol
+	session  string
+}
+
+// clientSessionFromContext returns the client of pr that sent the request handled with ctx
+func clientSessionFromContext(pr *protocol.Protocol, ctx context.Context) clientSession {
+	session, _ := transport.SessionFromContext(ctx)
+	return clientSession{protocol: pr, session: session}
 }
 
 type prompt struct {
@@ -118,12 +153,13 @@ type prompt struct {
 	Description       string
 	Handler           func(context.Context, baseGetPromptRequestParamsArguments) *promptResponseSent
 	PromptInputSchema *PromptSchema
+	completions       Completions
 }
 
 type tool struct {
 	Name            string
 	Description     string
-	Handler         func(context.Context, baseCallToolRequestParams) *toolResponseSent
+	Handler         func(context.Context, baseCallToolRequestParams, []ToolInterceptor) *toolResponseSent
 	ToolInputSchema *jsonschema.Schema
 }
 
@@ -140,6 +176,10 @@ type resourceTemplate struct {
 	Description string
 	UriTemplate string
 	MimeType    string
+	// Reads the resources matching the template, nil for templates that are only listed
+	Handler     func(ctx context.Context, uri string, values map[string]string) *resourceResponseSent
+	template    *uritemplate.Template
+	completions Completions
 }
 
 type ServerOptions func(*Server)
@@ -169,18 +209,34 @@ func WithVersion(version string) ServerOptions {
 	}
 }
 
-func NewServer(transport transport.Transport, options ...ServerOptions) *Server {
+// WithLogger sets the logger that receives the diagnostics of the server, such as failed requests. The records of
+// sessions carry a "session" attribute. Nothing is logged by default.
+// A protocol given with WithProtocol keeps its own logger.
+func WithLogger(logger *slog.Logger) ServerOptions {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+func NewServer(tr transport.Transport, options ...ServerOptions) *Server {
 	server := &Server{
-		protocol:          protocol.NewProtocol(nil),
-		transport:         transport,
+		transport:         tr,
 		tools:             new(datastructures.SyncMap[string, *tool]),
 		prompts:           new(datastructures.SyncMap[string, *prompt]),
 		resources:         new(datastructures.SyncMap[string, *resource]),
 		resourceTemplates: new(datastructures.SyncMap[string, *resourceTemplate]),
+		subscriptions:     newResourceSubscriptions(),
+		samplingClients:   new(datastructures.SyncMap[clientSession, struct{}]),
+		loggingLevels:     new(datastructures.SyncMap[clientSession, LoggingLevel]),
+		sessions:          new(datastructures.SyncMap[*serverSession, struct{}]),
+		listeners:         new(datastructures.SyncMap[transport.Listener, struct{}]),
 	}
 	for _, option := range options {
 		option(server)
 	}
+	if server.protocol == nil {
+		server.protocol = protocol.NewProtocol(&protocol.ProtocolOptions{Logger: server.logger, OnPanic: server.onPanic})
+	}
 	return server
 }
 
@@ -203,10 +259,7 @@ func (s *Server) RegisterTool(name string, description string, handler any) erro
 }
 
 func (s *Server) sendToolListChangedNotification() error {
-	if !s.isRunning {
-		return nil
-	}
-	return s.protocol.Notification("notifications/tools/list_changed", nil)
+	return s.notifyAll("notifications/tools/list_changed", nil)
 }
 
 func (s *Server) CheckToolRegistered(name string) bool {
@@ -235,10 +288,7 @@ func (s *Server) RegisterResource(uri string, name string, description string, m
 }
 
 func (s *Server) sendResourceListChangedNotification() error {
-	if !s.isRunning {
-		return nil
-	}
-	return s.protocol.Notification("notifications/resources/list_changed", nil)
+	return s.notifyAll("notifications/resources/list_changed", nil)
 }
 
 func (s *Server) CheckResourceRegistered(uri string) bool {
@@ -308,13 +358,40 @@ func validateResourceHandler(handler any) error {
 	return nil
 }
 
+// RegisterResourceTemplate registers an RFC 6570 URI template, up to level 3, that is only listed. Use
+// RegisterResourceTemplateHandler to also read the resources that match it.
 func (s *Server) RegisterResourceTemplate(uriTemplate string, name string, description string, mimeType string) error {
-	s.resourceTemplates.Store(uriTemplate, &resourceTemplate{
+	return s.RegisterResourceTemplateHandler(uriTemplate, name, description, mimeType, nil)
+}
+
+// RegisterResourceTemplateHandler registers an RFC 6570 URI template, up to level 3, such as file:///{+path} or
+// db://{table}/{id}. The handler reads the resources whose uri matches the template and has no exact resource
+// registered. It takes an optional context.Context and a struct whose fields are filled with the values of the
+// template variables, by json tag or field name like encoding/json, and returns a *ResourceResponse and an error.
+// A nil handler only lists the template. Completions suggest values for the template variables, see Completions.
+func (s *Server) RegisterResourceTemplateHandler(uriTemplate string, name string, description string, mimeType string, handler any, completions ...Completions) error {
+	template, err := uritemplate.Parse(uriTemplate)
+	if err != nil {
+		return err
+	}
+	t := &resourceTemplate{
 		Name:        name,
 		Description: description,
 		UriTemplate: uriTemplate,
 		MimeType:    mimeType,
-	})
+		template:    template,
+	}
+	t.completions, err = mergeCompletions(template.Variables(), completions)
+	if err != nil {
+		return errors.Wrapf(err, "invalid completions for resource template %s", uriTemplate)
+	}
+	if handler != nil {
+		if err := validateResourceTemplateHandler(handler); err != nil {
+			return errors.Wrapf(err, "invalid handler for resource template %s", uriTemplate)
+		}
+		t.Handler = createWrappedResourceTemplateHandler(handler)
+	}
+	s.resourceTemplates.Store(uriTemplate, t)
 	return s.sendResourceListChangedNotification()
 }
 
@@ -328,27 +405,34 @@ func (s *Server) DeregisterResourceTemplate(uriTemplate string) error {
 	return s.sendResourceListChangedNotification()
 }
 
-func (s *Server) RegisterPrompt(name string, description string, handler any) error {
+// RegisterPrompt registers a prompt. Completions suggest values for the arguments of the prompt, see Completions.
+func (s *Server) RegisterPrompt(name string, description string, handler any, completions ...Completions) error {
 	err := validatePromptHandler(handler)
 	if err != nil {
 		return err
 	}
 	promptSchema := createPromptSchemaFromHandler(handler)
+	arguments := make([]string, len(promptSchema.Arguments))
+	for i, argument := range promptSchema.Arguments {
+		arguments[i] = argument.Name
+	}
+	merged, err := mergeCompletions(arguments, completions)
+	if err != nil {
+		return errors.Wrapf(err, "invalid completions for prompt %s", name)
+	}
 	s.prompts.Store(name, &prompt{
 		Name:              name,
 		Description:       description,
 		Handler:           createWrappedPromptHandler(handler),
 		PromptInputSchema: promptSchema,
+		completions:       merged,
 	})
 
 	return s.sendPromptListChangedNotification()
 }
 
 func (s *Server) sendPromptListChangedNotification() error {
-	if !s.isRunning {
-		return nil
-	}
-	return s.protocol.Notification("notifications/prompts/list_changed", nil)
+	return s.notifyAll("notifications/prompts/list_changed", nil)
 }
 
 func (s *Server) CheckPromptRegistered(name string) bool {
@@ -513,7 +597,8 @@ func createJsonSchemaFromHandler(handler any) *jsonschema.Schema {
 // This takes a user provided handler and returns a wrapped handler which can be used to actually answer requests
 // Concretely, it will deserialize the arguments and call the user provided handler and then serialize the response
 // If the handler returns an error, it will be serialized and sent back as a tool error rather than a protocol error
-func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToolRequestParams) *toolResponseSent {
+// The interceptors run around the user provided handler, once the arguments have been deserialized
+func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToolRequestParams, []ToolInterceptor) *toolResponseSent {
 	handlerValue := reflect.ValueOf(userHandler)
 	handlerType := handlerValue.Type()
 	var argumentType reflect.Type
@@ -522,7 +607,7 @@ func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToo
 	} else if handlerType.NumIn() == 1 {
 		argumentType = handlerType.In(0)
 	}
-	return func(ctx context.Context, arguments baseCallToolRequestParams) *toolResponseSent {
+	return func(ctx context.Context, arguments baseCallToolRequestParams, interceptors []ToolInterceptor) *toolResponseSent {
 		// Instantiate a struct of the type of the arguments
 		if !reflect.New(argumentType).CanInterface() {
 			return newToolResponseSentError(errors.Wrap(fmt.Errorf("arguments must be a struct"), "failed to create argument struct"))
@@ -541,56 +626,235 @@ func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToo
 			return newToolResponseSentError(errors.Wrap(fmt.Errorf("arguments must be a struct"), "failed to dereference arguments"))
 		}
 
-		var args []reflect.Value
-		if handlerType.NumIn() == 2 {
-			args = []reflect.Value{reflect.ValueOf(ctx), of.Elem()}
-		} else {
-			args = []reflect.Value{of.Elem()}
-		}
+		var handler ToolHandler = func(ctx context.Context, call *ToolCall) (*ToolResponse, error) {
+			// Interceptors may have replaced the arguments
+			argumentsValue := reflect.ValueOf(call.Arguments)
+			if argumentsValue.Type() != of.Type() {
+				return nil, fmt.Errorf("arguments must be a %s, got %T", of.Type(), call.Arguments)
+			}
 
-		// Call the handler with the typed arguments
-		output := handlerValue.Call(args)
+			var args []reflect.Value
+			if handlerType.NumIn() == 2 {
+				args = []reflect.Value{reflect.ValueOf(ctx), argumentsValue.Elem()}
+			} else {
+				args = []reflect.Value{argumentsValue.Elem()}
+			}
 
-		if len(output) != 2 {
-			return newToolResponseSentError(errors.Wrap(fmt.Errorf("handler must return exactly two values, got %d", len(output)), "invalid handler return"))
-		}
+			// Call the handler with the typed arguments
+			output := handlerValue.Call(args)
 
-		if !output[0].CanInterface() {
-			return newToolResponseSentError(errors.Wrap(fmt.Errorf("handler must return a struct, got %s", output[0].Type().Name()), "invalid handler return"))
+			if len(output) != 2 {
+				return nil, errors.Wrap(fmt.Errorf("handler must return exactly two values, got %d", len(output)), "invalid handler return")
+			}
+
+			if !output[0].CanInterface() {
+				return nil, errors.Wrap(fmt.Errorf("handler must return a struct, got %s", output[0].Type().Name()), "invalid handler return")
+			}
+			tool := output[0].Interface()
+			if !output[1].CanInterface() {
+				return nil, errors.Wrap(fmt.Errorf("handler must return an error, got %s", output[1].Type().Name()), "invalid handler return")
+			}
+			errorOut := output[1].Interface()
+			if errorOut == nil {
+				return tool.(*ToolResponse), nil
+			}
+			return nil, errors.Wrap(errorOut.(error), "handler returned an error")
 		}
-		tool := output[0].Interface()
-		if !output[1].CanInterface() {
-			return newToolResponseSentError(errors.Wrap(fmt.Errorf("handler must return an error, got %s", output[1].Type().Name()), "invalid handler return"))
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			handler = interceptors[i](handler)
 		}
-		errorOut := output[1].Interface()
-		if errorOut == nil {
-			return newToolResponseSent(tool.(*ToolResponse))
+
+		// Errors from the interceptors are returned as they are, the handler errors are already wrapped
+		response, err := callTool(ctx, handler, &ToolCall{Name: arguments.Name, Arguments: of.Interface()})
+		if err != nil {
+			return newToolResponseSentError(err)
 		}
-		return newToolResponseSentError(errors.Wrap(errorOut.(error), "handler returned an error"))
+		return newToolResponseSent(response)
 	}
 }
 
 func (s *Server) Serve() error {
-	if s.isRunning {
+	if s.isRunning.Load() {
 		return fmt.Errorf("server is already running")
 	}
+	if s.shutdown.Load() {
+		return fmt.Errorf("server has been shut down")
+	}
+	if s.transport == nil {
+		return fmt.Errorf("server has no transport, use ServeListener or ServeSession instead")
+	}
 	pr := s.protocol
+	s.registerHandlers(pr)
+	s.releaseEndedSessions(pr, s.transport)
+	err := pr.Connect(s.transport)
+	if err != nil {
+		return err
+	}
+	s.isRunning.Store(true)
+	// Shutdown may have run while the transport was starting, before it could see the server running
+	if s.shutdown.Load() {
+		return pr.Shutdown(context.Background())
+	}
+	return nil
+}
+
+// ServeSession serves tr as an additional session with its own protocol state, alongside the server's main transport
+// and any other sessions. Tools, prompts and resources are shared by all sessions, and list changed notifications are
+// sent to every session. The session ends when tr is closed.
+func (s *Server) ServeSession(tr transport.Transport) error {
+	if s.shutdown.Load() {
+		return fmt.Errorf("server has been shut down")
+	}
+	session := &serverSession{
+		protocol:  protocol.NewProtocol(s.sessionOptions(tr)),
+		transport: tr,
+	}
+	s.registerHandlers(session.protocol)
+	session.protocol.Use(s.middleware...)
+	s.releaseEndedSessions(session.protocol, tr)
+	session.protocol.OnClose = func() {
+		session.closed.Store(true)
+		s.sessions.Delete(session)
+		s.subscriptions.unsubscribeProtocol(session.protocol)
+		s.forgetSamplingClients(session.protocol)
+		s.forgetLoggingLevels(session.protocol)
+	}
+
+	if err := session.protocol.Connect(tr); err != nil {
+		return err
+	}
+	// The session is only stored once connected, it may already have been closed by then
+	s.sessions.Store(session, struct{}{})
+	if session.closed.Load() {
+		s.sessions.Delete(session)
+	}
+	return nil
+}
+
+// sessionOptions returns the protocol options of a session served on tr. Sessions are identified by their transport's
+// session id, if it has one, or by their number.
+func (s *Server) sessionOptions(tr transport.Transport) *protocol.ProtocolOptions {
+	var session string
+	if identified, ok := tr.(interface{ SessionID() string }); ok {
+		session = identified.SessionID()
+	} else {
+		session = strconv.FormatInt(s.sessionCount.Add(1), 10)
+	}
+	options := &protocol.ProtocolOptions{Session: session, OnPanic: s.onPanic}
+	if s.logger != nil {
+		options.Logger = s.logger.With("session", session)
+	}
+	return options
+}
+
+// releaseEndedSessions releases the state kept for the clients of tr that disconnect, when tr serves several clients at
+// once
+func (s *Server) releaseEndedSessions(pr *protocol.Protocol, tr transport.Transport) {
+	if notifier, ok := tr.(transport.SessionCloseNotifier); ok {
+		notifier.SetSessionCloseHandler(func(session string) {
+			s.subscriptions.unsubscribeSession(pr, session)
+			s.samplingClients.Delete(clientSession{protocol: pr, session: session})
+			s.loggingLevels.Delete(clientSession{protocol: pr, session: session})
+		})
+	}
+}
+
+// ServeListener accepts connections from listener and serves each one as its own session, see ServeSession.
+// The server can be created without a transport when it is only served with ServeListener.
+// ServeListener blocks until the listener is closed, in which case it returns nil. Shutdown closes the listener.
+func (s *Server) ServeListener(listener transport.Listener) error {
+	if s.shutdown.Load() {
+		return fmt.Errorf("server has been shut down")
+	}
+	s.listeners.Store(listener, struct{}{})
+	defer s.listeners.Delete(listener)
+
+	for {
+		tr, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, transport.ErrListenerClosed) {
+				return nil
+			}
+			return err
+		}
+		if err := s.ServeSession(tr); err != nil {
+			tr.Close()
+		}
+	}
+}
+
+// Shutdown gracefully stops the server. Listeners passed to ServeListener are closed and every session, including the
+// main transport, stops accepting new requests. Shutdown then waits for the tool, prompt and resource handlers that are
+// running to finish and for their responses to be sent before closing the transports.
+//
+// If ctx is done first, the handlers that are still running are cancelled through their contexts, their requests are
+// answered with an error, the transports are closed and ctx.Err() is returned.
+//
+// A server that has been shut down cannot be served again.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdown.Store(true)
+
+	s.listeners.Range(func(listener transport.Listener, _ struct{}) bool {
+		listener.Close()
+		return true
+	})
+
+	var protocols []*protocol.Protocol
+	if s.isRunning.Load() {
+		protocols = append(protocols, s.protocol)
+	}
+	s.sessions.Range(func(session *serverSession, _ struct{}) bool {
+		protocols = append(protocols, session.protocol)
+		return true
+	})
+
+	errs := make(chan error, len(protocols))
+	for _, pr := range protocols {
+		go func(pr *protocol.Protocol) {
+			errs <- pr.Shutdown(ctx)
+		}(pr)
+	}
+	var firstErr error
+	for range protocols {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	s.isRunning.Store(false)
+	return firstErr
+}
+
+func (s *Server) registerHandlers(pr *protocol.Protocol) {
 	pr.SetRequestHandler("ping", s.handlePing)
-	pr.SetRequestHandler("initialize", s.handleInitialize)
+	pr.SetRequestHandler("initialize", s.withClientCapabilities(pr, s.handleInitialize))
+	pr.SetRequestHandler("logging/setLevel", s.withLogging(pr, s.handleSetLevel))
 	pr.SetRequestHandler("tools/list", s.handleListTools)
-	pr.SetRequestHandler("tools/call", s.handleToolCalls)
+	pr.SetRequestHandler("tools/call", s.withLogging(pr, s.withSampler(pr, s.handleToolCalls)))
 	pr.SetRequestHandler("prompts/list", s.handleListPrompts)
-	pr.SetRequestHandler("prompts/get", s.handlePromptCalls)
+	pr.SetRequestHandler("prompts/get", s.withLogging(pr, s.withSampler(pr, s.handlePromptCalls)))
 	pr.SetRequestHandler("resources/list", s.handleListResources)
 	pr.SetRequestHandler("resources/templates/list", s.handleListResourceTemplates)
-	pr.SetRequestHandler("resources/read", s.handleResourceCalls)
-	err := pr.Connect(s.transport)
-	if err != nil {
-		return err
+	pr.SetRequestHandler("resources/read", s.withLogging(pr, s.withSampler(pr, s.handleResourceCalls)))
+	pr.SetRequestHandler("resources/subscribe", handleResourceSubscription(pr, s.subscriptions.subscribe))
+	pr.SetRequestHandler("resources/unsubscribe", handleResourceSubscription(pr, s.subscriptions.unsubscribe))
+	pr.SetRequestHandler("completion/complete", s.withLogging(pr, s.handleComplete))
+}
+
+// notifyAll sends a notification on the main transport, if the server is running, and to every session.
+// Every session is notified even if sending to one fails, the first error is returned.
+func (s *Server) notifyAll(method string, params interface{}) error {
+	var firstErr error
+	if s.isRunning.Load() {
+		firstErr = s.protocol.Notification(method, params)
 	}
-	s.protocol = pr
-	s.isRunning = true
-	return nil
+	s.sessions.Range(func(session *serverSession, _ struct{}) bool {
+		if err := session.protocol.Notification(method, params); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return true
+	})
+	return firstErr
 }
 
 func (s *Server) handleInitialize(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
@@ -616,7 +880,7 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 	} else {
 		err := json.Unmarshal(request.Params, &params)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal arguments")
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
 		}
 	}
 
@@ -635,7 +899,7 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to decode cursor: "+err.Error())
 		}
 		cString := string(c)
 		// Iterate through the tools until we find an entry > the cursor
@@ -686,7 +950,7 @@ func (s *Server) handleToolCalls(ctx context.Context, req *transport.BaseJSONRPC
 	// Instantiate a struct of the type of the arguments
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+		return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
 	}
 
 	var toolToUse *tool
@@ -699,13 +963,22 @@ func (s *Server) handleToolCalls(ctx context.Context, req *transport.BaseJSONRPC
 	})
 
 	if toolToUse == nil {
-		return nil, errors.Wrapf(err, "unknown tool: %s", req.Method)
+		return nil, NewRPCError(ErrorCodeInvalidParams, fmt.Sprintf("unknown tool: %s", params.Name))
+	}
+	response := toolToUse.Handler(ctx, params, s.toolInterceptors)
+	if panicked, ok := response.Error.(*toolPanic); ok {
+		s.reportToolPanic(panicked)
+	}
+	if isRPCError(response.Error) {
+		return nil, response.Error
 	}
-	return toolToUse.Handler(ctx, params), nil
+	return response, nil
 }
 func (s *Server) generateCapabilities() ServerCapabilities {
 	t := false
 	return ServerCapabilities{
+		Completions: &ServerCapabilitiesCompletions{},
+		Logging:     &ServerCapabilitiesLogging{},
 		Tools: func() *ServerCapabilitiesTools {
 			return &ServerCapabilitiesTools{
 				ListChanged: &t,
@@ -717,8 +990,10 @@ func (s *Server) generateCapabilities() ServerCapabilities {
 			}
 		}(),
 		Resources: func() *ServerCapabilitiesResources {
+			subscribe := true
 			return &ServerCapabilitiesResources{
 				ListChanged: &t,
+				Subscribe:   &subscribe,
 			}
 		}(),
 	}
@@ -730,7 +1005,7 @@ func (s *Server) handleListPrompts(ctx context.Context, request *transport.BaseJ
 	var params promptRequestParams
 	err := json.Unmarshal(request.Params, &params)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+		return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
 	}
 
 	// Order by name for pagination
@@ -748,7 +1023,7 @@ func (s *Server) handleListPrompts(ctx context.Context, request *transport.BaseJ
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to decode cursor: "+err.Error())
 		}
 		cString := string(c)
 		// Iterate through the prompts until we find an entry > the cursor
@@ -797,7 +1072,7 @@ func (s *Server) handleListResources(ctx context.Context, request *transport.Bas
 	} else {
 		err := json.Unmarshal(request.Params, &params)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal arguments")
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
 		}
 	}
 
@@ -816,7 +1091,7 @@ func (s *Server) handleListResources(ctx context.Context, request *transport.Bas
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to decode cursor: "+err.Error())
 		}
 		cString := string(c)
 		// Iterate through the resources until we find an entry > the cursor
@@ -869,7 +1144,7 @@ func (s *Server) handleListResourceTemplates(ctx context.Context, request *trans
 	} else {
 		err := json.Unmarshal(request.Params, &params)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal arguments")
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
 		}
 	}
 
@@ -888,7 +1163,7 @@ func (s *Server) handleListResourceTemplates(ctx context.Context, request *trans
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, NewRPCError(ErrorCodeInvalidParams, "failed to decode cursor: "+err.Error())
 		}
 		cString := string(c)
 		// Iterate through the templates until we find an entry > the cursor
@@ -936,7 +1211,7 @@ func (s *Server) handlePromptCalls(ctx context.Context, req *transport.BaseJSONR
 	// Instantiate a struct of the type of the arguments
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+		return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
 	}
 
 	var promptToUse *prompt
@@ -949,9 +1224,13 @@ func (s *Server) handlePromptCalls(ctx context.Context, req *transport.BaseJSONR
 	})
 
 	if promptToUse == nil {
-		return nil, errors.Wrapf(err, "unknown prompt: %s", req.Method)
+		return nil, NewRPCError(ErrorCodeInvalidParams, fmt.Sprintf("unknown prompt: %s", params.Name))
 	}
-	return promptToUse.Handler(ctx, params), nil
+	response := promptToUse.Handler(ctx, params)
+	if isRPCError(response.Error) {
+		return nil, response.Error
+	}
+	return response, nil
 }
 
 func (s *Server) handleResourceCalls(ctx context.Context, req *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
@@ -959,7 +1238,7 @@ func (s *Server) handleResourceCalls(ctx context.Context, req *transport.BaseJSO
 	// Instantiate a struct of the type of the arguments
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+		return nil, NewRPCError(ErrorCodeInvalidParams, "failed to unmarshal arguments: "+err.Error())
 	}
 
 	var resourceToUse *resource
@@ -971,10 +1250,18 @@ func (s *Server) handleResourceCalls(ctx context.Context, req *transport.BaseJSO
 		return false
 	})
 
-	if resourceToUse == nil {
-		return nil, errors.Wrapf(err, "unknown prompt: %s", req.Method)
+	var response *resourceResponseSent
+	if resourceToUse != nil {
+		response = resourceToUse.Handler(ctx)
+	} else if template, values := s.matchResourceTemplate(params.Uri); template != nil {
+		response = template.Handler(ctx, params.Uri, values)
+	} else {
+		return nil, NewRPCError(ErrorCodeInvalidParams, fmt.Sprintf("unknown resource: %s", params.Uri))
+	}
+	if isRPCError(response.Error) {
+		return nil, response.Error
 	}
-	return resourceToUse.Handler(ctx), nil
+	return response, nil
 }
 
 func (s *Server) handlePing(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
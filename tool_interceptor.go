@@ -0,0 +1,35 @@
+package mcp_golang
+
+import "context"
+
+// ToolCall is a call to a tool, as seen by tool interceptors
+type ToolCall struct {
+	// The name of the tool
+	Name string
+	// A pointer to the decoded argument struct of the tool, such as *MyToolArguments. Interceptors can change the
+	// arguments, or replace them with another pointer of the same type, before calling the next handler.
+	Arguments any
+}
+
+// ToolHandler calls a tool and returns its response. An error is sent to the client as an error result, unless it is
+// an *RPCError.
+type ToolHandler func(ctx context.Context, call *ToolCall) (*ToolResponse, error)
+
+// ToolInterceptor wraps every call to a tool. It can rewrite the arguments, veto the call by returning an error without
+// calling next, or post-process the response:
+//
+//	server.InterceptTools(func(next mcp_golang.ToolHandler) mcp_golang.ToolHandler {
+//		return func(ctx context.Context, call *mcp_golang.ToolCall) (*mcp_golang.ToolResponse, error) {
+//			if !quota.Allow(call.Name) {
+//				return nil, fmt.Errorf("quota exceeded for %s", call.Name)
+//			}
+//			return next(ctx, call)
+//		}
+//	})
+type ToolInterceptor func(next ToolHandler) ToolHandler
+
+// InterceptTools adds interceptors around every call to a tool, registered before or after. The first interceptor is
+// the outermost one. InterceptTools should be called before the server is served.
+func (s *Server) InterceptTools(interceptors ...ToolInterceptor) {
+	s.toolInterceptors = append(s.toolInterceptors, interceptors...)
+}
@@ -0,0 +1,82 @@
+package mcp_golang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolInterceptors(t *testing.T) {
+	calls := make(map[string]int)
+	var seen []string
+	client := newInMemoryClient(t, func(s *Server) {
+		s.InterceptTools(
+			// Quotas
+			func(next ToolHandler) ToolHandler {
+				return func(ctx context.Context, call *ToolCall) (*ToolResponse, error) {
+					calls[call.Name]++
+					if args, ok := call.Arguments.(*echoArgs); ok && args.Message == "forbidden" {
+						return nil, NewRPCError(-32001, "forbidden")
+					}
+					if calls[call.Name] > 4 {
+						return nil, fmt.Errorf("quota exceeded for %s", call.Name)
+					}
+					return next(ctx, call)
+				}
+			},
+			// Redaction of the output, and rewriting of the arguments
+			func(next ToolHandler) ToolHandler {
+				return func(ctx context.Context, call *ToolCall) (*ToolResponse, error) {
+					args := call.Arguments.(*echoArgs)
+					seen = append(seen, args.Message)
+					if args.Message == "replace" {
+						call.Arguments = &echoArgs{Message: "replaced"}
+					}
+					if args.Message == "invalid" {
+						call.Arguments = echoArgs{Message: "not a pointer"}
+					}
+					response, err := next(ctx, call)
+					if err != nil {
+						return nil, err
+					}
+					for _, content := range response.Content {
+						content.TextContent.Text = strings.ReplaceAll(content.TextContent.Text, "s3cr3t", "[redacted]")
+					}
+					return response, nil
+				}
+			},
+		)
+		registerEcho(t, s)
+	})
+	ctx := context.Background()
+
+	response, err := client.CallTool(ctx, "echo", echoArgs{Message: "the password is s3cr3t"})
+	require.NoError(t, err)
+	assert.Equal(t, "the password is [redacted]", response.Content[0].TextContent.Text)
+
+	response, err = client.CallTool(ctx, "echo", echoArgs{Message: "replace"})
+	require.NoError(t, err)
+	assert.Equal(t, "replaced", response.Content[0].TextContent.Text)
+
+	response, err = client.CallTool(ctx, "echo", echoArgs{Message: "invalid"})
+	require.NoError(t, err)
+	assert.Equal(t, "arguments must be a *mcp_golang.echoArgs, got mcp_golang.echoArgs", response.Content[0].TextContent.Text)
+
+	// Vetoes with an *RPCError fail the request with that error
+	_, err = client.CallTool(ctx, "echo", echoArgs{Message: "forbidden"})
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr), "expected an RPCError, got %v", err)
+	assert.Equal(t, -32001, rpcErr.Code)
+	assert.Equal(t, "forbidden", rpcErr.Message)
+
+	// Other vetoed calls are error results and do not reach the tool
+	response, err = client.CallTool(ctx, "echo", echoArgs{Message: "vetoed"})
+	require.NoError(t, err)
+	assert.Equal(t, "quota exceeded for echo", response.Content[0].TextContent.Text)
+	assert.Equal(t, []string{"the password is s3cr3t", "replace", "invalid"}, seen)
+}
@@ -9,11 +9,14 @@ import (
 // this schema, but this is not a closed set: any server can define its own,
 // additional capabilities.
 type ServerCapabilities struct {
+	// Present if the server supports argument autocompletion suggestions.
+	Completions *ServerCapabilitiesCompletions `json:"completions,omitempty" yaml:"completions,omitempty" mapstructure:"completions,omitempty"`
+
 	// Experimental, non-standard capabilities that the server supports.
 	Experimental ServerCapabilitiesExperimental `json:"experimental,omitempty" yaml:"experimental,omitempty" mapstructure:"experimental,omitempty"`
 
 	// Present if the server supports sending log messages to the client.
-	Logging ServerCapabilitiesLogging `json:"logging,omitempty" yaml:"logging,omitempty" mapstructure:"logging,omitempty"`
+	Logging *ServerCapabilitiesLogging `json:"logging,omitempty" yaml:"logging,omitempty" mapstructure:"logging,omitempty"`
 
 	// Present if the server offers any prompt templates.
 	Prompts *ServerCapabilitiesPrompts `json:"prompts,omitempty" yaml:"prompts,omitempty" mapstructure:"prompts,omitempty"`
@@ -25,6 +28,21 @@ type ServerCapabilities struct {
 	Tools *ServerCapabilitiesTools `json:"tools,omitempty" yaml:"tools,omitempty" mapstructure:"tools,omitempty"`
 }
 
+// Present if the server supports argument autocompletion suggestions.
+type ServerCapabilitiesCompletions map[string]interface{}
+
+// Capabilities a client may support, sent in the initialize request.
+type ClientCapabilities struct {
+	// Experimental, non-standard capabilities that the client supports.
+	Experimental map[string]map[string]interface{} `json:"experimental,omitempty" yaml:"experimental,omitempty" mapstructure:"experimental,omitempty"`
+
+	// Present if the client supports sampling from an LLM.
+	Sampling *ClientCapabilitiesSampling `json:"sampling,omitempty" yaml:"sampling,omitempty" mapstructure:"sampling,omitempty"`
+}
+
+// Present if the client supports sampling from an LLM.
+type ClientCapabilitiesSampling map[string]interface{}
+
 // Experimental, non-standard capabilities that the server supports.
 type ServerCapabilitiesExperimental map[string]map[string]interface{}
 
@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Standard JSON-RPC error codes
+const (
+	// ErrorCodeParseError means that the message is not valid JSON
+	ErrorCodeParseError = -32700
+	// ErrorCodeInvalidRequest means that the message is not a valid JSON-RPC request
+	ErrorCodeInvalidRequest = -32600
+	// ErrorCodeMethodNotFound means that the method does not exist or is not available
+	ErrorCodeMethodNotFound = -32601
+	// ErrorCodeInvalidParams means that the parameters of the request are invalid
+	ErrorCodeInvalidParams = -32602
+	// ErrorCodeInternalError means that the request failed for a reason internal to the receiver
+	ErrorCodeInternalError = -32603
+)
+
+// RPCError is a JSON-RPC error. Request handlers can return it, possibly wrapped, to answer a request with a specific
+// code and data; any other error is answered with ErrorCodeInternalError. Errors received in response to a request are
+// returned as an *RPCError, which can be retrieved with errors.As.
+type RPCError struct {
+	Code    int
+	Message string
+	// Optional additional information, sent as the "data" member of the error
+	Data interface{}
+}
+
+// NewRPCError creates an error with the given code and message
+func NewRPCError(code int, message string) *RPCError {
+	return &RPCError{Code: code, Message: message}
+}
+
+// WithData sets the additional information sent with the error
+func (e *RPCError) WithData(data interface{}) *RPCError {
+	e.Data = data
+	return e
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("RPC error %d: %s", e.Code, e.Message)
+}
+
+// NewInvalidMessageError returns the error that answers data that is not a valid JSON-RPC message: ErrorCodeParseError
+// if data is not JSON, ErrorCodeInvalidRequest otherwise. The error has the id of the message if it could be read, and a
+// null id otherwise.
+func NewInvalidMessageError(data []byte, err error) *BaseJsonRpcMessage {
+	code, message := ErrorCodeInvalidRequest, "Invalid Request"
+	id := NullRequestId()
+	if !json.Valid(data) {
+		code, message = ErrorCodeParseError, "Parse error"
+	} else {
+		var withId struct {
+			Id *RequestId `json:"id"`
+		}
+		if json.Unmarshal(data, &withId) == nil && withId.Id != nil {
+			id = *withId.Id
+		}
+	}
+	if err != nil {
+		message += ": " + err.Error()
+	}
+	return NewBaseMessageError(&BaseJSONRPCError{
+		Jsonrpc: "2.0",
+		Id:      id,
+		Error:   BaseJSONRPCErrorInner{Code: code, Message: message},
+	})
+}
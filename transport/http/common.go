@@ -1,10 +1,12 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"sync"
 
 	"github.com/metoro-io/mcp-golang/transport"
@@ -16,21 +18,30 @@ type baseTransport struct {
 	errorHandler   func(error)
 	closeHandler   func()
 	mu             sync.RWMutex
-	responseMap    map[int64]chan *transport.BaseJsonRpcMessage
+	responseMap    map[transport.RequestId]chan *transport.BaseJsonRpcMessage
+	// Key of the next request, keys are never reused so a late response cannot reach a newer request
+	nextKey int64
 }
 
 func newBaseTransport() *baseTransport {
 	return &baseTransport{
-		responseMap: make(map[int64]chan *transport.BaseJsonRpcMessage),
+		responseMap: make(map[transport.RequestId]chan *transport.BaseJsonRpcMessage),
 	}
 }
 
 // Send implements Transport.Send
 func (t *baseTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
-	key := message.JsonRpcResponse.Id
-	responseChannel := t.responseMap[int64(key)]
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType, transport.BaseMessageTypeJSONRPCErrorType:
+	default:
+		return fmt.Errorf("stateless HTTP transport can only send responses, got %s", message.Type)
+	}
+	key := responseId(message)
+	t.mu.RLock()
+	responseChannel := t.responseMap[key]
+	t.mu.RUnlock()
 	if responseChannel == nil {
-		return fmt.Errorf("no response channel found for key: %d", key)
+		return fmt.Errorf("no response channel found for key: %s", key)
 	}
 	responseChannel <- message
 	return nil
@@ -38,8 +49,11 @@ func (t *baseTransport) Send(ctx context.Context, message *transport.BaseJsonRpc
 
 // Close implements Transport.Close
 func (t *baseTransport) Close() error {
-	if t.closeHandler != nil {
-		t.closeHandler()
+	t.mu.RLock()
+	handler := t.closeHandler
+	t.mu.RUnlock()
+	if handler != nil {
+		handler()
 	}
 	return nil
 }
@@ -65,102 +79,175 @@ func (t *baseTransport) SetMessageHandler(handler func(ctx context.Context, mess
 	t.messageHandler = handler
 }
 
-// handleMessage processes an incoming message and returns a response
-func (t *baseTransport) handleMessage(ctx context.Context, body []byte) (*transport.BaseJsonRpcMessage, error) {
-	// Store the response writer for later use
-	t.mu.Lock()
-	var key int64 = 0
+// handleMessages delivers the messages of a single POST request and waits for the responses to its requests.
+// Requests are delivered with ids unique to the transport, as requests from different clients may share ids, and their
+// responses are returned with the original ids, in the order of the requests.
+func (t *baseTransport) handleMessages(ctx context.Context, messages []*transport.BaseJsonRpcMessage) ([]*transport.BaseJsonRpcMessage, error) {
+	type pendingRequest struct {
+		key      transport.RequestId
+		id       transport.RequestId
+		response chan *transport.BaseJsonRpcMessage
+	}
 
-	for key < 1000000 {
-		if _, ok := t.responseMap[key]; !ok {
-			break
+	var pending []pendingRequest
+	t.mu.Lock()
+	for _, message := range messages {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			continue
 		}
-		key = key + 1
+		key := transport.NewRequestId(t.nextKey)
+		t.nextKey++
+		// Buffered so that a response sent after the client has gone away does not block
+		response := make(chan *transport.BaseJsonRpcMessage, 1)
+		t.responseMap[key] = response
+		pending = append(pending, pendingRequest{key: key, id: message.JsonRpcRequest.Id, response: response})
+		message.JsonRpcRequest.Id = key
 	}
-	t.responseMap[key] = make(chan *transport.BaseJsonRpcMessage)
+	handler := t.messageHandler
 	t.mu.Unlock()
 
-	var prevId *transport.RequestId = nil
-	deserialized := false
-	// Try to unmarshal as a request first
-	var request transport.BaseJSONRPCRequest
-	if err := json.Unmarshal(body, &request); err == nil {
-		deserialized = true
-		id := request.Id
-		prevId = &id
-		request.Id = transport.RequestId(key)
-		t.mu.RLock()
-		handler := t.messageHandler
-		t.mu.RUnlock()
-
-		if handler != nil {
-			handler(ctx, transport.NewBaseMessageRequest(&request))
+	defer func() {
+		t.mu.Lock()
+		for _, request := range pending {
+			delete(t.responseMap, request.key)
 		}
-	}
-
-	// Try as a notification
-	var notification transport.BaseJSONRPCNotification
-	if !deserialized {
-		if err := json.Unmarshal(body, &notification); err == nil {
-			deserialized = true
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
+		t.mu.Unlock()
+	}()
 
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageNotification(&notification))
-			}
+	if handler != nil {
+		for _, message := range messages {
+			handler(ctx, message)
 		}
 	}
 
-	// Try as a response
-	var response transport.BaseJSONRPCResponse
-	if !deserialized {
-		if err := json.Unmarshal(body, &response); err == nil {
-			deserialized = true
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
-
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageResponse(&response))
+	// Block until every request has been answered
+	responses := make([]*transport.BaseJsonRpcMessage, 0, len(pending))
+	for _, request := range pending {
+		select {
+		case response := <-request.response:
+			if response.Type == transport.BaseMessageTypeJSONRPCErrorType {
+				response.JsonRpcError.Id = request.id
+			} else {
+				response.JsonRpcResponse.Id = request.id
 			}
+			responses = append(responses, response)
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
+	return responses, nil
+}
 
-	// Try as an error
-	var errorResponse transport.BaseJSONRPCError
-	if !deserialized {
-		if err := json.Unmarshal(body, &errorResponse); err == nil {
-			deserialized = true
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
+// serveHTTP answers a single stateless POST request with the response to the message in its body, or with one batch
+// of responses if the body is a batch. Bodies holding only notifications or responses are acknowledged with 202.
+// The message handler is called with ctx, which carries the verified client certificate of r if there is one.
+func (t *baseTransport) serveHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is supported", http.StatusMethodNotAllowed)
+		return
+	}
 
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageError(&errorResponse))
-			}
-		}
+	body, err := t.readBody(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	messages, isBatch, err := unmarshalMessages(body)
+	if err != nil {
+		t.writeInvalidMessage(w, body, err)
+		return
+	}
+
+	responses, err := t.handleMessages(withClientCertificate(ctx, r), messages)
+	if err != nil {
+		// The client went away before every request was answered
+		return
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
 	}
 
-	// Block until the response is received
-	responseToUse := <-t.responseMap[key]
-	delete(t.responseMap, key)
-	if prevId != nil {
-		responseToUse.JsonRpcResponse.Id = *prevId
+	var jsonData []byte
+	if isBatch {
+		jsonData, err = json.Marshal(responses)
+	} else {
+		jsonData, err = json.Marshal(responses[0])
+	}
+	if err != nil {
+		t.reportError(fmt.Errorf("failed to marshal response: %w", err))
+		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		return
 	}
 
-	return responseToUse, nil
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// writeInvalidMessage answers a body that is not a valid JSON-RPC message or batch with a JSON-RPC error
+func (t *baseTransport) writeInvalidMessage(w http.ResponseWriter, body []byte, err error) {
+	t.reportError(err)
+	jsonData, err := json.Marshal(transport.NewInvalidMessageError(body, err))
+	if err != nil {
+		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(jsonData)
 }
 
 // readBody reads and returns the body from an io.Reader
 func (t *baseTransport) readBody(reader io.Reader) ([]byte, error) {
 	body, err := io.ReadAll(reader)
 	if err != nil {
-		if t.errorHandler != nil {
-			t.errorHandler(fmt.Errorf("failed to read request body: %w", err))
-		}
+		t.reportError(fmt.Errorf("failed to read request body: %w", err))
 		return nil, fmt.Errorf("failed to read request body: %w", err)
 	}
 	return body, nil
 }
+
+func (t *baseTransport) reportError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// responseId returns the id of the request a response or error answers
+func responseId(message *transport.BaseJsonRpcMessage) transport.RequestId {
+	if message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+		return message.JsonRpcError.Id
+	}
+	return message.JsonRpcResponse.Id
+}
+
+// unmarshalMessages unmarshals a body holding either a single JSON-RPC message or a batch (JSON array) of them.
+// The returned bool reports whether the body was a batch.
+func unmarshalMessages(body []byte) ([]*transport.BaseJsonRpcMessage, bool, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var messages []*transport.BaseJsonRpcMessage
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, true, fmt.Errorf("failed to unmarshal JSON-RPC batch: %w", err)
+		}
+		if len(messages) == 0 {
+			return nil, true, fmt.Errorf("JSON-RPC batch must not be empty")
+		}
+		for _, message := range messages {
+			if message == nil {
+				return nil, true, fmt.Errorf("JSON-RPC batch must not contain null")
+			}
+		}
+		return messages, true, nil
+	}
+
+	var message transport.BaseJsonRpcMessage
+	if err := json.Unmarshal(trimmed, &message); err != nil {
+		return nil, false, err
+	}
+	return []*transport.BaseJsonRpcMessage{&message}, false, nil
+}
@@ -2,129 +2,155 @@ package http
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
-
-	"github.com/metoro-io/mcp-golang/transport"
 )
 
-// HTTPTransport implements a stateless HTTP transport for MCP
+// HTTPTransport implements a stateless HTTP transport for MCP.
+//
+// HTTPTransport is an http.Handler, so it can be mounted on any router instead of listening by itself:
+//
+//	transport := http.NewHTTPHandler()
+//	server := mcp_golang.NewServer(transport)
+//	go server.Serve()
+//	mux.Handle("/mcp", transport)
 type HTTPTransport struct {
 	*baseTransport
-	server         *http.Server
-	endpoint       string
-	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
-	errorHandler   func(error)
-	closeHandler   func()
-	mu             sync.RWMutex
-	addr           string
-	responseMap    map[int64]chan *transport.BaseJsonRpcMessage
+	server   *http.Server
+	serverMu sync.Mutex
+	endpoint string
+	addr     string
+	tls      serverTLS
 }
 
-// NewHTTPTransport creates a new HTTP transport that listens on the specified endpoint
+// NewHTTPTransport creates a new HTTP transport that listens on the specified endpoint when started
 func NewHTTPTransport(endpoint string) *HTTPTransport {
 	return &HTTPTransport{
 		baseTransport: newBaseTransport(),
 		endpoint:      endpoint,
 		addr:          ":8080", // Default port
-		responseMap:   make(map[int64]chan *transport.BaseJsonRpcMessage),
 	}
 }
 
-// WithAddr sets the address to listen on
+// NewHTTPHandler creates a new HTTP transport that does not listen by itself. Start returns immediately and requests
+// are served once the transport is mounted on a router with ServeHTTP.
+func NewHTTPHandler() *HTTPTransport {
+	return &HTTPTransport{
+		baseTransport: newBaseTransport(),
+	}
+}
+
+// WithAddr sets the address to listen on. An empty address disables the built-in server.
 func (t *HTTPTransport) WithAddr(addr string) *HTTPTransport {
 	t.addr = addr
 	return t
 }
 
-// Start implements Transport.Start
-func (t *HTTPTransport) Start(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc(t.endpoint, t.handleRequest)
-
-	t.server = &http.Server{
-		Addr:    t.addr,
-		Handler: mux,
-	}
+// WithTLSConfig serves the built-in server over TLS with config
+func (t *HTTPTransport) WithTLSConfig(config *tls.Config) *HTTPTransport {
+	t.tls.config = config
+	return t
+}
 
-	return t.server.ListenAndServe()
+// WithTLSCertFile serves the built-in server over TLS with the certificate and key in the given PEM files. They are
+// loaded when the transport is started.
+func (t *HTTPTransport) WithTLSCertFile(certFile, keyFile string) *HTTPTransport {
+	t.tls.certFile = certFile
+	t.tls.keyFile = keyFile
+	return t
 }
 
-// Send implements Transport.Send
-func (t *HTTPTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
-	key := message.JsonRpcResponse.Id
-	responseChannel := t.responseMap[int64(key)]
-	if responseChannel == nil {
-		return fmt.Errorf("no response channel found for key: %d", key)
-	}
-	responseChannel <- message
-	return nil
+// WithClientCAs requires mutual TLS: clients must present a certificate signed by one of the CAs in pool.
+// Handlers can read the verified certificate with ClientCertificateFromContext.
+func (t *HTTPTransport) WithClientCAs(pool *x509.CertPool) *HTTPTransport {
+	t.tls.clientCAs = pool
+	return t
 }
 
-// Close implements Transport.Close
-func (t *HTTPTransport) Close() error {
-	if t.server != nil {
-		if err := t.server.Close(); err != nil {
-			return err
-		}
+// Start implements Transport.Start
+// If the transport has an address, Start listens on it and serves the endpoint in the background until the transport
+// is shut down or closed. Otherwise it returns immediately.
+func (t *HTTPTransport) Start(ctx context.Context) error {
+	if t.addr == "" {
+		return nil
 	}
-	if t.closeHandler != nil {
-		t.closeHandler()
+
+	tlsConfig, err := t.tls.tlsConfig()
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-// SetCloseHandler implements Transport.SetCloseHandler
-func (t *HTTPTransport) SetCloseHandler(handler func()) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.closeHandler = handler
-}
+	mux := http.NewServeMux()
+	mux.Handle(t.endpoint, t)
 
-// SetErrorHandler implements Transport.SetErrorHandler
-func (t *HTTPTransport) SetErrorHandler(handler func(error)) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.errorHandler = handler
+	listener, err := net.Listen("tcp", t.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", t.addr, err)
+	}
+	t.serverMu.Lock()
+	t.server = &http.Server{
+		Addr:      t.addr,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}
+	server := t.server
+	t.serverMu.Unlock()
+
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.reportError(fmt.Errorf("server error: %w", err))
+		}
+	}()
+	return nil
 }
 
-// SetMessageHandler implements Transport.SetMessageHandler
-func (t *HTTPTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.messageHandler = handler
+// ServeHTTP implements http.Handler
+// The context passed to tool, prompt and resource handlers is the context of the request.
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.serveHTTP(r.Context(), w, r)
 }
 
-func (t *HTTPTransport) handleRequest(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is supported", http.StatusMethodNotAllowed)
-		return
-	}
-
-	ctx := r.Context()
-	body, err := t.readBody(r.Body)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// Shutdown implements transport.Shutdowner
+// The built-in server stops accepting connections and waits for the requests being served to be answered. If ctx is
+// done first, the remaining connections are closed.
+func (t *HTTPTransport) Shutdown(ctx context.Context) error {
+	t.serverMu.Lock()
+	server := t.server
+	t.serverMu.Unlock()
+
+	var err error
+	if server != nil {
+		if err = server.Shutdown(ctx); err != nil {
+			server.Close()
+		}
 	}
-
-	response, err := t.handleMessage(ctx, body)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if closeErr := t.baseTransport.Close(); err == nil {
+		err = closeErr
 	}
+	return err
+}
 
-	jsonData, err := json.Marshal(response)
-	if err != nil {
-		if t.errorHandler != nil {
-			t.errorHandler(fmt.Errorf("failed to marshal response: %w", err))
+// Close implements Transport.Close
+// Active connections are closed straight away, use Shutdown to let them finish.
+func (t *HTTPTransport) Close() error {
+	t.serverMu.Lock()
+	server := t.server
+	t.serverMu.Unlock()
+	if server != nil {
+		if err := server.Close(); err != nil {
+			return err
 		}
-		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
-		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(jsonData)
+	return t.baseTransport.Close()
 }
@@ -3,6 +3,7 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +14,9 @@ import (
 )
 
 // HTTPClientTransport implements a client-side HTTP transport for MCP
+//
+// Deprecated: Use StreamableHTTPClientTransport, which also supports servers that stream responses and notifications.
+// It works with HTTPTransport and GinTransport servers as well.
 type HTTPClientTransport struct {
 	baseURL        string
 	endpoint       string
@@ -45,6 +49,24 @@ func (t *HTTPClientTransport) WithHeader(key, value string) *HTTPClientTransport
 	return t
 }
 
+// WithHTTPClient sets the client used to send requests
+func (t *HTTPClientTransport) WithHTTPClient(client *http.Client) *HTTPClientTransport {
+	t.client = client
+	return t
+}
+
+// WithRoundTripper sets the RoundTripper used to send requests, e.g. to add authentication or tracing
+func (t *HTTPClientTransport) WithRoundTripper(roundTripper http.RoundTripper) *HTTPClientTransport {
+	t.client = withRoundTripper(t.client, roundTripper)
+	return t
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to the server. For mutual TLS, include the client
+// certificate in config.Certificates.
+func (t *HTTPClientTransport) WithTLSConfig(config *tls.Config) *HTTPClientTransport {
+	return t.WithRoundTripper(tlsRoundTripper(config))
+}
+
 // Start implements Transport.Start
 func (t *HTTPClientTransport) Start(ctx context.Context) error {
 	// Does nothing in the stateless http client transport
@@ -57,7 +79,21 @@ func (t *HTTPClientTransport) Send(ctx context.Context, message *transport.BaseJ
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
+	return t.post(ctx, jsonData)
+}
+
+// SendBatch implements transport.BatchSender
+// The responses to the requests of the batch are delivered to the message handler one at a time.
+func (t *HTTPClientTransport) SendBatch(ctx context.Context, messages []*transport.BaseJsonRpcMessage) error {
+	jsonData, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	return t.post(ctx, jsonData)
+}
 
+// post sends a message or batch and delivers the messages in the response body to the message handler
+func (t *HTTPClientTransport) post(ctx context.Context, jsonData []byte) error {
 	url := fmt.Sprintf("%s%s", t.baseURL, t.endpoint)
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
@@ -79,64 +115,25 @@ func (t *HTTPClientTransport) Send(ctx context.Context, message *transport.BaseJ
 		return fmt.Errorf("failed to read response: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	// Notifications and responses are acknowledged without a body
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		return fmt.Errorf("server returned error: %s (status: %d)", string(body), resp.StatusCode)
 	}
 
-	if len(body) > 0 {
-		// Try to unmarshal as a response first
-		var response transport.BaseJSONRPCResponse
-		if err := json.Unmarshal(body, &response); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
-
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageResponse(&response))
-			}
-			return nil
-		}
-
-		// Try as an error
-		var errorResponse transport.BaseJSONRPCError
-		if err := json.Unmarshal(body, &errorResponse); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
-
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageError(&errorResponse))
-			}
-			return nil
+	if len(bytes.TrimSpace(body)) > 0 {
+		messages, _, err := unmarshalMessages(body)
+		if err != nil {
+			return fmt.Errorf("received invalid response: %s", string(body))
 		}
 
-		// Try as a notification
-		var notification transport.BaseJSONRPCNotification
-		if err := json.Unmarshal(body, &notification); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
-
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageNotification(&notification))
-			}
-			return nil
-		}
-
-		// Try as a request
-		var request transport.BaseJSONRPCRequest
-		if err := json.Unmarshal(body, &request); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
-
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageRequest(&request))
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			for _, message := range messages {
+				handler(ctx, message)
 			}
-			return nil
 		}
-
-		return fmt.Errorf("received invalid response: %s", string(body))
 	}
 
 	return nil
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userContextKey struct{}
+
+// echoRequests answers every request with its method name and the user stored in its context, if any
+func echoRequests(t *testing.T, tr transport.Transport) {
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return
+		}
+		request := message.JsonRpcRequest
+		go func() {
+			user, _ := ctx.Value(userContextKey{}).(string)
+			result, _ := json.Marshal(map[string]string{"method": request.Method, "user": user})
+			err := tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      request.Id,
+				Result:  result,
+			}))
+			assert.NoError(t, err)
+		}()
+	})
+	require.NoError(t, tr.Start(context.Background()))
+}
+
+func post(t *testing.T, url string, body string) (int, string) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestHTTPHandler(t *testing.T) {
+	t.Run("mounted behind middleware", func(t *testing.T) {
+		tr := NewHTTPHandler()
+		echoRequests(t, tr)
+
+		withUser := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := context.WithValue(r.Context(), userContextKey{}, r.Header.Get("X-User"))
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/api/mcp", withUser(tr))
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+		require.NoError(t, err)
+		req.Header.Set("X-User", "alice")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var message transport.BaseJsonRpcMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+		require.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, message.Type)
+		assert.Equal(t, transport.NewRequestId(7), message.JsonRpcResponse.Id)
+		assert.JSONEq(t, `{"method":"ping","user":"alice"}`, string(message.JsonRpcResponse.Result))
+	})
+
+	t.Run("only POST is supported", func(t *testing.T) {
+		tr := NewHTTPHandler()
+		echoRequests(t, tr)
+		server := httptest.NewServer(tr)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("concurrent requests get their own responses", func(t *testing.T) {
+		tr := NewHTTPHandler()
+		echoRequests(t, tr)
+		server := httptest.NewServer(tr)
+		defer server.Close()
+
+		methods := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+		results := make(chan string, len(methods))
+		for _, method := range methods {
+			go func(method string) {
+				_, body := post(t, server.URL, `{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`)
+				results <- method + " " + body
+			}(method)
+		}
+		for range methods {
+			result := <-results
+			method, body, _ := strings.Cut(result, " ")
+			assert.Contains(t, body, `"method":"`+method+`"`)
+		}
+	})
+
+	t.Run("batches are answered with one batch", func(t *testing.T) {
+		tr := NewHTTPHandler()
+		echoRequests(t, tr)
+		server := httptest.NewServer(tr)
+		defer server.Close()
+
+		status, body := post(t, server.URL, `[
+			{"jsonrpc":"2.0","id":1,"method":"a"},
+			{"jsonrpc":"2.0","method":"notifications/initialized"},
+			{"jsonrpc":"2.0","id":"two","method":"b"}
+		]`)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `[
+			{"jsonrpc":"2.0","id":1,"result":{"method":"a","user":""}},
+			{"jsonrpc":"2.0","id":"two","result":{"method":"b","user":""}}
+		]`, body)
+
+		// Notifications only get an acknowledgement
+		status, body = post(t, server.URL, `[{"jsonrpc":"2.0","method":"notifications/initialized"}]`)
+		assert.Equal(t, http.StatusAccepted, status)
+		assert.Empty(t, body)
+
+		status, _ = post(t, server.URL, `[]`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("invalid messages are answered with JSON-RPC errors", func(t *testing.T) {
+		tr := NewHTTPHandler()
+		echoRequests(t, tr)
+		server := httptest.NewServer(tr)
+		defer server.Close()
+
+		status, body := post(t, server.URL, `{"jsonrpc":"2.0","id":1,"method":`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error: unexpected end of JSON input"}}`, body)
+
+		status, body = post(t, server.URL, `{"jsonrpc":"2.0","id":1}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, `"code":-32600`)
+		assert.Contains(t, body, `"id":1`)
+
+		status, body = post(t, server.URL, `[]`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, `"code":-32600`)
+		assert.Contains(t, body, `"id":null`)
+	})
+}
+
+func TestGinTransport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tr := NewGinTransport()
+	echoRequests(t, tr)
+
+	router := gin.New()
+	router.POST("/mcp", tr.Handler())
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	status, body := post(t, server.URL+"/mcp", `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":3,"result":{"method":"ping","user":""}}`, body)
+}
@@ -0,0 +1,929 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/sse"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// McpSessionIdHeader is the header used by the Streamable HTTP transport to carry the session id
+const McpSessionIdHeader = "Mcp-Session-Id"
+
+var (
+	errStreamClosed    = errors.New("stream closed")
+	errTooManySessions = errors.New("Service Unavailable: too many sessions")
+)
+
+// StreamableHTTPTransport implements the server side of the Streamable HTTP transport (MCP protocol revision 2025-03-26).
+//
+// Clients POST JSON-RPC messages to a single endpoint. Requests are answered either with a single application/json
+// body or with a text/event-stream that stays open until every request in the POST has been answered, so the server
+// can also send notifications and requests related to them. Clients can additionally open a GET stream on the same
+// endpoint to receive messages the server sends unprompted.
+//
+// A session id is issued in the Mcp-Session-Id header of the response to the initialize request, and clients must send
+// it back on every following request. A DELETE request on the endpoint terminates the session, and so does an
+// initialize request that fails.
+//
+// The transport can be used in two ways. Served as the transport of a Server, all sessions share the server's protocol
+// state. Passed to Server.ServeListener instead, it is a transport.Listener that hands out a transport for every
+// session, so that each session gets its own protocol state:
+//
+//	t := http.NewStreamableHTTPTransport("/mcp").WithSessionTimeout(30 * time.Minute)
+//	server := mcp_golang.NewServer(nil)
+//	go server.ServeListener(t)
+//	mux.Handle("/mcp", t)
+type StreamableHTTPTransport struct {
+	*baseTransport
+	server         *http.Server
+	endpoint       string
+	addr           string
+	jsonResponse   bool
+	sessionTimeout time.Duration
+	maxSessions    int
+	tls            serverTLS
+	// Guarded by the mutex of baseTransport
+	sessionCloseHandler func(session string)
+
+	stateMu       sync.Mutex
+	sessions      map[string]*streamableSession
+	pending       map[transport.RequestId]*pendingResponse
+	inflight      map[inflightKey]transport.RequestId
+	nextRequestId int64
+
+	// Listener mode, see Accept
+	listening bool
+	accepted  chan *streamableSessionTransport
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type streamableSession struct {
+	id string
+	// The standalone stream opened with GET, nil if there is none
+	standalone *streamableStream
+	// Set once the initialize request of the session has been answered successfully
+	initialized bool
+
+	// The transport of the session in listener mode, nil when sessions share the transport's protocol
+	transport *streamableSessionTransport
+	// Requests being handled in listener mode, by the id the client gave them
+	pending map[transport.RequestId]*pendingResponse
+
+	// The verified certificate of the client that created the session, if it used mutual TLS
+	clientCertificate *x509.Certificate
+
+	// Number of HTTP requests in progress, the session does not expire while there are any
+	active int
+	expiry *time.Timer
+}
+
+// The transport rewrites the ids of incoming requests so that requests from different sessions can never collide.
+// pendingResponse remembers where the response to a rewritten request has to go.
+type pendingResponse struct {
+	session    *streamableSession
+	originalId transport.RequestId
+	stream     *streamableStream
+	initialize bool
+}
+
+type inflightKey struct {
+	sessionId  string
+	originalId transport.RequestId
+}
+
+type streamContextKey struct{}
+type sessionContextKey struct{}
+
+// SessionIDFromContext returns the id of the Streamable HTTP session a request belongs to. The context passed to tool,
+// prompt and resource handlers carries it.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(*streamableSession)
+	if !ok {
+		return "", false
+	}
+	return session.id, true
+}
+
+// NewStreamableHTTPTransport creates a new Streamable HTTP transport that listens on the specified endpoint
+func NewStreamableHTTPTransport(endpoint string) *StreamableHTTPTransport {
+	return &StreamableHTTPTransport{
+		baseTransport: newBaseTransport(),
+		endpoint:      endpoint,
+		addr:          ":8080", // Default port
+		sessions:      make(map[string]*streamableSession),
+		pending:       make(map[transport.RequestId]*pendingResponse),
+		inflight:      make(map[inflightKey]transport.RequestId),
+		accepted:      make(chan *streamableSessionTransport),
+		closed:        make(chan struct{}),
+	}
+}
+
+// WithAddr sets the address to listen on
+func (t *StreamableHTTPTransport) WithAddr(addr string) *StreamableHTTPTransport {
+	t.addr = addr
+	return t
+}
+
+// WithJSONResponse makes the transport answer requests with a single application/json body instead of a
+// text/event-stream. Messages the server sends while handling a request are then delivered on the GET stream instead.
+func (t *StreamableHTTPTransport) WithJSONResponse(enabled bool) *StreamableHTTPTransport {
+	t.jsonResponse = enabled
+	return t
+}
+
+// WithTLSConfig serves the transport over TLS with config when it is started
+func (t *StreamableHTTPTransport) WithTLSConfig(config *tls.Config) *StreamableHTTPTransport {
+	t.tls.config = config
+	return t
+}
+
+// WithTLSCertFile serves the transport over TLS with the certificate and key in the given PEM files when it is
+// started. They are loaded by Start.
+func (t *StreamableHTTPTransport) WithTLSCertFile(certFile, keyFile string) *StreamableHTTPTransport {
+	t.tls.certFile = certFile
+	t.tls.keyFile = keyFile
+	return t
+}
+
+// WithClientCAs requires mutual TLS when the transport is started: clients must present a certificate signed by one of
+// the CAs in pool. Handlers can read the verified certificate with ClientCertificateFromContext, and a session can only
+// be used with the certificate it was created with.
+func (t *StreamableHTTPTransport) WithClientCAs(pool *x509.CertPool) *StreamableHTTPTransport {
+	t.tls.clientCAs = pool
+	return t
+}
+
+// WithSessionTimeout terminates sessions that have not been used for timeout. A session is in use while one of its
+// requests or its GET stream is open. Zero, the default, keeps sessions until they are deleted.
+func (t *StreamableHTTPTransport) WithSessionTimeout(timeout time.Duration) *StreamableHTTPTransport {
+	t.sessionTimeout = timeout
+	return t
+}
+
+// WithMaxSessions limits the number of sessions open at once. Initialize requests beyond the limit are answered with
+// 503 Service Unavailable. Zero, the default, means no limit.
+func (t *StreamableHTTPTransport) WithMaxSessions(max int) *StreamableHTTPTransport {
+	t.maxSessions = max
+	return t
+}
+
+// Start implements Transport.Start
+// Unlike HTTPTransport, Start returns as soon as the transport is listening so that the server can send notifications.
+func (t *StreamableHTTPTransport) Start(ctx context.Context) error {
+	tlsConfig, err := t.tls.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(t.endpoint, t.handleRequest)
+
+	listener, err := net.Listen("tcp", t.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", t.addr, err)
+	}
+	t.server = &http.Server{
+		Addr:      t.addr,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}
+
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = t.server.ServeTLS(listener, "", "")
+		} else {
+			err = t.server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.reportError(fmt.Errorf("server error: %w", err))
+		}
+	}()
+	return nil
+}
+
+// ServeHTTP implements http.Handler, so the transport can be mounted on any router instead of being started
+func (t *StreamableHTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.handleRequest(w, r)
+}
+
+// Accept implements transport.Listener
+//
+// The first call switches the transport to listener mode: every session created from then on is served by a transport
+// of its own, which Accept returns when the client sends its initialize request. Call Server.ServeListener before
+// serving HTTP requests, and do not serve the StreamableHTTPTransport itself with a Server.
+func (t *StreamableHTTPTransport) Accept() (transport.Transport, error) {
+	t.stateMu.Lock()
+	t.listening = true
+	t.stateMu.Unlock()
+
+	select {
+	case tr := <-t.accepted:
+		return tr, nil
+	case <-t.closed:
+		return nil, transport.ErrListenerClosed
+	}
+}
+
+// Send implements Transport.Send
+//
+// Responses are routed to the POST they answer. Other messages are sent on the POST stream of the request that is being
+// handled if ctx belongs to one, otherwise on the GET stream of the session, or on the GET streams of all sessions.
+func (t *StreamableHTTPTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType, transport.BaseMessageTypeJSONRPCErrorType:
+		return t.sendResponse(message)
+	}
+
+	if stream, ok := ctx.Value(streamContextKey{}).(*streamableStream); ok && stream.sse {
+		if err := stream.write(message); err == nil {
+			return nil
+		}
+	}
+
+	t.stateMu.Lock()
+	var streams []*streamableStream
+	session, ok := ctx.Value(sessionContextKey{}).(*streamableSession)
+	if !ok {
+		// A session named with transport.WithSession outside of the handling of its requests, which may have ended
+		if id, named := transport.SessionFromContext(ctx); named {
+			session, ok = t.sessions[id], true
+		}
+	}
+	if ok {
+		if session != nil && session.standalone != nil {
+			streams = append(streams, session.standalone)
+		}
+	} else {
+		for _, session := range t.sessions {
+			if session.standalone != nil {
+				streams = append(streams, session.standalone)
+			}
+		}
+	}
+	t.stateMu.Unlock()
+
+	// Without an open GET stream there is nowhere to deliver the message, which the spec allows
+	for _, stream := range streams {
+		if err := stream.write(message); err != nil && !errors.Is(err, errStreamClosed) {
+			t.reportError(fmt.Errorf("failed to write to stream: %w", err))
+		}
+	}
+	return nil
+}
+
+func (t *StreamableHTTPTransport) sendResponse(message *transport.BaseJsonRpcMessage) error {
+	id := responseId(message)
+
+	t.stateMu.Lock()
+	pending := t.pending[id]
+	if pending != nil {
+		delete(t.pending, id)
+		delete(t.inflight, inflightKey{sessionId: pending.session.id, originalId: pending.originalId})
+	}
+	t.stateMu.Unlock()
+
+	if pending == nil {
+		return fmt.Errorf("no pending request found for id: %s", id)
+	}
+	return t.deliverResponse(pending, message)
+}
+
+// deliverResponse restores the id the client gave a request and writes the response to the stream of the request.
+// A session whose initialize request fails is terminated.
+func (t *StreamableHTTPTransport) deliverResponse(pending *pendingResponse, message *transport.BaseJsonRpcMessage) error {
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType {
+		message.JsonRpcResponse.Id = pending.originalId
+	} else {
+		message.JsonRpcError.Id = pending.originalId
+	}
+
+	if pending.initialize {
+		if message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+			defer t.terminateSession(pending.session)
+		} else {
+			t.stateMu.Lock()
+			pending.session.initialized = true
+			t.stateMu.Unlock()
+		}
+	}
+	return pending.stream.respond(message)
+}
+
+// Close implements Transport.Close
+//
+// In listener mode Close only stops accepting new sessions, the sessions that have been accepted end when their own
+// transports are closed.
+func (t *StreamableHTTPTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+
+	t.stateMu.Lock()
+	listening := t.listening
+	t.stateMu.Unlock()
+	if listening {
+		return nil
+	}
+
+	if t.server != nil {
+		if err := t.server.Close(); err != nil {
+			return err
+		}
+	}
+
+	t.stateMu.Lock()
+	sessions := make([]*streamableSession, 0, len(t.sessions))
+	for _, session := range t.sessions {
+		sessions = append(sessions, session)
+	}
+	t.stateMu.Unlock()
+	for _, session := range sessions {
+		t.terminateSession(session)
+	}
+
+	t.mu.RLock()
+	handler := t.closeHandler
+	t.mu.RUnlock()
+	if handler != nil {
+		handler()
+	}
+	return nil
+}
+
+func (t *StreamableHTTPTransport) handleRequest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleGet(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *StreamableHTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	if !acceptsMediaType(r, "application/json") || !acceptsMediaType(r, "text/event-stream") {
+		http.Error(w, "Not Acceptable: client must accept both application/json and text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "Unsupported Media Type: Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := t.readBody(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	messages, isBatch, err := unmarshalMessages(body)
+	if err != nil {
+		t.writeInvalidMessage(w, body, err)
+		return
+	}
+
+	var session *streamableSession
+	if isInitializeRequest(messages) {
+		if len(messages) > 1 {
+			http.Error(w, "Invalid Request: the initialize request must not be part of a batch", http.StatusBadRequest)
+			return
+		}
+		session, err = t.newSession(verifiedClientCertificate(r))
+		if errors.Is(err, errTooManySessions) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer t.release(session)
+		if session.transport != nil {
+			if err := t.startSession(r.Context(), session); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set(McpSessionIdHeader, session.id)
+	} else {
+		var status int
+		session, status, err = t.sessionFromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		defer t.release(session)
+	}
+
+	ctx := withClientCertificate(transport.WithSession(context.WithValue(r.Context(), sessionContextKey{}, session), session.id), r)
+
+	requestCount := 0
+	for _, message := range messages {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			requestCount++
+		}
+	}
+
+	// Notifications and responses are only acknowledged
+	if requestCount == 0 {
+		for _, message := range messages {
+			t.dispatch(ctx, session, message)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	stream := &streamableStream{
+		w:           w,
+		flusher:     flusher,
+		sse:         !t.jsonResponse && canFlush,
+		outstanding: requestCount,
+		done:        make(chan struct{}),
+	}
+	ctx = context.WithValue(ctx, streamContextKey{}, stream)
+
+	if stream.sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+	}
+
+	for _, message := range messages {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			t.trackRequest(session, stream, message.JsonRpcRequest)
+		}
+		t.dispatch(ctx, session, message)
+	}
+
+	select {
+	case <-stream.done:
+	case <-r.Context().Done():
+		// The client went away, responses to its requests can no longer be delivered
+		t.untrackStream(session, stream)
+	}
+	stream.close()
+
+	if stream.sse {
+		return
+	}
+
+	var jsonData []byte
+	if isBatch {
+		jsonData, err = json.Marshal(stream.responses)
+	} else if len(stream.responses) == 1 {
+		jsonData, err = json.Marshal(stream.responses[0])
+	} else {
+		return
+	}
+	if err != nil {
+		t.reportError(fmt.Errorf("failed to marshal response: %w", err))
+		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+func (t *StreamableHTTPTransport) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsMediaType(r, "text/event-stream") {
+		http.Error(w, "Not Acceptable: client must accept text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	session, status, err := t.sessionFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer t.release(session)
+
+	stream := &streamableStream{
+		w:       w,
+		flusher: flusher,
+		sse:     true,
+		done:    make(chan struct{}),
+	}
+
+	t.stateMu.Lock()
+	if session.standalone != nil {
+		t.stateMu.Unlock()
+		http.Error(w, "Conflict: only one GET stream is allowed per session", http.StatusConflict)
+		return
+	}
+	// Hold the stream until the headers are written, messages may be sent to it as soon as it is registered
+	stream.mu.Lock()
+	session.standalone = stream
+	t.stateMu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	stream.mu.Unlock()
+
+	select {
+	case <-stream.done:
+	case <-r.Context().Done():
+	}
+
+	t.stateMu.Lock()
+	if session.standalone == stream {
+		session.standalone = nil
+	}
+	t.stateMu.Unlock()
+	stream.close()
+}
+
+func (t *StreamableHTTPTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	session, status, err := t.sessionFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer t.release(session)
+
+	t.terminateSession(session)
+	w.WriteHeader(http.StatusOK)
+}
+
+// newSession creates a session for an initialize request. The session is in use until it is released.
+func (t *StreamableHTTPTransport) newSession(clientCertificate *x509.Certificate) (*streamableSession, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	session := &streamableSession{id: id, active: 1, clientCertificate: clientCertificate}
+
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+	if t.maxSessions > 0 && len(t.sessions) >= t.maxSessions {
+		return nil, errTooManySessions
+	}
+	if t.listening {
+		session.transport = newStreamableSessionTransport(t, session)
+		session.pending = make(map[transport.RequestId]*pendingResponse)
+	}
+	t.sessions[id] = session
+	return session, nil
+}
+
+// startSession hands the transport of a new session to Accept and waits for it to be started
+func (t *StreamableHTTPTransport) startSession(ctx context.Context, session *streamableSession) error {
+	select {
+	case t.accepted <- session.transport:
+	case <-t.closed:
+		t.terminateSession(session)
+		return errors.New("Service Unavailable: not accepting new sessions")
+	case <-ctx.Done():
+		t.terminateSession(session)
+		return ctx.Err()
+	}
+
+	select {
+	case <-session.transport.started:
+		return nil
+	case <-session.transport.done:
+		return errors.New("Service Unavailable: failed to start session")
+	case <-ctx.Done():
+		t.terminateSession(session)
+		return ctx.Err()
+	}
+}
+
+// sessionFromRequest looks up the session named by the header of r. The session is in use until it is released.
+func (t *StreamableHTTPTransport) sessionFromRequest(r *http.Request) (*streamableSession, int, error) {
+	id := r.Header.Get(McpSessionIdHeader)
+	if id == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("Bad Request: %s header is required", McpSessionIdHeader)
+	}
+
+	t.stateMu.Lock()
+	session := t.sessions[id]
+	if session != nil && session.clientCertificate != nil && !session.clientCertificate.Equal(verifiedClientCertificate(r)) {
+		t.stateMu.Unlock()
+		return nil, http.StatusForbidden, errors.New("Forbidden: the session belongs to another client")
+	}
+	if session != nil {
+		session.active++
+		if session.expiry != nil {
+			session.expiry.Stop()
+		}
+	}
+	t.stateMu.Unlock()
+
+	if session == nil {
+		return nil, http.StatusNotFound, errors.New("Session not found")
+	}
+	return session, 0, nil
+}
+
+// release marks the end of an HTTP request of session. A session whose initialize request was never answered is
+// terminated, otherwise the expiry timer starts once the session is no longer in use.
+func (t *StreamableHTTPTransport) release(session *streamableSession) {
+	t.stateMu.Lock()
+	session.active--
+	if session.active > 0 || t.sessions[session.id] != session {
+		t.stateMu.Unlock()
+		return
+	}
+	if !session.initialized {
+		streams := t.removeSession(session)
+		t.stateMu.Unlock()
+		t.endSession(session, streams)
+		return
+	}
+	if t.sessionTimeout > 0 {
+		if session.expiry == nil {
+			session.expiry = time.AfterFunc(t.sessionTimeout, func() { t.expireSession(session) })
+		} else {
+			session.expiry.Reset(t.sessionTimeout)
+		}
+	}
+	t.stateMu.Unlock()
+}
+
+func (t *StreamableHTTPTransport) expireSession(session *streamableSession) {
+	t.stateMu.Lock()
+	if session.active > 0 || t.sessions[session.id] != session {
+		t.stateMu.Unlock()
+		return
+	}
+	streams := t.removeSession(session)
+	t.stateMu.Unlock()
+	t.endSession(session, streams)
+}
+
+// terminateSession removes session and ends its streams and its transport
+func (t *StreamableHTTPTransport) terminateSession(session *streamableSession) {
+	t.stateMu.Lock()
+	if t.sessions[session.id] != session {
+		t.stateMu.Unlock()
+		return
+	}
+	streams := t.removeSession(session)
+	t.stateMu.Unlock()
+	t.endSession(session, streams)
+}
+
+// removeSession removes session and the requests it has in flight, and returns the streams to close.
+// It must be called with stateMu held.
+func (t *StreamableHTTPTransport) removeSession(session *streamableSession) []*streamableStream {
+	delete(t.sessions, session.id)
+	if session.expiry != nil {
+		session.expiry.Stop()
+	}
+
+	var streams []*streamableStream
+	if session.standalone != nil {
+		streams = append(streams, session.standalone)
+		session.standalone = nil
+	}
+	for id, pending := range t.pending {
+		if pending.session == session {
+			streams = append(streams, pending.stream)
+			delete(t.pending, id)
+			delete(t.inflight, inflightKey{sessionId: session.id, originalId: pending.originalId})
+		}
+	}
+	for id, pending := range session.pending {
+		streams = append(streams, pending.stream)
+		delete(session.pending, id)
+	}
+	return streams
+}
+
+func (t *StreamableHTTPTransport) endSession(session *streamableSession, streams []*streamableStream) {
+	for _, stream := range streams {
+		stream.close()
+	}
+	if session.transport != nil {
+		session.transport.finish()
+	}
+
+	t.mu.RLock()
+	handler := t.sessionCloseHandler
+	t.mu.RUnlock()
+	if handler != nil {
+		handler(session.id)
+	}
+}
+
+// SetSessionCloseHandler implements transport.SessionCloseNotifier. The handler is called when a session is terminated or expires.
+func (t *StreamableHTTPTransport) SetSessionCloseHandler(handler func(session string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessionCloseHandler = handler
+}
+
+// trackRequest remembers where to send the response to request. When sessions share the transport's protocol, the id
+// of request is rewritten to one that is unique across sessions.
+func (t *StreamableHTTPTransport) trackRequest(session *streamableSession, stream *streamableStream, request *transport.BaseJSONRPCRequest) {
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+
+	pending := &pendingResponse{
+		session:    session,
+		originalId: request.Id,
+		stream:     stream,
+		initialize: request.Method == "initialize",
+	}
+	if session.transport != nil {
+		// The session has a protocol of its own, ids only need to be unique within the session
+		session.pending[request.Id] = pending
+		return
+	}
+
+	id := transport.NewRequestId(t.nextRequestId)
+	t.nextRequestId++
+	t.pending[id] = pending
+	t.inflight[inflightKey{sessionId: session.id, originalId: request.Id}] = id
+	request.Id = id
+}
+
+func (t *StreamableHTTPTransport) untrackStream(session *streamableSession, stream *streamableStream) {
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+
+	for id, pending := range t.pending {
+		if pending.stream == stream {
+			delete(t.pending, id)
+			delete(t.inflight, inflightKey{sessionId: pending.session.id, originalId: pending.originalId})
+		}
+	}
+	for id, pending := range session.pending {
+		if pending.stream == stream {
+			delete(session.pending, id)
+		}
+	}
+}
+
+func (t *StreamableHTTPTransport) dispatch(ctx context.Context, session *streamableSession, message *transport.BaseJsonRpcMessage) {
+	if session.transport != nil {
+		session.transport.deliver(ctx, message)
+		return
+	}
+
+	if message.Type == transport.BaseMessageTypeJSONRPCNotificationType && message.JsonRpcNotification.Method == "notifications/cancelled" {
+		t.rewriteCancellation(session, message.JsonRpcNotification)
+	}
+
+	t.mu.RLock()
+	handler := t.messageHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(ctx, message)
+	}
+}
+
+// rewriteCancellation points a cancellation at the rewritten id of the request it cancels
+func (t *StreamableHTTPTransport) rewriteCancellation(session *streamableSession, notification *transport.BaseJSONRPCNotification) {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(notification.Params, &params); err != nil {
+		return
+	}
+	var originalId transport.RequestId
+	if err := json.Unmarshal(params["requestId"], &originalId); err != nil {
+		return
+	}
+
+	t.stateMu.Lock()
+	id, ok := t.inflight[inflightKey{sessionId: session.id, originalId: originalId}]
+	t.stateMu.Unlock()
+	if !ok {
+		return
+	}
+
+	rewritten, err := json.Marshal(id)
+	if err != nil {
+		return
+	}
+	params["requestId"] = rewritten
+	if marshalled, err := json.Marshal(params); err == nil {
+		notification.Params = marshalled
+	}
+}
+
+// streamableStream is the response to a single POST or GET request
+type streamableStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	// Whether messages are written as a text/event-stream, otherwise responses are collected into one JSON body
+	sse    bool
+	closed bool
+
+	// Number of requests in the POST that have not been answered yet
+	outstanding int
+	responses   []*transport.BaseJsonRpcMessage
+	// Closed once all requests have been answered or the stream is closed
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func (s *streamableStream) write(message *transport.BaseJsonRpcMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errStreamClosed
+	}
+	return s.writeEvent(message)
+}
+
+func (s *streamableStream) respond(message *transport.BaseJsonRpcMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errStreamClosed
+	}
+	if s.sse {
+		if err := s.writeEvent(message); err != nil {
+			return err
+		}
+	} else {
+		s.responses = append(s.responses, message)
+	}
+
+	s.outstanding--
+	if s.outstanding <= 0 {
+		s.doneOnce.Do(func() { close(s.done) })
+	}
+	return nil
+}
+
+func (s *streamableStream) writeEvent(message *transport.BaseJsonRpcMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if err := sse.WriteEvent(s.w, sse.Event{Event: "message", Data: string(data)}); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *streamableStream) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+func isInitializeRequest(messages []*transport.BaseJsonRpcMessage) bool {
+	for _, message := range messages {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType && message.JsonRpcRequest.Method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsMediaType reports whether the Accept header of r includes mediaType
+func acceptsMediaType(r *http.Request, mediaType string) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			accepted, _, _ := strings.Cut(part, ";")
+			accepted = strings.TrimSpace(accepted)
+			if accepted == mediaType || accepted == "*/*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
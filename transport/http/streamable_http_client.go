@@ -0,0 +1,328 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/sse"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// StreamableHTTPClientTransport implements the client side of the Streamable HTTP transport (MCP protocol revision 2025-03-26).
+//
+// Every message is POSTed to the server's endpoint. The server answers requests with either a JSON body or a
+// text/event-stream, and all messages in either are passed to the message handler. Once the server has issued a session
+// id, the transport sends it on every request and opens a GET stream to receive messages the server sends unprompted.
+type StreamableHTTPClientTransport struct {
+	url            string
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	mu             sync.RWMutex
+	client         *http.Client
+	headers        map[string]string
+
+	sessionId string
+	listening bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewStreamableHTTPClientTransport creates a new Streamable HTTP client transport that connects to the endpoint at url,
+// e.g. "http://localhost:8080/mcp"
+func NewStreamableHTTPClientTransport(url string) *StreamableHTTPClientTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamableHTTPClientTransport{
+		url:     url,
+		client:  &http.Client{},
+		headers: make(map[string]string),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// WithHeader adds a header to every request
+func (t *StreamableHTTPClientTransport) WithHeader(key, value string) *StreamableHTTPClientTransport {
+	t.headers[key] = value
+	return t
+}
+
+// WithHTTPClient sets the client used to send requests
+func (t *StreamableHTTPClientTransport) WithHTTPClient(client *http.Client) *StreamableHTTPClientTransport {
+	t.client = client
+	return t
+}
+
+// WithRoundTripper sets the RoundTripper used to send requests, e.g. to add authentication or tracing
+func (t *StreamableHTTPClientTransport) WithRoundTripper(roundTripper http.RoundTripper) *StreamableHTTPClientTransport {
+	t.client = withRoundTripper(t.client, roundTripper)
+	return t
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to the server. For mutual TLS, include the client
+// certificate in config.Certificates.
+func (t *StreamableHTTPClientTransport) WithTLSConfig(config *tls.Config) *StreamableHTTPClientTransport {
+	return t.WithRoundTripper(tlsRoundTripper(config))
+}
+
+// SessionID returns the session id issued by the server, or an empty string if there is none yet
+func (t *StreamableHTTPClientTransport) SessionID() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.sessionId
+}
+
+// Start implements Transport.Start
+func (t *StreamableHTTPClientTransport) Start(ctx context.Context) error {
+	// Nothing to connect to until the first message is sent
+	return nil
+}
+
+// Send implements Transport.Send
+func (t *StreamableHTTPClientTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return t.post(ctx, jsonData)
+}
+
+// SendBatch implements transport.BatchSender
+// The responses to the requests of the batch are delivered to the message handler one at a time.
+func (t *StreamableHTTPClientTransport) SendBatch(ctx context.Context, messages []*transport.BaseJsonRpcMessage) error {
+	jsonData, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	return t.post(ctx, jsonData)
+}
+
+// post sends a message or batch and delivers the messages of the response, read from its body or event stream
+func (t *StreamableHTTPClientTransport) post(ctx context.Context, jsonData []byte) error {
+	// The request is bound to the lifetime of the transport rather than ctx, a streamed response is read after Send returns
+	req, err := t.newRequest(http.MethodPost, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound && req.Header.Get(McpSessionIdHeader) != "" {
+		resp.Body.Close()
+		t.mu.Lock()
+		t.sessionId = ""
+		t.mu.Unlock()
+		return errors.New("session expired, the client must be initialized again")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned error: %s (status: %d)", string(body), resp.StatusCode)
+	}
+
+	if sessionId := resp.Header.Get(McpSessionIdHeader); sessionId != "" {
+		t.setSessionId(sessionId)
+	}
+
+	if resp.StatusCode == http.StatusAccepted {
+		resp.Body.Close()
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		go t.readStream(resp.Body)
+		return nil
+	default:
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		if len(bytes.TrimSpace(body)) == 0 {
+			return nil
+		}
+		messages, _, err := unmarshalMessages(body)
+		if err != nil {
+			return fmt.Errorf("received invalid response: %s", string(body))
+		}
+		for _, m := range messages {
+			t.handleMessage(ctx, m)
+		}
+		return nil
+	}
+}
+
+// Close implements Transport.Close
+// If the server issued a session, the session is terminated with a DELETE request.
+func (t *StreamableHTTPClientTransport) Close() error {
+	t.cancel()
+
+	t.mu.Lock()
+	sessionId := t.sessionId
+	t.sessionId = ""
+	t.mu.Unlock()
+
+	if sessionId != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+		if err == nil {
+			req.Header.Set(McpSessionIdHeader, sessionId)
+			for key, value := range t.headers {
+				req.Header.Set(key, value)
+			}
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+
+	t.mu.RLock()
+	handler := t.closeHandler
+	t.mu.RUnlock()
+	if handler != nil {
+		handler()
+	}
+	return nil
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *StreamableHTTPClientTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *StreamableHTTPClientTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *StreamableHTTPClientTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+func (t *StreamableHTTPClientTransport) newRequest(method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(t.ctx, method, t.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	if sessionId := t.SessionID(); sessionId != "" {
+		req.Header.Set(McpSessionIdHeader, sessionId)
+	}
+	return req, nil
+}
+
+// setSessionId stores the session id issued by the server and opens the GET stream the first time one is issued
+func (t *StreamableHTTPClientTransport) setSessionId(sessionId string) {
+	t.mu.Lock()
+	t.sessionId = sessionId
+	startListening := !t.listening
+	t.listening = true
+	t.mu.Unlock()
+
+	if startListening {
+		go t.listen()
+	}
+}
+
+// listen opens the GET stream on which the server sends messages that are not related to a POST
+func (t *StreamableHTTPClientTransport) listen() {
+	req, err := t.newRequest(http.MethodGet, nil)
+	if err != nil {
+		t.handleError(err)
+		return
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if t.ctx.Err() == nil {
+			t.handleError(fmt.Errorf("failed to open event stream: %w", err))
+		}
+		return
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		// The server does not offer a GET stream
+		resp.Body.Close()
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.handleError(fmt.Errorf("failed to open event stream (status: %d)", resp.StatusCode))
+		return
+	}
+
+	t.readStream(resp.Body)
+}
+
+func (t *StreamableHTTPClientTransport) readStream(body io.ReadCloser) {
+	defer body.Close()
+
+	reader := sse.NewReader(body)
+	for {
+		event, err := reader.Next()
+		if err != nil {
+			if err != io.EOF && t.ctx.Err() == nil {
+				t.handleError(fmt.Errorf("failed to read event stream: %w", err))
+			}
+			return
+		}
+		if event.Event != "" && event.Event != "message" {
+			continue
+		}
+
+		messages, _, err := unmarshalMessages([]byte(event.Data))
+		if err != nil {
+			t.handleError(fmt.Errorf("received invalid message: %w", err))
+			continue
+		}
+		for _, message := range messages {
+			t.handleMessage(t.ctx, message)
+		}
+	}
+}
+
+func (t *StreamableHTTPClientTransport) handleMessage(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+	t.mu.RLock()
+	handler := t.messageHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(ctx, message)
+	}
+}
+
+func (t *StreamableHTTPClientTransport) handleError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
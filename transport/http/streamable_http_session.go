@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// streamableSessionTransport is the transport of a single Streamable HTTP session in listener mode, see
+// StreamableHTTPTransport.Accept. It is closed when the session is terminated.
+type streamableSessionTransport struct {
+	parent  *StreamableHTTPTransport
+	session *streamableSession
+
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	mu             sync.RWMutex
+
+	started   chan struct{}
+	startOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+}
+
+func newStreamableSessionTransport(parent *StreamableHTTPTransport, session *streamableSession) *streamableSessionTransport {
+	return &streamableSessionTransport{
+		parent:  parent,
+		session: session,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start implements Transport.Start
+// The session is terminated when ctx is done.
+func (t *streamableSessionTransport) Start(ctx context.Context) error {
+	alreadyStarted := true
+	t.startOnce.Do(func() {
+		alreadyStarted = false
+		close(t.started)
+	})
+	if alreadyStarted {
+		return fmt.Errorf("session transport already started")
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.done:
+		}
+	}()
+	return nil
+}
+
+// Send implements Transport.Send
+// Responses are routed to the POST they answer. Other messages are sent on the POST stream of the request that is being
+// handled if ctx belongs to one, otherwise on the GET stream of the session.
+func (t *streamableSessionTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	select {
+	case <-t.done:
+		return errors.New("session terminated")
+	default:
+	}
+
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType, transport.BaseMessageTypeJSONRPCErrorType:
+		id := responseId(message)
+		t.parent.stateMu.Lock()
+		pending := t.session.pending[id]
+		delete(t.session.pending, id)
+		t.parent.stateMu.Unlock()
+
+		if pending == nil {
+			return fmt.Errorf("no pending request found for id: %s", id)
+		}
+		return t.parent.deliverResponse(pending, message)
+	}
+
+	if stream, ok := ctx.Value(streamContextKey{}).(*streamableStream); ok && stream.sse {
+		if err := stream.write(message); err == nil {
+			return nil
+		}
+	}
+
+	t.parent.stateMu.Lock()
+	stream := t.session.standalone
+	t.parent.stateMu.Unlock()
+
+	// Without an open GET stream there is nowhere to deliver the message, which the spec allows
+	if stream == nil {
+		return nil
+	}
+	if err := stream.write(message); err != nil && !errors.Is(err, errStreamClosed) {
+		return fmt.Errorf("failed to write to stream: %w", err)
+	}
+	return nil
+}
+
+// Close implements Transport.Close
+// The session is terminated, its client has to initialize a new one.
+func (t *streamableSessionTransport) Close() error {
+	t.parent.terminateSession(t.session)
+	t.finish()
+	return nil
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *streamableSessionTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *streamableSessionTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *streamableSessionTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+// SessionID returns the id of the session, as sent in the Mcp-Session-Id header
+func (t *streamableSessionTransport) SessionID() string {
+	return t.session.id
+}
+
+func (t *streamableSessionTransport) deliver(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+	t.mu.RLock()
+	handler := t.messageHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(ctx, message)
+	}
+}
+
+// finish calls the close handler, once
+func (t *streamableSessionTransport) finish() {
+	t.doneOnce.Do(func() {
+		close(t.done)
+
+		t.mu.RLock()
+		handler := t.closeHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			handler()
+		}
+	})
+}
@@ -0,0 +1,448 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/sse"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEchoStreamableServer starts a StreamableHTTPTransport whose message handler answers every request with its method name.
+// Requests for the "notify" method first send a notification on the stream of the request being handled.
+func newEchoStreamableServer(t *testing.T, tr *StreamableHTTPTransport) *httptest.Server {
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return
+		}
+		request := message.JsonRpcRequest
+		go func() {
+			if request.Method == "notify" {
+				err := tr.Send(ctx, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+					Jsonrpc: "2.0",
+					Method:  "notifications/progress",
+					Params:  json.RawMessage(`{}`),
+				}))
+				assert.NoError(t, err)
+			}
+			result, _ := json.Marshal(map[string]string{"method": request.Method})
+			err := tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      request.Id,
+				Result:  result,
+			}))
+			assert.NoError(t, err)
+		}()
+	})
+	server := httptest.NewServer(http.HandlerFunc(tr.handleRequest))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postMessage(t *testing.T, url string, sessionId string, body string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionId != "" {
+		req.Header.Set(McpSessionIdHeader, sessionId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func readEvents(t *testing.T, body io.Reader) []*transport.BaseJsonRpcMessage {
+	var messages []*transport.BaseJsonRpcMessage
+	reader := sse.NewReader(body)
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return messages
+		}
+		require.NoError(t, err)
+		var message transport.BaseJsonRpcMessage
+		require.NoError(t, json.Unmarshal([]byte(event.Data), &message))
+		messages = append(messages, &message)
+	}
+}
+
+func TestStreamableHTTPTransport(t *testing.T) {
+	t.Run("initialize creates a session and streams the response", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp"))
+
+		resp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":42,"method":"initialize","params":{}}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.NotEmpty(t, resp.Header.Get(McpSessionIdHeader))
+
+		messages := readEvents(t, resp.Body)
+		require.Len(t, messages, 1)
+		assert.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, messages[0].Type)
+		assert.Equal(t, transport.NewRequestId(42), messages[0].JsonRpcResponse.Id)
+		assert.JSONEq(t, `{"method":"initialize"}`, string(messages[0].JsonRpcResponse.Result))
+	})
+
+	t.Run("messages sent while handling a request go to its stream", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp"))
+		initResp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		initResp.Body.Close()
+		sessionId := initResp.Header.Get(McpSessionIdHeader)
+
+		resp := postMessage(t, server.URL, sessionId, `{"jsonrpc":"2.0","id":2,"method":"notify"}`)
+		defer resp.Body.Close()
+		messages := readEvents(t, resp.Body)
+		require.Len(t, messages, 2)
+		assert.Equal(t, transport.BaseMessageTypeJSONRPCNotificationType, messages[0].Type)
+		assert.Equal(t, "notifications/progress", messages[0].JsonRpcNotification.Method)
+		assert.Equal(t, transport.NewRequestId(2), messages[1].JsonRpcResponse.Id)
+	})
+
+	t.Run("session header is required", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp"))
+
+		resp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = postMessage(t, server.URL, "unknown", `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("notifications are accepted without a body", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp"))
+		initResp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		initResp.Body.Close()
+
+		resp := postMessage(t, server.URL, initResp.Header.Get(McpSessionIdHeader), `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	})
+
+	t.Run("json response mode answers batches with an array", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp").WithJSONResponse(true))
+		initResp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		initResp.Body.Close()
+		assert.Equal(t, "application/json", initResp.Header.Get("Content-Type"))
+
+		resp := postMessage(t, server.URL, initResp.Header.Get(McpSessionIdHeader),
+			`[{"jsonrpc":"2.0","id":1,"method":"a"},{"jsonrpc":"2.0","id":2,"method":"b"},{"jsonrpc":"2.0","method":"c"}]`)
+		defer resp.Body.Close()
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var responses []map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&responses))
+		require.Len(t, responses, 2)
+		ids := []float64{responses[0]["id"].(float64), responses[1]["id"].(float64)}
+		assert.ElementsMatch(t, []float64{1, 2}, ids)
+	})
+
+	t.Run("requests with the same id in different sessions do not collide", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp").WithJSONResponse(true))
+
+		for i := 0; i < 2; i++ {
+			resp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+			var response transport.BaseJSONRPCResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			resp.Body.Close()
+			assert.Equal(t, transport.NewRequestId(1), response.Id)
+		}
+	})
+
+	t.Run("unsupported requests are rejected", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp"))
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+
+		req, _ = http.NewRequest(http.MethodPut, server.URL, nil)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+		resp = postMessage(t, server.URL, "", `{not json`)
+		var parseError transport.BaseJsonRpcMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&parseError))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, transport.BaseMessageTypeJSONRPCErrorType, parseError.Type)
+		assert.Equal(t, transport.ErrorCodeParseError, parseError.JsonRpcError.Error.Code)
+		assert.True(t, parseError.JsonRpcError.Id.IsNull())
+	})
+}
+
+// newSessionStreamableServer serves tr in listener mode. Every session transport answers requests with their method
+// name, their id as seen by the handler and the session id from the context; initialize requests with "fail" as
+// params are answered with an error.
+func newSessionStreamableServer(t *testing.T, tr *StreamableHTTPTransport) (*httptest.Server, <-chan transport.Transport) {
+	accepted := make(chan transport.Transport, 10)
+	go func() {
+		for {
+			session, err := tr.Accept()
+			if err != nil {
+				assert.ErrorIs(t, err, transport.ErrListenerClosed)
+				return
+			}
+			session.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+				if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+					return
+				}
+				request := message.JsonRpcRequest
+				if request.Method == "initialize" && string(request.Params) == `"fail"` {
+					err := session.Send(ctx, transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+						Jsonrpc: "2.0",
+						Id:      request.Id,
+						Error:   transport.BaseJSONRPCErrorInner{Code: -32602, Message: "unsupported"},
+					}))
+					assert.NoError(t, err)
+					return
+				}
+				sessionId, _ := SessionIDFromContext(ctx)
+				result, _ := json.Marshal(map[string]interface{}{"method": request.Method, "id": request.Id, "session": sessionId})
+				err := session.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+					Jsonrpc: "2.0",
+					Id:      request.Id,
+					Result:  result,
+				}))
+				assert.NoError(t, err)
+			})
+			assert.NoError(t, session.Start(context.Background()))
+			accepted <- session
+		}
+	}()
+	server := httptest.NewServer(tr)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { tr.Close() })
+	return server, accepted
+}
+
+func decodeResult(t *testing.T, resp *http.Response) map[string]interface{} {
+	defer resp.Body.Close()
+	var response transport.BaseJSONRPCResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(response.Result, &result))
+	return result
+}
+
+func TestStreamableHTTPSessions(t *testing.T) {
+	t.Run("every session gets its own transport", func(t *testing.T) {
+		tr := NewStreamableHTTPTransport("/mcp").WithJSONResponse(true)
+		server, accepted := newSessionStreamableServer(t, tr)
+		// Make sure the transport is in listener mode before the first session
+		require.Eventually(t, func() bool {
+			tr.stateMu.Lock()
+			defer tr.stateMu.Unlock()
+			return tr.listening
+		}, time.Second, time.Millisecond)
+
+		var sessionIds []string
+		for i := 0; i < 2; i++ {
+			resp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+			sessionId := resp.Header.Get(McpSessionIdHeader)
+			result := decodeResult(t, resp)
+			sessionIds = append(sessionIds, sessionId)
+
+			// Ids are not rewritten, each session has its own id space
+			assert.Equal(t, float64(1), result["id"])
+			assert.Equal(t, sessionId, result["session"])
+		}
+		assert.NotEqual(t, sessionIds[0], sessionIds[1])
+		first, second := <-accepted, <-accepted
+		assert.NotSame(t, first, second)
+
+		result := decodeResult(t, postMessage(t, server.URL, sessionIds[1], `{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		assert.Equal(t, sessionIds[1], result["session"])
+
+		// Closing a session transport terminates the session
+		closed := make(chan struct{})
+		first.SetCloseHandler(func() { close(closed) })
+		require.NoError(t, first.Close())
+		<-closed
+		resp := postMessage(t, server.URL, sessionIds[0], `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		// Closing the listener stops new sessions but keeps the others
+		require.NoError(t, tr.Close())
+		resp = postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		result = decodeResult(t, postMessage(t, server.URL, sessionIds[1], `{"jsonrpc":"2.0","id":3,"method":"ping"}`))
+		assert.Equal(t, "ping", result["method"])
+	})
+
+	t.Run("failed initialize does not create a session", func(t *testing.T) {
+		tr := NewStreamableHTTPTransport("/mcp").WithJSONResponse(true)
+		server, _ := newSessionStreamableServer(t, tr)
+		require.Eventually(t, func() bool {
+			tr.stateMu.Lock()
+			defer tr.stateMu.Unlock()
+			return tr.listening
+		}, time.Second, time.Millisecond)
+
+		resp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":"fail"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = postMessage(t, server.URL, resp.Header.Get(McpSessionIdHeader), `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("session limit", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp").WithJSONResponse(true).WithMaxSessions(1))
+
+		resp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		resp.Body.Close()
+		sessionId := resp.Header.Get(McpSessionIdHeader)
+
+		resp = postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		req, _ := http.NewRequest(http.MethodDelete, server.URL, nil)
+		req.Header.Set(McpSessionIdHeader, sessionId)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		resp = postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("idle sessions expire", func(t *testing.T) {
+		server := newEchoStreamableServer(t, NewStreamableHTTPTransport("/mcp").WithJSONResponse(true).WithSessionTimeout(100*time.Millisecond))
+
+		resp := postMessage(t, server.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+		resp.Body.Close()
+		sessionId := resp.Header.Get(McpSessionIdHeader)
+
+		// Requests keep the session alive
+		for i := 0; i < 3; i++ {
+			time.Sleep(50 * time.Millisecond)
+			resp = postMessage(t, server.URL, sessionId, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		time.Sleep(300 * time.Millisecond)
+		resp = postMessage(t, server.URL, sessionId, `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestStreamableHTTPClientTransport(t *testing.T) {
+	serverTransport := NewStreamableHTTPTransport("/mcp")
+	server := newEchoStreamableServer(t, serverTransport)
+
+	client := NewStreamableHTTPClientTransport(server.URL)
+	received := make(chan *transport.BaseJsonRpcMessage, 10)
+	client.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		received <- message
+	})
+	require.NoError(t, client.Start(context.Background()))
+
+	next := func() *transport.BaseJsonRpcMessage {
+		select {
+		case message := <-received:
+			return message
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for message")
+			return nil
+		}
+	}
+
+	err := client.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      transport.NewRequestId(1),
+		Method:  "initialize",
+		Params:  json.RawMessage(`{}`),
+	}))
+	require.NoError(t, err)
+	message := next()
+	assert.Equal(t, transport.NewRequestId(1), message.JsonRpcResponse.Id)
+	require.NotEmpty(t, client.SessionID())
+
+	// Wait for the GET stream to be opened, then broadcast a notification to it
+	require.Eventually(t, func() bool {
+		serverTransport.stateMu.Lock()
+		defer serverTransport.stateMu.Unlock()
+		session := serverTransport.sessions[client.SessionID()]
+		return session != nil && session.standalone != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	err = serverTransport.Send(context.Background(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  "notifications/tools/list_changed",
+	}))
+	require.NoError(t, err)
+	message = next()
+	assert.Equal(t, "notifications/tools/list_changed", message.JsonRpcNotification.Method)
+
+	err = client.Send(context.Background(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  "notifications/initialized",
+	}))
+	require.NoError(t, err)
+
+	sessionId := client.SessionID()
+	closed := false
+	client.SetCloseHandler(func() {
+		closed = true
+	})
+	require.NoError(t, client.Close())
+	assert.True(t, closed)
+
+	// Close terminated the session on the server
+	resp := postMessage(t, server.URL, sessionId, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Messages can no longer be sent once closed
+	err = client.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      transport.NewRequestId(3),
+		Method:  "ping",
+	}))
+	assert.Error(t, err)
+}
+
+func TestUnmarshalMessages(t *testing.T) {
+	messages, isBatch, err := unmarshalMessages([]byte(` {"jsonrpc":"2.0","method":"a"}`))
+	require.NoError(t, err)
+	assert.False(t, isBatch)
+	require.Len(t, messages, 1)
+	assert.Equal(t, transport.BaseMessageTypeJSONRPCNotificationType, messages[0].Type)
+
+	messages, isBatch, err = unmarshalMessages(bytes.TrimSpace([]byte(`[{"jsonrpc":"2.0","id":1,"result":{}},{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"not found"}}]`)))
+	require.NoError(t, err)
+	assert.True(t, isBatch)
+	require.Len(t, messages, 2)
+	assert.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, messages[0].Type)
+	assert.Equal(t, transport.BaseMessageTypeJSONRPCErrorType, messages[1].Type)
+
+	_, _, err = unmarshalMessages([]byte(`[]`))
+	assert.Error(t, err)
+	_, _, err = unmarshalMessages([]byte(`{"foo":"bar"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+)
+
+// serverTLS holds the TLS settings of a server transport. TLS is enabled once any of them is set.
+type serverTLS struct {
+	config    *tls.Config
+	certFile  string
+	keyFile   string
+	clientCAs *x509.CertPool
+}
+
+// tlsConfig builds the configuration to serve with, nil if TLS is not enabled
+func (s *serverTLS) tlsConfig() (*tls.Config, error) {
+	if s.config == nil && s.certFile == "" && s.keyFile == "" && s.clientCAs == nil {
+		return nil, nil
+	}
+
+	var config *tls.Config
+	if s.config != nil {
+		config = s.config.Clone()
+	} else {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if s.certFile != "" || s.keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		config.Certificates = append(config.Certificates, certificate)
+	}
+	if s.clientCAs != nil {
+		config.ClientCAs = s.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if len(config.Certificates) == 0 && config.GetCertificate == nil && config.GetConfigForClient == nil {
+		return nil, fmt.Errorf("TLS is enabled but no server certificate is configured")
+	}
+	return config, nil
+}
+
+type clientCertificateContextKey struct{}
+
+// ClientCertificateFromContext returns the certificate a client presented over mutual TLS, once it has been verified
+// against the client CAs. The context passed to tool, prompt and resource handlers carries it.
+func ClientCertificateFromContext(ctx context.Context) (*x509.Certificate, bool) {
+	certificate, ok := ctx.Value(clientCertificateContextKey{}).(*x509.Certificate)
+	return certificate, ok
+}
+
+// withClientCertificate adds the verified client certificate of r to ctx, if there is one
+func withClientCertificate(ctx context.Context, r *http.Request) context.Context {
+	if certificate := verifiedClientCertificate(r); certificate != nil {
+		return context.WithValue(ctx, clientCertificateContextKey{}, certificate)
+	}
+	return ctx
+}
+
+func verifiedClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// withRoundTripper returns a copy of client that sends requests with roundTripper, so a client passed in by the user
+// is never modified
+func withRoundTripper(client *http.Client, roundTripper http.RoundTripper) *http.Client {
+	c := *client
+	c.Transport = roundTripper
+	return &c
+}
+
+// tlsRoundTripper returns a RoundTripper with the defaults of http.DefaultTransport that connects with config
+func tlsRoundTripper(config *tls.Config) http.RoundTripper {
+	roundTripper := http.DefaultTransport.(*http.Transport).Clone()
+	roundTripper.TLSClientConfig = config
+	return roundTripper
+}
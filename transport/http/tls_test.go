@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pool        *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return &testCA{certificate: certificate, key: key, pool: pool}
+}
+
+// issue returns a certificate for commonName signed by the CA, valid for localhost
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writePEM writes certificate and its key to files in a temporary directory
+func writePEM(t *testing.T, certificate tls.Certificate) (string, string) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]}), 0600))
+	keyDer, err := x509.MarshalECPrivateKey(certificate.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// answerWithClientName answers every request with the common name of the verified client certificate
+func answerWithClientName(t *testing.T, tr transport.Transport) {
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return
+		}
+		request := message.JsonRpcRequest
+		go func() {
+			name := ""
+			if certificate, ok := ClientCertificateFromContext(ctx); ok {
+				name = certificate.Subject.CommonName
+			}
+			result, _ := json.Marshal(map[string]string{"client": name})
+			err := tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      request.Id,
+				Result:  result,
+			}))
+			assert.NoError(t, err)
+		}()
+	})
+}
+
+func sendAndReceive(t *testing.T, tr transport.Transport, method string) (*transport.BaseJsonRpcMessage, error) {
+	received := make(chan *transport.BaseJsonRpcMessage, 10)
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		received <- message
+	})
+	err := tr.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      transport.NewRequestId(1),
+		Method:  method,
+		Params:  json.RawMessage(`{}`),
+	}))
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case message := <-received:
+		return message, nil
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for response")
+		return nil, nil
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCertificate := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	clientCertificate := ca.issue(t, "client-a", x509.ExtKeyUsageClientAuth)
+
+	t.Run("streamable http", func(t *testing.T) {
+		addr := freeAddr(t)
+		server := NewStreamableHTTPTransport("/mcp").
+			WithAddr(addr).
+			WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{serverCertificate}}).
+			WithClientCAs(ca.pool)
+		answerWithClientName(t, server)
+		require.NoError(t, server.Start(context.Background()))
+		defer server.Close()
+
+		client := NewStreamableHTTPClientTransport("https://" + addr + "/mcp").
+			WithTLSConfig(&tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{clientCertificate}})
+		defer client.Close()
+		message, err := sendAndReceive(t, client, "initialize")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"client":"client-a"}`, string(message.JsonRpcResponse.Result))
+
+		// Clients without a certificate are rejected during the handshake
+		anonymous := NewStreamableHTTPClientTransport("https://" + addr + "/mcp").
+			WithTLSConfig(&tls.Config{RootCAs: ca.pool})
+		_, err = sendAndReceive(t, anonymous, "initialize")
+		assert.Error(t, err)
+	})
+
+	t.Run("http with certificate files", func(t *testing.T) {
+		addr := freeAddr(t)
+		certFile, keyFile := writePEM(t, serverCertificate)
+		server := NewHTTPTransport("/mcp").
+			WithAddr(addr).
+			WithTLSCertFile(certFile, keyFile).
+			WithClientCAs(ca.pool)
+		answerWithClientName(t, server)
+		require.NoError(t, server.Start(context.Background()))
+		defer server.Close()
+
+		httpClient := &http.Client{Transport: tlsRoundTripper(&tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{clientCertificate}})}
+		client := NewHTTPClientTransport("/mcp").WithBaseURL("https://" + addr).WithHTTPClient(httpClient)
+		message, err := sendAndReceive(t, client, "ping")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"client":"client-a"}`, string(message.JsonRpcResponse.Result))
+	})
+
+	t.Run("missing server certificate", func(t *testing.T) {
+		server := NewStreamableHTTPTransport("/mcp").WithAddr(freeAddr(t)).WithClientCAs(ca.pool)
+		assert.ErrorContains(t, server.Start(context.Background()), "no server certificate")
+	})
+}
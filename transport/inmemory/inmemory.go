@@ -0,0 +1,260 @@
+// Package inmemory implements a pair of linked transports for connecting a client and a server in the same process.
+//
+//	clientTransport, serverTransport := inmemory.NewPair()
+//	server := mcp_golang.NewServer(serverTransport)
+//	client := mcp_golang.NewClient(clientTransport)
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+var errClosed = errors.New("in-memory transport closed")
+
+// InMemoryTransport is one end of a pair of linked transports. Messages sent on one end are delivered asynchronously
+// and in order to the message handler of the other end once it has been started.
+//
+// Messages are serialized to JSON on Send and deserialized on delivery, so both ends never share message values and
+// anything that would fail to serialize over a real transport fails here too.
+type InMemoryTransport struct {
+	peer *InMemoryTransport
+	pair *pair
+
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	mu             sync.RWMutex
+
+	// Fault injection for messages sent from this end
+	latency time.Duration
+	drop    func(message *transport.BaseJsonRpcMessage) bool
+
+	// Messages waiting to be delivered to this end
+	queueMu sync.Mutex
+	queue   []envelope
+	notify  chan struct{}
+	started bool
+}
+
+// pair is the state shared by both ends
+type pair struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+type envelope struct {
+	data      []byte
+	deliverAt time.Time
+	// Set for the marker queued by Shutdown, closed once every message before it has been delivered
+	flushed chan struct{}
+}
+
+// NewPair creates two linked transports. Messages sent on either one are delivered to the other and closing either one
+// closes both.
+func NewPair() (*InMemoryTransport, *InMemoryTransport) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &pair{ctx: ctx, cancel: cancel}
+
+	a := &InMemoryTransport{pair: p, notify: make(chan struct{}, 1)}
+	b := &InMemoryTransport{pair: p, notify: make(chan struct{}, 1)}
+	a.peer = b
+	b.peer = a
+	return a, b
+}
+
+// WithLatency delays the delivery of every message sent from this end by latency
+func (t *InMemoryTransport) WithLatency(latency time.Duration) *InMemoryTransport {
+	t.latency = latency
+	return t
+}
+
+// WithDropFunc makes this end silently discard every message it sends for which drop returns true
+func (t *InMemoryTransport) WithDropFunc(drop func(message *transport.BaseJsonRpcMessage) bool) *InMemoryTransport {
+	t.drop = drop
+	return t
+}
+
+// Start implements Transport.Start
+// Messages sent by the peer before Start are delivered once the transport has started.
+// The pair is closed when ctx is done.
+func (t *InMemoryTransport) Start(ctx context.Context) error {
+	t.queueMu.Lock()
+	if t.started {
+		t.queueMu.Unlock()
+		return fmt.Errorf("in-memory transport already started")
+	}
+	t.started = true
+	t.queueMu.Unlock()
+
+	if t.pair.ctx.Err() != nil {
+		return errClosed
+	}
+
+	go t.deliverLoop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.pair.ctx.Done():
+		}
+	}()
+	return nil
+}
+
+// Send implements Transport.Send
+func (t *InMemoryTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	if t.pair.ctx.Err() != nil {
+		return errClosed
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	if t.drop != nil && t.drop(message) {
+		return nil
+	}
+
+	t.peer.enqueue(envelope{data: data, deliverAt: time.Now().Add(t.latency)})
+	return nil
+}
+
+// Shutdown implements transport.Shutdowner
+// It waits for the messages sent from this end to be delivered to the peer, or for ctx to be done, then closes the pair.
+func (t *InMemoryTransport) Shutdown(ctx context.Context) error {
+	flushed := make(chan struct{})
+	t.peer.enqueue(envelope{flushed: flushed})
+
+	var err error
+	select {
+	case <-flushed:
+	case <-t.pair.ctx.Done():
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	t.Close()
+	return err
+}
+
+// Close implements Transport.Close
+// Both ends of the pair are closed and their close handlers called. Messages that have not been delivered yet are
+// discarded.
+func (t *InMemoryTransport) Close() error {
+	t.pair.closeOnce.Do(func() {
+		t.pair.cancel()
+		t.handleClose()
+		t.peer.handleClose()
+	})
+	return nil
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *InMemoryTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *InMemoryTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *InMemoryTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+func (t *InMemoryTransport) enqueue(e envelope) {
+	t.queueMu.Lock()
+	t.queue = append(t.queue, e)
+	t.queueMu.Unlock()
+
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliverLoop delivers queued messages one at a time, in the order they were sent
+func (t *InMemoryTransport) deliverLoop() {
+	for {
+		t.queueMu.Lock()
+		if len(t.queue) == 0 {
+			t.queueMu.Unlock()
+			select {
+			case <-t.notify:
+				continue
+			case <-t.pair.ctx.Done():
+				return
+			}
+		}
+		e := t.queue[0]
+		t.queue = t.queue[1:]
+		t.queueMu.Unlock()
+
+		if e.flushed != nil {
+			close(e.flushed)
+			continue
+		}
+
+		if wait := time.Until(e.deliverAt); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-t.pair.ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+		if t.pair.ctx.Err() != nil {
+			return
+		}
+
+		var message transport.BaseJsonRpcMessage
+		if err := json.Unmarshal(e.data, &message); err != nil {
+			t.handleError(fmt.Errorf("failed to unmarshal message: %w", err))
+			continue
+		}
+
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			handler(t.pair.ctx, &message)
+		}
+	}
+}
+
+func (t *InMemoryTransport) handleClose() {
+	t.mu.RLock()
+	handler := t.closeHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler()
+	}
+}
+
+func (t *InMemoryTransport) handleError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
@@ -0,0 +1,112 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newNotification(method string) *transport.BaseJsonRpcMessage {
+	return transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  method,
+	})
+}
+
+// receive starts t and returns a channel with the method of every message it receives
+func receive(t *testing.T, tr *InMemoryTransport) <-chan string {
+	received := make(chan string, 100)
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		received <- message.JsonRpcNotification.Method
+	})
+	require.NoError(t, tr.Start(context.Background()))
+	return received
+}
+
+func TestInMemoryTransport(t *testing.T) {
+	t.Run("messages are delivered in order", func(t *testing.T) {
+		a, b := NewPair()
+		require.NoError(t, a.Start(context.Background()))
+		defer a.Close()
+
+		// Messages sent before the peer has started are kept until it starts
+		for i := 0; i < 50; i++ {
+			require.NoError(t, a.Send(context.Background(), newNotification(fmt.Sprintf("m%d", i))))
+		}
+		received := receive(t, b)
+		for i := 50; i < 100; i++ {
+			require.NoError(t, a.Send(context.Background(), newNotification(fmt.Sprintf("m%d", i))))
+		}
+
+		for i := 0; i < 100; i++ {
+			select {
+			case method := <-received:
+				assert.Equal(t, fmt.Sprintf("m%d", i), method)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for message %d", i)
+			}
+		}
+	})
+
+	t.Run("close propagates to both ends", func(t *testing.T) {
+		a, b := NewPair()
+		aClosed := make(chan struct{})
+		bClosed := make(chan struct{})
+		a.SetCloseHandler(func() { close(aClosed) })
+		b.SetCloseHandler(func() { close(bClosed) })
+		require.NoError(t, a.Start(context.Background()))
+		require.NoError(t, b.Start(context.Background()))
+
+		require.NoError(t, b.Close())
+		<-aClosed
+		<-bClosed
+		require.NoError(t, a.Close())
+
+		assert.Error(t, a.Send(context.Background(), newNotification("late")))
+		assert.Error(t, b.Send(context.Background(), newNotification("late")))
+	})
+
+	t.Run("latency delays delivery", func(t *testing.T) {
+		a, b := NewPair()
+		a.WithLatency(50 * time.Millisecond)
+		require.NoError(t, a.Start(context.Background()))
+		defer a.Close()
+		received := receive(t, b)
+
+		sent := time.Now()
+		require.NoError(t, a.Send(context.Background(), newNotification("slow")))
+		assert.Equal(t, "slow", <-received)
+		assert.GreaterOrEqual(t, time.Since(sent), 50*time.Millisecond)
+	})
+
+	t.Run("shutdown delivers sent messages before closing", func(t *testing.T) {
+		a, b := NewPair()
+		a.WithLatency(20 * time.Millisecond)
+		require.NoError(t, a.Start(context.Background()))
+		received := receive(t, b)
+
+		require.NoError(t, a.Send(context.Background(), newNotification("last")))
+		require.NoError(t, a.Shutdown(context.Background()))
+		assert.Equal(t, "last", <-received)
+		assert.Error(t, a.Send(context.Background(), newNotification("late")))
+	})
+
+	t.Run("dropped messages are not delivered", func(t *testing.T) {
+		a, b := NewPair()
+		a.WithDropFunc(func(message *transport.BaseJsonRpcMessage) bool {
+			return message.JsonRpcNotification.Method == "dropped"
+		})
+		require.NoError(t, a.Start(context.Background()))
+		defer a.Close()
+		received := receive(t, b)
+
+		require.NoError(t, a.Send(context.Background(), newNotification("dropped")))
+		require.NoError(t, a.Send(context.Background(), newNotification("kept")))
+		assert.Equal(t, "kept", <-received)
+	})
+}
@@ -0,0 +1,177 @@
+// Package record implements a transport that records every message of a session to a JSONL file, and a transport that
+// replays a recorded session to a Server or Client and checks that it answers the same way. Together they give golden
+// tests for servers and clients without writing JSON by hand:
+//
+//	// Record a session once, e.g. while running a client against the server
+//	f, _ := os.Create("testdata/session.jsonl")
+//	server := mcp_golang.NewServer(record.NewRecordingTransport(serverTransport, f))
+//
+//	// Replay it in a test
+//	entries, _ := record.ReadFile("testdata/session.jsonl")
+//	replay := record.NewReplayTransport(entries)
+//	server := mcp_golang.NewServer(replay)
+//	// Register tools, prompts and resources, then start the server
+//	server.Serve()
+//	err := replay.Wait(ctx)
+package record
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// Direction tells whether a message was received or sent by the recorded side of the session
+type Direction string
+
+const (
+	Inbound  Direction = "inbound"
+	Outbound Direction = "outbound"
+)
+
+// Entry is one line of a recording
+type Entry struct {
+	Time      time.Time                     `json:"time"`
+	Direction Direction                     `json:"direction"`
+	Message   *transport.BaseJsonRpcMessage `json:"message"`
+}
+
+// ReadEntries reads a recording, one JSON entry per line
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to read entry on line %d: %w", line, err)
+		}
+		if entry.Message == nil {
+			return nil, fmt.Errorf("entry on line %d has no message", line)
+		}
+		if entry.Direction != Inbound && entry.Direction != Outbound {
+			return nil, fmt.Errorf("entry on line %d has unknown direction %q", line, entry.Direction)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return entries, nil
+}
+
+// ReadFile reads a recording from a file
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadEntries(f)
+}
+
+// RecordingTransport wraps a transport and writes every message it sends or receives to a writer, one JSON entry per
+// line. Messages are written in the order they pass through the transport; failures to write are reported to the error
+// handler.
+type RecordingTransport struct {
+	inner transport.Transport
+
+	w       io.Writer
+	writeMu sync.Mutex
+	now     func() time.Time
+
+	errorHandler func(error)
+	mu           sync.RWMutex
+}
+
+// NewRecordingTransport creates a transport that records the session carried by inner to w
+func NewRecordingTransport(inner transport.Transport, w io.Writer) *RecordingTransport {
+	t := &RecordingTransport{inner: inner, w: w, now: time.Now}
+	inner.SetErrorHandler(t.handleError)
+	return t
+}
+
+// WithClock sets the function used to timestamp entries, e.g. to get reproducible recordings
+func (t *RecordingTransport) WithClock(now func() time.Time) *RecordingTransport {
+	t.now = now
+	return t
+}
+
+// Start implements Transport.Start
+func (t *RecordingTransport) Start(ctx context.Context) error {
+	return t.inner.Start(ctx)
+}
+
+// Send implements Transport.Send
+// The message is recorded before it is sent, so that it always comes before the peer's reply in the recording.
+func (t *RecordingTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	t.record(Outbound, message)
+	return t.inner.Send(ctx, message)
+}
+
+// Shutdown implements transport.Shutdowner, falling back to Close if the wrapped transport does not
+func (t *RecordingTransport) Shutdown(ctx context.Context) error {
+	if shutdowner, ok := t.inner.(transport.Shutdowner); ok {
+		return shutdowner.Shutdown(ctx)
+	}
+	return t.inner.Close()
+}
+
+// Close implements Transport.Close
+func (t *RecordingTransport) Close() error {
+	return t.inner.Close()
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *RecordingTransport) SetCloseHandler(handler func()) {
+	t.inner.SetCloseHandler(handler)
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *RecordingTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *RecordingTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.inner.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		t.record(Inbound, message)
+		handler(ctx, message)
+	})
+}
+
+func (t *RecordingTransport) record(direction Direction, message *transport.BaseJsonRpcMessage) {
+	data, err := json.Marshal(Entry{Time: t.now(), Direction: direction, Message: message})
+	if err != nil {
+		t.handleError(fmt.Errorf("failed to record message: %w", err))
+		return
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.w.Write(append(data, '\n')); err != nil {
+		t.handleError(fmt.Errorf("failed to record message: %w", err))
+	}
+}
+
+func (t *RecordingTransport) handleError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
@@ -0,0 +1,124 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/metoro-io/mcp-golang/transport/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type echoArgs struct {
+	Message string `json:"message" jsonschema:"required,description=Message to echo back"`
+}
+
+func newEchoServer(t *testing.T, tr transport.Transport, prefix string) *mcp_golang.Server {
+	server := mcp_golang.NewServer(tr)
+	err := server.RegisterTool("echo", "Echo back the input message", func(args echoArgs) (*mcp_golang.ToolResponse, error) {
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(prefix + args.Message)), nil
+	})
+	require.NoError(t, err)
+	return server
+}
+
+// runSession initializes client, lists its tools and calls the echo tool
+func runSession(t *testing.T, client *mcp_golang.Client) {
+	ctx := context.Background()
+	_, err := client.Initialize(ctx)
+	require.NoError(t, err)
+	tools, err := client.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	response, err := client.CallTool(ctx, "echo", echoArgs{Message: "hello"})
+	require.NoError(t, err)
+	require.Len(t, response.Content, 1)
+	assert.Equal(t, "hello", response.Content[0].TextContent.Text)
+}
+
+// recordSession runs a session between a client and an echo server, recording both sides
+func recordSession(t *testing.T) (serverEntries, clientEntries []Entry) {
+	clientTransport, serverTransport := inmemory.NewPair()
+	var serverRecording, clientRecording bytes.Buffer
+	server := newEchoServer(t, NewRecordingTransport(serverTransport, &serverRecording), "")
+	require.NoError(t, server.Serve())
+
+	runSession(t, mcp_golang.NewClient(NewRecordingTransport(clientTransport, &clientRecording)))
+	require.NoError(t, server.Shutdown(context.Background()))
+
+	serverEntries, err := ReadEntries(&serverRecording)
+	require.NoError(t, err)
+	clientEntries, err = ReadEntries(&clientRecording)
+	require.NoError(t, err)
+	return serverEntries, clientEntries
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	serverEntries, clientEntries := recordSession(t)
+
+	t.Run("recording", func(t *testing.T) {
+		// initialize, tools/list and tools/call, each followed by its response
+		require.Len(t, serverEntries, 6)
+		assert.Equal(t, Inbound, serverEntries[0].Direction)
+		assert.Equal(t, "initialize", serverEntries[0].Message.JsonRpcRequest.Method)
+		assert.Equal(t, Outbound, serverEntries[1].Direction)
+		assert.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, serverEntries[1].Message.Type)
+		assert.False(t, serverEntries[0].Time.IsZero())
+
+		require.Len(t, clientEntries, 6)
+		assert.Equal(t, Outbound, clientEntries[0].Direction)
+	})
+
+	t.Run("replay to a server", func(t *testing.T) {
+		replay := NewReplayTransport(serverEntries)
+		require.NoError(t, newEchoServer(t, replay, "").Serve())
+		defer replay.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, replay.Wait(ctx))
+	})
+
+	t.Run("replay detects a different answer", func(t *testing.T) {
+		replay := NewReplayTransport(serverEntries)
+		require.NoError(t, newEchoServer(t, replay, "changed: ").Serve())
+		defer replay.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := replay.Wait(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "changed: hello")
+	})
+
+	t.Run("replay to a client with different request ids", func(t *testing.T) {
+		// Replace the ids of the client's requests, the replay maps them to the ids the client actually uses
+		var shifted []Entry
+		for _, entry := range clientEntries {
+			data, err := entry.Message.MarshalJSON()
+			require.NoError(t, err)
+			var message transport.BaseJsonRpcMessage
+			require.NoError(t, message.UnmarshalJSON(data))
+			switch message.Type {
+			case transport.BaseMessageTypeJSONRPCRequestType:
+				message.JsonRpcRequest.Id = transport.NewStringRequestId("recorded-" + message.JsonRpcRequest.Id.String())
+			case transport.BaseMessageTypeJSONRPCResponseType:
+				message.JsonRpcResponse.Id = transport.NewStringRequestId("recorded-" + message.JsonRpcResponse.Id.String())
+			}
+			entry.Message = &message
+			shifted = append(shifted, entry)
+		}
+
+		replay := NewReplayTransport(shifted)
+		defer replay.Close()
+		runSession(t, mcp_golang.NewClient(replay))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, replay.Wait(ctx))
+	})
+}
@@ -0,0 +1,336 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// ReplayTransport plays the peer of a recorded session. It delivers the recorded inbound messages to the Server or
+// Client under test and checks that the messages it sends match the recorded outbound ones.
+//
+// The recording is replayed in turns: a run of inbound messages is delivered, then the transport waits until every
+// outbound message recorded after them has been sent, in any order, before moving on. Messages are compared as JSON
+// values, ignoring timestamps and the ids of requests sent by the side under test, which are generated at runtime.
+// Responses to those requests are rewritten to carry the new ids before they are delivered.
+type ReplayTransport struct {
+	turns []turn
+
+	// The current turn and its outbound messages that have not been sent yet
+	turn     int
+	expected []*transport.BaseJsonRpcMessage
+	// Maps the ids of recorded outbound requests to the ids they were sent with
+	ids      map[transport.RequestId]transport.RequestId
+	sent     chan struct{}
+	finished bool
+	err      error
+	done     chan struct{}
+
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	mu             sync.Mutex
+
+	started   bool
+	closed    chan struct{}
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+type turn struct {
+	inbound  []*transport.BaseJsonRpcMessage
+	outbound []*transport.BaseJsonRpcMessage
+}
+
+// NewReplayTransport creates a transport that replays entries to the side that recorded them
+func NewReplayTransport(entries []Entry) *ReplayTransport {
+	var turns []turn
+	for i, entry := range entries {
+		// A new turn starts with every inbound message that follows an outbound one
+		if i == 0 || (entry.Direction == Inbound && entries[i-1].Direction == Outbound) {
+			turns = append(turns, turn{})
+		}
+		current := &turns[len(turns)-1]
+		if entry.Direction == Inbound {
+			current.inbound = append(current.inbound, entry.Message)
+		} else {
+			current.outbound = append(current.outbound, entry.Message)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ReplayTransport{
+		turns:  turns,
+		ids:    make(map[transport.RequestId]transport.RequestId),
+		sent:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start implements Transport.Start
+// The replay begins straight away; the transport is closed when ctx is done.
+func (t *ReplayTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	if t.started {
+		t.mu.Unlock()
+		return fmt.Errorf("replay transport already started")
+	}
+	t.started = true
+	// The outbound messages of a turn are expected before its inbound messages are delivered, as the side under test
+	// may send them straight away
+	if len(t.turns) > 0 {
+		t.expected = append([]*transport.BaseJsonRpcMessage(nil), t.turns[0].outbound...)
+	}
+	t.advance()
+	t.mu.Unlock()
+
+	go t.replay()
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.closed:
+		}
+	}()
+	return nil
+}
+
+// Send implements Transport.Send
+// A message that does not match any of the outbound messages expected next fails the replay.
+func (t *ReplayTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return t.err
+	}
+	for i, expected := range t.expected {
+		if !matches(expected, message) {
+			continue
+		}
+		if expected.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			t.ids[expected.JsonRpcRequest.Id] = message.JsonRpcRequest.Id
+		}
+		t.expected = append(t.expected[:i], t.expected[i+1:]...)
+		t.advance()
+		return nil
+	}
+
+	data, _ := json.Marshal(message)
+	t.fail(fmt.Errorf("unexpected message %s, expected %s", data, describe(t.expected)))
+	return t.err
+}
+
+// Wait blocks until the whole recording has been replayed and returns nil, or returns an error describing the first
+// difference from the recording. If ctx is done first it returns an error listing the messages still expected.
+func (t *ReplayTransport) Wait(ctx context.Context) error {
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return fmt.Errorf("replay did not finish, still expecting %s: %w", describe(t.expected), ctx.Err())
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Close implements Transport.Close
+// Closing the transport before the end of the recording fails the replay.
+func (t *ReplayTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		if !t.finished {
+			t.fail(errors.New("replay transport closed before the end of the recording"))
+		}
+		handler := t.closeHandler
+		t.mu.Unlock()
+
+		close(t.closed)
+		t.cancel()
+		if handler != nil {
+			handler()
+		}
+	})
+	return nil
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *ReplayTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *ReplayTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *ReplayTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+func (t *ReplayTransport) replay() {
+	for i, turn := range t.turns {
+		t.mu.Lock()
+		handler := t.messageHandler
+		t.mu.Unlock()
+
+		for _, message := range turn.inbound {
+			message, err := t.rewriteId(message)
+			if err != nil {
+				t.mu.Lock()
+				t.fail(err)
+				t.mu.Unlock()
+				return
+			}
+			if handler != nil {
+				handler(t.ctx, message)
+			}
+		}
+
+		// Wait for the side under test to send the outbound messages of the turn
+		for {
+			t.mu.Lock()
+			if t.turn == i {
+				t.advance()
+			}
+			next := t.turn > i
+			failed := t.err != nil
+			t.mu.Unlock()
+			if failed {
+				return
+			}
+			if next {
+				break
+			}
+			select {
+			case <-t.sent:
+			case <-t.closed:
+				return
+			}
+		}
+	}
+}
+
+// advance moves on to the next turn once every outbound message of the current one has been sent, called with the
+// lock held
+func (t *ReplayTransport) advance() {
+	if len(t.expected) > 0 || t.err != nil || t.finished {
+		return
+	}
+	t.turn++
+	if t.turn >= len(t.turns) {
+		t.finished = true
+		close(t.done)
+	} else {
+		t.expected = append([]*transport.BaseJsonRpcMessage(nil), t.turns[t.turn].outbound...)
+	}
+	select {
+	case t.sent <- struct{}{}:
+	default:
+	}
+}
+
+// rewriteId returns a copy of an inbound response or error that answers a request sent by the side under test, with
+// the id the request was actually sent with
+func (t *ReplayTransport) rewriteId(message *transport.BaseJsonRpcMessage) (*transport.BaseJsonRpcMessage, error) {
+	// Copy the message so that the recording can be replayed more than once
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay message: %w", err)
+	}
+	var replayed transport.BaseJsonRpcMessage
+	if err := json.Unmarshal(data, &replayed); err != nil {
+		return nil, fmt.Errorf("failed to replay message: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch replayed.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType:
+		if id, ok := t.ids[replayed.JsonRpcResponse.Id]; ok {
+			replayed.JsonRpcResponse.Id = id
+		}
+	case transport.BaseMessageTypeJSONRPCErrorType:
+		if id, ok := t.ids[replayed.JsonRpcError.Id]; ok {
+			replayed.JsonRpcError.Id = id
+		}
+	}
+	return &replayed, nil
+}
+
+// fail ends the replay with err, called with the lock held
+func (t *ReplayTransport) fail(err error) {
+	if t.err != nil || t.finished {
+		return
+	}
+	t.err = err
+	close(t.done)
+
+	if handler := t.errorHandler; handler != nil {
+		go handler(err)
+	}
+}
+
+// matches reports whether a sent message is the same as a recorded one, ignoring request ids
+func matches(expected, actual *transport.BaseJsonRpcMessage) bool {
+	if expected.Type != actual.Type {
+		return false
+	}
+	e, err := normalize(expected)
+	if err != nil {
+		return false
+	}
+	a, err := normalize(actual)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(e, a)
+}
+
+// normalize returns a message as a generic JSON value without the id of requests
+func normalize(message *transport.BaseJsonRpcMessage) (interface{}, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+		delete(value, "id")
+	}
+	return value, nil
+}
+
+func describe(messages []*transport.BaseJsonRpcMessage) string {
+	if len(messages) == 0 {
+		return "no more messages"
+	}
+	descriptions := make([]string, 0, len(messages))
+	for _, message := range messages {
+		data, _ := json.Marshal(message)
+		descriptions = append(descriptions, string(data))
+	}
+	return "one of " + strings.Join(descriptions, ", ")
+}
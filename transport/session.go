@@ -0,0 +1,24 @@
+package transport
+
+import "context"
+
+type sessionContextKey struct{}
+
+// WithSession returns a copy of ctx that carries the id of a session. Transports that serve several clients at once
+// pass the session of every message they receive in the context of the message handler, and deliver the messages sent
+// with such a context to that session only.
+func WithSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the id of the session carried by ctx, see WithSession
+func SessionFromContext(ctx context.Context) (string, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(string)
+	return session, ok
+}
+
+// SessionCloseNotifier is implemented by transports that serve several sessions at once, so that state kept for a
+// session can be released when it ends. The handler is called with the id of every session that ends.
+type SessionCloseNotifier interface {
+	SetSessionCloseHandler(handler func(session string))
+}
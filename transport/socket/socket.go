@@ -0,0 +1,243 @@
+// Package socket implements transports over stream sockets, such as Unix domain sockets and TCP connections.
+// Messages are framed as newline-delimited JSON, the same as the stdio transports.
+//
+// A server accepts connections with a Listener and serves each one as its own session:
+//
+//	listener, err := socket.Listen("unix", "/tmp/mcp.sock")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	server := mcp_golang.NewServer(nil)
+//	// Register tools, prompts and resources
+//	log.Fatal(server.ServeListener(listener))
+//
+// Clients connect with NewSocketClientTransport:
+//
+//	client := mcp_golang.NewClient(socket.NewSocketClientTransport("unix", "/tmp/mcp.sock"))
+package socket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// DefaultMaxMessageSize is the largest message accepted from the peer
+const DefaultMaxMessageSize = 4 * 1024 * 1024 // 4MB
+
+// SocketTransport implements a transport over a single stream connection
+type SocketTransport struct {
+	conn net.Conn
+
+	// Client side only, used to dial the connection in Start
+	network string
+	address string
+	dialer  *net.Dialer
+
+	maxMessageSize int
+
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	mu             sync.RWMutex
+	writeMu        sync.Mutex
+
+	started   bool
+	closing   bool
+	done      chan struct{}
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newSocketTransport(conn net.Conn) *SocketTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SocketTransport{
+		conn:           conn,
+		maxMessageSize: DefaultMaxMessageSize,
+		done:           make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// WithMaxMessageSize sets the largest message accepted from the peer. A larger message closes the connection.
+func (t *SocketTransport) WithMaxMessageSize(size int) *SocketTransport {
+	t.maxMessageSize = size
+	return t
+}
+
+// Start implements Transport.Start
+// On the client side it dials the server. The transport is closed when ctx is done.
+func (t *SocketTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	if t.started {
+		t.mu.Unlock()
+		return fmt.Errorf("socket transport already started")
+	}
+	t.started = true
+	t.mu.Unlock()
+
+	if t.conn == nil {
+		conn, err := t.dial(ctx)
+		t.mu.Lock()
+		if err != nil {
+			t.started = false
+			t.mu.Unlock()
+			return err
+		}
+		if t.closing {
+			t.mu.Unlock()
+			conn.Close()
+			return errors.New("socket transport closed")
+		}
+		t.conn = conn
+		t.mu.Unlock()
+	}
+
+	go t.readLoop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.done:
+		}
+	}()
+	return nil
+}
+
+// Send implements Transport.Send
+func (t *SocketTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	data = append(data, '\n')
+
+	t.mu.RLock()
+	conn := t.conn
+	closing := t.closing
+	t.mu.RUnlock()
+	if conn == nil {
+		return errors.New("socket transport not started")
+	}
+	if closing {
+		return errors.New("socket transport closed")
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := conn.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// Close implements Transport.Close
+func (t *SocketTransport) Close() error {
+	t.finish()
+	return nil
+}
+
+// RemoteAddr returns the address of the peer, or nil if the transport is not connected
+func (t *SocketTransport) RemoteAddr() net.Addr {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.conn == nil {
+		return nil
+	}
+	return t.conn.RemoteAddr()
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *SocketTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *SocketTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *SocketTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+func (t *SocketTransport) readLoop() {
+	defer t.finish()
+
+	scanner := bufio.NewScanner(t.conn)
+	// The initial buffer must not be larger than the limit, Scanner allows tokens up to the larger of the two
+	scanner.Buffer(make([]byte, 0, min(64*1024, t.maxMessageSize)), t.maxMessageSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var message transport.BaseJsonRpcMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			t.handleError(fmt.Errorf("failed to unmarshal message: %w", err))
+			continue
+		}
+
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			handler(t.ctx, &message)
+		}
+	}
+
+	t.mu.RLock()
+	closing := t.closing
+	t.mu.RUnlock()
+	if err := scanner.Err(); err != nil && !closing {
+		t.handleError(fmt.Errorf("failed to read message: %w", err))
+	}
+}
+
+// finish closes the connection and calls the close handler, once
+func (t *SocketTransport) finish() {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		t.closing = true
+		conn := t.conn
+		t.mu.Unlock()
+		if conn != nil {
+			conn.Close()
+		}
+		t.cancel()
+		close(t.done)
+
+		t.mu.RLock()
+		handler := t.closeHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			handler()
+		}
+	})
+}
+
+func (t *SocketTransport) handleError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
@@ -0,0 +1,30 @@
+package socket
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// NewSocketClientTransport creates a new socket transport that connects to the server at address on the named
+// network, e.g. ("unix", "/tmp/mcp.sock") or ("tcp", "localhost:9000"). The connection is dialed when the transport
+// is started.
+func NewSocketClientTransport(network, address string) *SocketTransport {
+	t := newSocketTransport(nil)
+	t.network = network
+	t.address = address
+	t.dialer = &net.Dialer{}
+	return t
+}
+
+func (t *SocketTransport) dial(ctx context.Context) (net.Conn, error) {
+	if t.dialer == nil {
+		return nil, fmt.Errorf("socket transport has no connection")
+	}
+
+	conn, err := t.dialer.DialContext(ctx, t.network, t.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return conn, nil
+}
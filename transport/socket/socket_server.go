@@ -0,0 +1,61 @@
+package socket
+
+import (
+	"errors"
+	"net"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// Listener implements transport.Listener on top of a net.Listener. Every accepted connection is returned as a
+// SocketTransport.
+type Listener struct {
+	listener       net.Listener
+	maxMessageSize int
+}
+
+// Listen announces on the local network address and returns a Listener for it.
+// network must be a stream network such as "unix" or "tcp", see net.Listen.
+func Listen(network, address string) (*Listener, error) {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewListener(l), nil
+}
+
+// NewListener creates a Listener that accepts connections from l
+func NewListener(l net.Listener) *Listener {
+	return &Listener{
+		listener:       l,
+		maxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// WithMaxMessageSize sets the largest message accepted from each client
+func (l *Listener) WithMaxMessageSize(size int) *Listener {
+	l.maxMessageSize = size
+	return l
+}
+
+// Accept implements transport.Listener.Accept
+func (l *Listener) Accept() (transport.Transport, error) {
+	conn, err := l.listener.Accept()
+	if err != nil {
+		if errors.Is(err, net.ErrClosed) {
+			return nil, transport.ErrListenerClosed
+		}
+		return nil, err
+	}
+	return newSocketTransport(conn).WithMaxMessageSize(l.maxMessageSize), nil
+}
+
+// Close implements transport.Listener.Close
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+// Addr returns the listener's network address
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
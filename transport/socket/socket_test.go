@@ -0,0 +1,125 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveEcho accepts connections from l and answers every request with its method name
+func serveEcho(t *testing.T, l *Listener) {
+	go func() {
+		for {
+			tr, err := l.Accept()
+			if err != nil {
+				assert.ErrorIs(t, err, transport.ErrListenerClosed)
+				return
+			}
+			tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+				if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+					return
+				}
+				result, _ := json.Marshal(map[string]string{"method": message.JsonRpcRequest.Method})
+				err := tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+					Jsonrpc: "2.0",
+					Id:      message.JsonRpcRequest.Id,
+					Result:  result,
+				}))
+				assert.NoError(t, err)
+			})
+			assert.NoError(t, tr.Start(context.Background()))
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+}
+
+func newRequest(method string, id int64) *transport.BaseJsonRpcMessage {
+	return transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Id:      transport.NewRequestId(id),
+	})
+}
+
+func TestSocketTransport(t *testing.T) {
+	for _, network := range []string{"unix", "tcp"} {
+		t.Run(network+" round trip", func(t *testing.T) {
+			address := "127.0.0.1:0"
+			if network == "unix" {
+				address = filepath.Join(t.TempDir(), "mcp.sock")
+			}
+			l, err := Listen(network, address)
+			require.NoError(t, err)
+			serveEcho(t, l)
+
+			client := NewSocketClientTransport(network, l.Addr().String())
+			received := make(chan *transport.BaseJsonRpcMessage, 1)
+			client.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+				received <- message
+			})
+			require.NoError(t, client.Start(context.Background()))
+			defer client.Close()
+
+			require.NoError(t, client.Send(context.Background(), newRequest("hello", 1)))
+			select {
+			case message := <-received:
+				require.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, message.Type)
+				assert.JSONEq(t, `{"method":"hello"}`, string(message.JsonRpcResponse.Result))
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the response")
+			}
+		})
+	}
+
+	t.Run("peer closing the connection calls the close handler", func(t *testing.T) {
+		server, conn := net.Pipe()
+		tr := newSocketTransport(conn)
+		closed := make(chan struct{})
+		tr.SetCloseHandler(func() { close(closed) })
+		require.NoError(t, tr.Start(context.Background()))
+
+		server.Close()
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("close handler was not called")
+		}
+		assert.Error(t, tr.Send(context.Background(), newRequest("late", 1)))
+	})
+
+	t.Run("messages over the size limit close the connection", func(t *testing.T) {
+		server, conn := net.Pipe()
+		defer server.Close()
+		tr := newSocketTransport(conn).WithMaxMessageSize(64)
+		errs := make(chan error, 1)
+		tr.SetErrorHandler(func(err error) { errs <- err })
+		closed := make(chan struct{})
+		tr.SetCloseHandler(func() { close(closed) })
+		require.NoError(t, tr.Start(context.Background()))
+
+		go server.Write([]byte(`{"jsonrpc":"2.0","method":"` + strings.Repeat("x", 100) + `"}` + "\n"))
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("close handler was not called")
+		}
+		assert.Contains(t, (<-errs).Error(), "too long")
+	})
+
+	t.Run("accept fails once the listener is closed", func(t *testing.T) {
+		l, err := Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+
+		_, err = l.Accept()
+		assert.ErrorIs(t, err, transport.ErrListenerClosed)
+	})
+}
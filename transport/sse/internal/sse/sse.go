@@ -1,244 +1,209 @@
-// /*
-// Package mcp implements Server-Sent Events (SSE) transport for JSON-RPC communication.
-//
-// SSE Transport Overview:
-// This implementation provides a bidirectional communication channel between client and server:
-// - Server to Client: Uses Server-Sent Events (SSE) for real-time message streaming
-// - Client to Server: Uses HTTP POST requests for sending messages
-//
-// Key Features:
-// 1. Bidirectional Communication:
-//   - SSE for server-to-client streaming (one-way, real-time updates)
-//   - HTTP POST endpoints for client-to-server messages
-//
-// 2. Session Management:
-//   - Unique session IDs for each connection
-//   - Proper connection lifecycle management
-//   - Automatic cleanup on connection close
-//
-// 3. Message Handling:
-//   - JSON-RPC message format support
-//   - Automatic message type detection (request vs response)
-//   - Built-in error handling and reporting
-//   - Message size limits for security
-//
-// 4. Security Features:
-//   - Content-type validation
-//   - Message size limits (4MB default)
-//   - Error handling for malformed messages
-//
-// Usage Example:
-//
-//	// Create a new SSE transport
-//	transport, err := NewSSETransport("/messages", responseWriter)
-//	if err != nil {
-//	    log.Fatal(err)
-//	}
-//
-//	// Set up message handling
-//	transport.SetMessageHandler(func(msg JSONRPCMessage) {
-//	    // Handle incoming messages
-//	})
-//
-//	// Start the SSE connection
-//	if err := transport.Start(context.Background()); err != nil {
-//	    log.Fatal(err)
-//	}
-//
-//	// Send a message
-//	msg := JSONRPCResponse{
-//	    Jsonrpc: "2.0",
-//	    Result:  Result{...},
-//	    Id:      1,
-//	}
-//	if err := transport.Send(msg); err != nil {
-//	    log.Fatal(err)
-//	}
-//
-// */
+/*
+Package sse implements a single server-side connection of the HTTP+SSE transport for JSON-RPC communication.
+
+SSE Transport Overview:
+This implementation provides one half of a bidirectional communication channel between client and server:
+- Server to Client: Uses Server-Sent Events (SSE) for real-time message streaming
+- Client to Server: Uses HTTP POST requests for sending messages, handled by the owner of the connection
+
+Key Features:
+1. Session Management:
+  - Unique session IDs for each connection
+  - The endpoint event tells the client where to POST its messages for this session
+  - Proper connection lifecycle management
+
+2. Message Handling:
+  - JSON-RPC messages are sent as "message" events
+  - Keepalive comments keep idle connections open through proxies
+  - Message size limits for security
+
+Usage Example:
+
+	// Create a new SSE connection for an incoming GET request
+	conn, err := NewSSETransport("/messages", responseWriter)
+	if err != nil {
+	    log.Fatal(err)
+	}
+
+	// Send the SSE headers and the endpoint event
+	if err := conn.Start(r.Context()); err != nil {
+	    log.Fatal(err)
+	}
+
+	// Send a message
+	if err := conn.Send(message); err != nil {
+	    log.Fatal(err)
+	}
+
+	// Block until the client disconnects or the connection is closed
+	<-conn.Done()
+*/
 package sse
 
-//
-//import (
-//	"context"
-//	"encoding/json"
-//	"fmt"
-//	"github.com/metoro-io/mcp-golang/transport"
-//	"net/http"
-//	"sync"
-//
-//	"github.com/google/uuid"
-//)
-//
-//const (
-//	maxMessageSize = 4 * 1024 * 1024 // 4MB
-//)
-//
-//// SSETransport implements a Server-Sent Events transport for JSON-RPC messages
-//type SSETransport struct {
-//	endpoint    string
-//	sessionID   string
-//	writer      http.ResponseWriter
-//	flusher     http.Flusher
-//	mu          sync.Mutex
-//	isConnected bool
-//
-//	// Callbacks
-//	closeHandler   func()
-//	errorHandler   func(error)
-//	messageHandler func(message *transport.BaseJsonRpcMessage)
-//}
-//
-//// NewSSETransport creates a new SSE transport with the given endpoint and response writer
-//func NewSSETransport(endpoint string, w http.ResponseWriter) (*SSETransport, error) {
-//	flusher, ok := w.(http.Flusher)
-//	if !ok {
-//		return nil, fmt.Errorf("streaming not supported")
-//	}
-//
-//	return &SSETransport{
-//		endpoint:  endpoint,
-//		sessionID: uuid.New().String(),
-//		writer:    w,
-//		flusher:   flusher,
-//	}, nil
-//}
-//
-//// Start initializes the SSE connection
-//func (t *SSETransport) Start(ctx context.Context) error {
-//	t.mu.Lock()
-//	defer t.mu.Unlock()
-//
-//	if t.isConnected {
-//		return fmt.Errorf("SSE transport already started")
-//	}
-//
-//	// Set SSE headers
-//	h := t.writer.Header()
-//	h.Set("Content-Type", "text/event-stream")
-//	h.Set("Cache-Control", "no-cache")
-//	h.Set("Connection", "keep-alive")
-//	h.Set("Access-Control-Allow-Origin", "*")
-//
-//	// Send the endpoint event
-//	endpointURL := fmt.Sprintf("%s?sessionId=%s", t.endpoint, t.sessionID)
-//	if err := t.writeEvent("endpoint", endpointURL); err != nil {
-//		return err
-//	}
-//
-//	t.isConnected = true
-//
-//	// Handle context cancellation
-//	go func() {
-//		<-ctx.Done()
-//		t.Close()
-//	}()
-//
-//	return nil
-//}
-//
-//// HandleMessage processes an incoming message
-//func (t *SSETransport) HandleMessage(msg []byte) error {
-//	var rpcMsg map[string]interface{}
-//	if err := json.Unmarshal(msg, &rpcMsg); err != nil {
-//		if t.errorHandler != nil {
-//			t.errorHandler(err)
-//		}
-//		return err
-//	}
-//
-//	// Parse as a JSONRPCMessage
-//	var jsonrpcMsg JSONRPCMessage
-//	if _, ok := rpcMsg["method"]; ok {
-//		var req JSONRPCRequest
-//		if err := json.Unmarshal(msg, &req); err != nil {
-//			if t.errorHandler != nil {
-//				t.errorHandler(err)
-//			}
-//			return err
-//		}
-//		jsonrpcMsg = &req
-//	} else {
-//		var resp JSONRPCResponse
-//		if err := json.Unmarshal(msg, &resp); err != nil {
-//			if t.errorHandler != nil {
-//				t.errorHandler(err)
-//			}
-//			return err
-//		}
-//		jsonrpcMsg = &resp
-//	}
-//
-//	if t.messageHandler != nil {
-//		t.messageHandler(jsonrpcMsg)
-//	}
-//	return nil
-//}
-//
-//// Send sends a message over the SSE connection
-//func (t *SSETransport) Send(msg JSONRPCMessage) error {
-//	t.mu.Lock()
-//	defer t.mu.Unlock()
-//
-//	if !t.isConnected {
-//		return fmt.Errorf("not connected")
-//	}
-//
-//	data, err := json.Marshal(msg)
-//	if err != nil {
-//		return err
-//	}
-//
-//	return t.writeEvent("message", string(data))
-//}
-//
-//// Close closes the SSE connection
-//func (t *SSETransport) Close() error {
-//	t.mu.Lock()
-//	defer t.mu.Unlock()
-//
-//	if !t.isConnected {
-//		return nil
-//	}
-//
-//	t.isConnected = false
-//	if t.closeHandler != nil {
-//		t.closeHandler()
-//	}
-//	return nil
-//}
-//
-//// SetCloseHandler sets the callback for when the connection is closed
-//func (t *SSETransport) SetCloseHandler(handler func()) {
-//	t.mu.Lock()
-//	defer t.mu.Unlock()
-//	t.closeHandler = handler
-//}
-//
-//// SetErrorHandler sets the callback for when an error occurs
-//func (t *SSETransport) SetErrorHandler(handler func(error)) {
-//	t.mu.Lock()
-//	defer t.mu.Unlock()
-//	t.errorHandler = handler
-//}
-//
-//// SetMessageHandler sets the callback for when a message is received
-//func (t *SSETransport) SetMessageHandler(handler func(JSONRPCMessage)) {
-//	t.mu.Lock()
-//	defer t.mu.Unlock()
-//	t.messageHandler = handler
-//}
-//
-//// SessionID returns the unique session identifier for this transport
-//func (t *SSETransport) SessionID() string {
-//	return t.sessionID
-//}
-//
-//// writeEvent writes an SSE event with the given event type and data
-//func (t *SSETransport) writeEvent(event, data string) error {
-//	if _, err := fmt.Fprintf(t.writer, "event: %s\ndata: %s\n\n", event, data); err != nil {
-//		return err
-//	}
-//	t.flusher.Flush()
-//	return nil
-//}
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	eventstream "github.com/metoro-io/mcp-golang/internal/sse"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+const (
+	MaxMessageSize = 4 * 1024 * 1024 // 4MB
+)
+
+// SSETransport is a single Server-Sent Events connection to a client
+type SSETransport struct {
+	endpoint    string
+	sessionID   string
+	writer      http.ResponseWriter
+	flusher     http.Flusher
+	mu          sync.Mutex
+	isConnected bool
+	done        chan struct{}
+}
+
+// NewSSETransport creates a new SSE connection with the given message endpoint and response writer
+func NewSSETransport(endpoint string, w http.ResponseWriter) (*SSETransport, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming not supported")
+	}
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSETransport{
+		endpoint:  endpoint,
+		sessionID: sessionID,
+		writer:    w,
+		flusher:   flusher,
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Start writes the SSE headers and the endpoint event. The connection is closed when ctx is done.
+func (t *SSETransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isConnected {
+		return fmt.Errorf("SSE transport already started")
+	}
+
+	// Set SSE headers
+	h := t.writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	t.writer.WriteHeader(http.StatusOK)
+
+	// Send the endpoint event
+	if err := t.writeEvent("endpoint", t.EndpointURL()); err != nil {
+		return err
+	}
+
+	t.isConnected = true
+
+	// Handle context cancellation
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.done:
+		}
+	}()
+
+	return nil
+}
+
+// EndpointURL returns the URL the client must POST its messages to
+func (t *SSETransport) EndpointURL() string {
+	separator := "?"
+	if u, err := url.Parse(t.endpoint); err == nil && u.RawQuery != "" {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%ssessionId=%s", t.endpoint, separator, url.QueryEscape(t.sessionID))
+}
+
+// Send sends a message over the SSE connection
+func (t *SSETransport) Send(message *transport.BaseJsonRpcMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.isConnected {
+		return fmt.Errorf("not connected")
+	}
+
+	return t.writeEvent("message", string(data))
+}
+
+// SendKeepAlive writes a comment to the connection to keep it open. A failed write means the client has gone away.
+func (t *SSETransport) SendKeepAlive() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.isConnected {
+		return fmt.Errorf("not connected")
+	}
+
+	if err := eventstream.WriteComment(t.writer, "keepalive"); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+// Close closes the SSE connection
+func (t *SSETransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.isConnected {
+		return nil
+	}
+
+	t.isConnected = false
+	close(t.done)
+	return nil
+}
+
+// Done returns a channel that is closed when the connection is closed
+func (t *SSETransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// SessionID returns the unique session identifier for this connection
+func (t *SSETransport) SessionID() string {
+	return t.sessionID
+}
+
+// writeEvent writes an SSE event with the given event type and data
+func (t *SSETransport) writeEvent(event, data string) error {
+	if err := eventstream.WriteEvent(t.writer, eventstream.Event{Event: event, Data: data}); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,479 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	eventstream "github.com/metoro-io/mcp-golang/internal/sse"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+const (
+	// DefaultInitialReconnectDelay is the delay before the first attempt to reconnect a dropped event stream
+	DefaultInitialReconnectDelay = time.Second
+	// DefaultMaxReconnectDelay caps the delay between attempts to reconnect
+	DefaultMaxReconnectDelay = 30 * time.Second
+	// DefaultMaxReconnectAttempts is the number of consecutive failed attempts after which the transport gives up and closes
+	DefaultMaxReconnectAttempts = 5
+)
+
+var errTransportClosed = errors.New("transport closed")
+
+// SSEClientTransport implements the client side of the HTTP+SSE transport (MCP protocol revision 2024-11-05).
+//
+// The transport opens an event stream with a GET request and waits for the server's "endpoint" event, which names the
+// URL outgoing messages are POSTed to. Every message on the stream is passed to the message handler.
+//
+// If the stream drops, the transport reconnects with exponential backoff, sending the id of the last event it saw in the
+// Last-Event-ID header. Messages sent while reconnecting wait until the server has announced the new endpoint.
+//
+// Whether the new stream resumes the previous one depends on the server. SSEServerTransport starts a new session for
+// every stream, so the responses to the requests that were in flight when the stream dropped are lost. These requests
+// are failed with an ErrorCodeInternalError error as soon as the stream drops, rather than left waiting forever.
+type SSEClientTransport struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+
+	initialReconnectDelay time.Duration
+	maxReconnectDelay     time.Duration
+	maxReconnectAttempts  int
+
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	mu             sync.RWMutex
+
+	started     bool
+	endpoint    string
+	ready       chan struct{}
+	lastEventID string
+	retry       time.Duration
+	// Requests sent on the current stream that have not been answered yet
+	inflight map[transport.RequestId]struct{}
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// NewSSEClientTransport creates a new SSE client transport that opens its event stream at url,
+// e.g. "http://localhost:8080/sse"
+func NewSSEClientTransport(url string) *SSEClientTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SSEClientTransport{
+		url:                   url,
+		client:                &http.Client{},
+		headers:               make(map[string]string),
+		initialReconnectDelay: DefaultInitialReconnectDelay,
+		maxReconnectDelay:     DefaultMaxReconnectDelay,
+		maxReconnectAttempts:  DefaultMaxReconnectAttempts,
+		ready:                 make(chan struct{}),
+		inflight:              make(map[transport.RequestId]struct{}),
+		ctx:                   ctx,
+		cancel:                cancel,
+	}
+}
+
+// WithHeader adds a header to every request
+func (t *SSEClientTransport) WithHeader(key, value string) *SSEClientTransport {
+	t.headers[key] = value
+	return t
+}
+
+// WithReconnectDelay sets the delay before the first reconnection attempt and the cap it doubles up to on every
+// following attempt. A retry interval sent by the server takes the place of the initial delay.
+func (t *SSEClientTransport) WithReconnectDelay(initial, max time.Duration) *SSEClientTransport {
+	t.initialReconnectDelay = initial
+	t.maxReconnectDelay = max
+	return t
+}
+
+// WithMaxReconnectAttempts sets the number of consecutive failed reconnection attempts after which the transport
+// closes. Zero disables reconnecting and a negative value retries forever.
+func (t *SSEClientTransport) WithMaxReconnectAttempts(attempts int) *SSEClientTransport {
+	t.maxReconnectAttempts = attempts
+	return t
+}
+
+// Start implements Transport.Start
+// It opens the event stream and returns once the server has sent the endpoint event.
+// The transport is closed when ctx is done.
+func (t *SSEClientTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	if t.started {
+		t.mu.Unlock()
+		return fmt.Errorf("SSE client transport already started")
+	}
+	t.started = true
+	t.mu.Unlock()
+
+	body, reader, err := t.connect(ctx)
+	if err != nil {
+		t.mu.Lock()
+		t.started = false
+		t.mu.Unlock()
+		return err
+	}
+
+	go t.readLoop(body, reader)
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.ctx.Done():
+		}
+	}()
+	return nil
+}
+
+// Send implements Transport.Send
+// The message is POSTed to the endpoint announced by the server, waiting for the stream to reconnect if necessary.
+func (t *SSEClientTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	endpoint, err := t.waitForEndpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	// Tracked before it is posted, as the response can arrive on the stream before the POST returns
+	if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+		t.mu.Lock()
+		t.inflight[message.JsonRpcRequest.Id] = struct{}{}
+		t.mu.Unlock()
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.forgetRequest(message)
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		t.forgetRequest(message)
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned error: %s (status: %d)", string(body), resp.StatusCode)
+	}
+	return nil
+}
+
+// forgetRequest stops tracking a request that could not be sent
+func (t *SSEClientTransport) forgetRequest(message *transport.BaseJsonRpcMessage) {
+	if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+		t.mu.Lock()
+		delete(t.inflight, message.JsonRpcRequest.Id)
+		t.mu.Unlock()
+	}
+}
+
+// failInflightRequests answers the requests sent on a stream that dropped with an error, as their responses would have
+// been sent on it
+func (t *SSEClientTransport) failInflightRequests() {
+	t.mu.Lock()
+	inflight := t.inflight
+	t.inflight = make(map[transport.RequestId]struct{})
+	handler := t.messageHandler
+	t.mu.Unlock()
+
+	if handler == nil {
+		return
+	}
+	for id := range inflight {
+		handler(t.ctx, transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+			Jsonrpc: "2.0",
+			Id:      id,
+			Error: transport.BaseJSONRPCErrorInner{
+				Code:    transport.ErrorCodeInternalError,
+				Message: "the event stream dropped before the request was answered",
+			},
+		}))
+	}
+}
+
+// Close implements Transport.Close
+func (t *SSEClientTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.cancel()
+
+		t.mu.RLock()
+		handler := t.closeHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			handler()
+		}
+	})
+	return nil
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *SSEClientTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *SSEClientTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *SSEClientTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+// connect opens the event stream and reads it until the endpoint event has been received.
+// The stream is bound to the lifetime of the transport, ctx only bounds the time spent connecting.
+func (t *SSEClientTransport) connect(ctx context.Context) (io.ReadCloser, *eventstream.Reader, error) {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	t.mu.RLock()
+	if t.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", t.lastEventID)
+	}
+	t.mu.RUnlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open event stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("failed to open event stream (status: %d)", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("unexpected content type: %s", resp.Header.Get("Content-Type"))
+	}
+
+	// Unblock the read below if ctx is done before the endpoint event arrives
+	stop := context.AfterFunc(ctx, func() { resp.Body.Close() })
+	defer stop()
+
+	reader := eventstream.NewReader(resp.Body)
+	for {
+		event, err := reader.Next()
+		if err != nil {
+			resp.Body.Close()
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			return nil, nil, fmt.Errorf("event stream ended before the endpoint event: %w", err)
+		}
+		isEndpoint, err := t.handleEvent(reader, event)
+		if err != nil {
+			resp.Body.Close()
+			return nil, nil, err
+		}
+		if isEndpoint {
+			return resp.Body, reader, nil
+		}
+	}
+}
+
+// readLoop reads the event stream and reconnects whenever it drops, until the transport is closed
+func (t *SSEClientTransport) readLoop(body io.ReadCloser, reader *eventstream.Reader) {
+	for {
+		for {
+			event, err := reader.Next()
+			if err != nil {
+				break
+			}
+			if _, err := t.handleEvent(reader, event); err != nil {
+				t.handleError(err)
+			}
+		}
+		body.Close()
+
+		if t.ctx.Err() != nil {
+			return
+		}
+
+		// The server has to announce a new endpoint for the new stream
+		t.mu.Lock()
+		t.endpoint = ""
+		t.ready = make(chan struct{})
+		t.mu.Unlock()
+		t.failInflightRequests()
+
+		var err error
+		body, reader, err = t.reconnect()
+		if err != nil {
+			if !errors.Is(err, errTransportClosed) {
+				t.handleError(err)
+				t.Close()
+			}
+			return
+		}
+	}
+}
+
+// reconnect reopens the event stream with exponential backoff
+func (t *SSEClientTransport) reconnect() (io.ReadCloser, *eventstream.Reader, error) {
+	t.mu.RLock()
+	delay := t.initialReconnectDelay
+	if t.retry > 0 {
+		delay = t.retry
+	}
+	t.mu.RUnlock()
+
+	var lastErr error
+	for attempt := 0; t.maxReconnectAttempts < 0 || attempt < t.maxReconnectAttempts; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-t.ctx.Done():
+			timer.Stop()
+			return nil, nil, errTransportClosed
+		case <-timer.C:
+		}
+
+		body, reader, err := t.connect(t.ctx)
+		if err == nil {
+			return body, reader, nil
+		}
+		if t.ctx.Err() != nil {
+			return nil, nil, errTransportClosed
+		}
+		lastErr = err
+
+		delay *= 2
+		if delay > t.maxReconnectDelay {
+			delay = t.maxReconnectDelay
+		}
+	}
+
+	if lastErr == nil {
+		return nil, nil, errors.New("event stream closed")
+	}
+	return nil, nil, fmt.Errorf("failed to reconnect event stream: %w", lastErr)
+}
+
+// handleEvent processes a single event from the stream and reports whether it was the endpoint event
+func (t *SSEClientTransport) handleEvent(reader *eventstream.Reader, event *eventstream.Event) (bool, error) {
+	t.mu.Lock()
+	t.lastEventID = reader.LastEventID()
+	t.retry = reader.Retry()
+	t.mu.Unlock()
+
+	switch event.Event {
+	case "endpoint":
+		endpoint, err := t.resolveEndpoint(event.Data)
+		if err != nil {
+			return false, err
+		}
+		t.mu.Lock()
+		if t.endpoint == "" {
+			close(t.ready)
+		}
+		t.endpoint = endpoint
+		t.mu.Unlock()
+		return true, nil
+	case "", "message":
+		var message transport.BaseJsonRpcMessage
+		if err := json.Unmarshal([]byte(event.Data), &message); err != nil {
+			return false, fmt.Errorf("received invalid message: %w", err)
+		}
+
+		t.mu.Lock()
+		switch message.Type {
+		case transport.BaseMessageTypeJSONRPCResponseType:
+			delete(t.inflight, message.JsonRpcResponse.Id)
+		case transport.BaseMessageTypeJSONRPCErrorType:
+			delete(t.inflight, message.JsonRpcError.Id)
+		}
+		handler := t.messageHandler
+		t.mu.Unlock()
+		if handler != nil {
+			handler(t.ctx, &message)
+		}
+	}
+	return false, nil
+}
+
+// resolveEndpoint resolves the endpoint announced by the server against the stream URL.
+// The endpoint must have the same origin as the stream, so a server cannot redirect messages elsewhere.
+func (t *SSEClientTransport) resolveEndpoint(endpoint string) (string, error) {
+	base, err := url.Parse(t.url)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	ref, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != base.Scheme || resolved.Host != base.Host {
+		return "", fmt.Errorf("endpoint origin does not match the event stream: %s", resolved)
+	}
+	return resolved.String(), nil
+}
+
+// waitForEndpoint returns the current endpoint, waiting for the server to announce one while the stream reconnects
+func (t *SSEClientTransport) waitForEndpoint(ctx context.Context) (string, error) {
+	for {
+		t.mu.RLock()
+		endpoint := t.endpoint
+		ready := t.ready
+		started := t.started
+		t.mu.RUnlock()
+
+		if !started {
+			return "", errors.New("transport not started")
+		}
+		if endpoint != "" {
+			return endpoint, nil
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-t.ctx.Done():
+			return "", errTransportClosed
+		}
+	}
+}
+
+func (t *SSEClientTransport) handleError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
@@ -0,0 +1,221 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	eventstream "github.com/metoro-io/mcp-golang/internal/sse"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEClientTransport(t *testing.T) {
+	t.Run("round trip with the server transport", func(t *testing.T) {
+		server := newEchoSSEServer(t, NewSSEServerTransport("/messages"))
+
+		client := NewSSEClientTransport(server.URL + "/sse")
+		received := make(chan *transport.BaseJsonRpcMessage, 1)
+		client.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+			received <- message
+		})
+		require.NoError(t, client.Start(context.Background()))
+		defer client.Close()
+
+		err := client.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  "hello",
+			Id:      transport.NewRequestId(3),
+		}))
+		require.NoError(t, err)
+
+		select {
+		case message := <-received:
+			require.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, message.Type)
+			assert.Equal(t, transport.NewRequestId(3), message.JsonRpcResponse.Id)
+			assert.JSONEq(t, `{"method":"hello"}`, string(message.JsonRpcResponse.Result))
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the response")
+		}
+	})
+
+	t.Run("reconnects with the last event id", func(t *testing.T) {
+		var connections atomic.Int32
+		lastEventIds := make(chan string, 2)
+		posted := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				posted <- r.URL.String()
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+
+			n := connections.Add(1)
+			lastEventIds <- r.Header.Get("Last-Event-ID")
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			endpoint := "/messages?connection=1"
+			if n > 1 {
+				endpoint = "/messages?connection=2"
+			}
+			assert.NoError(t, eventstream.WriteEvent(w, eventstream.Event{Event: "endpoint", Data: endpoint}))
+			if n == 1 {
+				// Send one message, then drop the stream
+				assert.NoError(t, eventstream.WriteEvent(w, eventstream.Event{ID: "41", Event: "message", Data: `{"jsonrpc":"2.0","method":"notifications/message"}`, Retry: 10 * time.Millisecond}))
+				return
+			}
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		client := NewSSEClientTransport(server.URL).WithReconnectDelay(time.Second, time.Second)
+		received := make(chan *transport.BaseJsonRpcMessage, 1)
+		client.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+			received <- message
+		})
+		require.NoError(t, client.Start(context.Background()))
+		defer client.Close()
+
+		assert.Equal(t, "", <-lastEventIds)
+		message := <-received
+		assert.Equal(t, "notifications/message", message.JsonRpcNotification.Method)
+
+		// The server's retry interval replaces the one second delay
+		select {
+		case id := <-lastEventIds:
+			assert.Equal(t, "41", id)
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("timed out waiting for the stream to reconnect")
+		}
+
+		err := client.Send(context.Background(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: "2.0",
+			Method:  "notifications/initialized",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "/messages?connection=2", <-posted)
+	})
+
+	t.Run("requests in flight fail when the stream drops", func(t *testing.T) {
+		var connections atomic.Int32
+		// Events for the first stream, which drops once the channel is closed
+		events := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				var message transport.BaseJsonRpcMessage
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+				w.WriteHeader(http.StatusAccepted)
+				if message.JsonRpcRequest.Method == "answered" {
+					events <- `{"jsonrpc":"2.0","id":1,"result":{}}`
+				} else {
+					close(events)
+				}
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			assert.NoError(t, eventstream.WriteEvent(w, eventstream.Event{Event: "endpoint", Data: "/messages"}))
+			w.(http.Flusher).Flush()
+			if connections.Add(1) > 1 {
+				<-r.Context().Done()
+				return
+			}
+			for data := range events {
+				assert.NoError(t, eventstream.WriteEvent(w, eventstream.Event{Event: "message", Data: data}))
+				w.(http.Flusher).Flush()
+			}
+		}))
+		defer server.Close()
+
+		client := NewSSEClientTransport(server.URL).WithReconnectDelay(time.Millisecond, time.Millisecond)
+		received := make(chan *transport.BaseJsonRpcMessage, 2)
+		client.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+			received <- message
+		})
+		require.NoError(t, client.Start(context.Background()))
+		defer client.Close()
+
+		for i, method := range []string{"answered", "lost"} {
+			require.NoError(t, client.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+				Jsonrpc: "2.0",
+				Method:  method,
+				Id:      transport.NewRequestId(int64(i + 1)),
+			})))
+			select {
+			case message := <-received:
+				if method == "answered" {
+					require.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, message.Type)
+					assert.Equal(t, transport.NewRequestId(1), message.JsonRpcResponse.Id)
+				} else {
+					// Only the request that was not answered is failed
+					require.Equal(t, transport.BaseMessageTypeJSONRPCErrorType, message.Type)
+					assert.Equal(t, transport.NewRequestId(2), message.JsonRpcError.Id)
+					assert.Equal(t, transport.ErrorCodeInternalError, message.JsonRpcError.Error.Code)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("no message for the %s request", method)
+			}
+		}
+		select {
+		case message := <-received:
+			t.Errorf("unexpected message %+v", message)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("closes after the reconnect attempts are exhausted", func(t *testing.T) {
+		var connections atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if connections.Add(1) > 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			assert.NoError(t, eventstream.WriteEvent(w, eventstream.Event{Event: "endpoint", Data: "/messages"}))
+		}))
+		defer server.Close()
+
+		client := NewSSEClientTransport(server.URL).
+			WithReconnectDelay(time.Millisecond, 5*time.Millisecond).
+			WithMaxReconnectAttempts(3)
+		closed := make(chan struct{})
+		client.SetCloseHandler(func() { close(closed) })
+		errs := make(chan error, 10)
+		client.SetErrorHandler(func(err error) { errs <- err })
+		require.NoError(t, client.Start(context.Background()))
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("transport was not closed")
+		}
+		assert.Contains(t, (<-errs).Error(), "status: 503")
+		assert.Equal(t, int32(4), connections.Load())
+
+		err := client.Send(context.Background(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: "2.0",
+			Method:  "notifications/initialized",
+		}))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects an endpoint on another origin", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			assert.NoError(t, eventstream.WriteEvent(w, eventstream.Event{Event: "endpoint", Data: "http://example.com/messages"}))
+		}))
+		defer server.Close()
+
+		client := NewSSEClientTransport(server.URL)
+		err := client.Start(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "endpoint origin does not match")
+	})
+}
@@ -1,82 +1,427 @@
 package sse
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	sse2 "github.com/metoro-io/mcp-golang/transport/sse/internal/sse"
+)
+
+// DefaultKeepAliveInterval is how often a keepalive comment is written to idle SSE connections
+const DefaultKeepAliveInterval = 30 * time.Second
+
+// SSEServerTransport implements the server side of the HTTP+SSE transport (MCP protocol revision 2024-11-05).
 //
-//import (
-//	"context"
-//	"fmt"
-//	sse2 "github.com/metoro-io/mcp-golang/transport/sse/internal/sse"
-//	"io"
-//	"net/http"
-//)
-//
-//// SSEServerTransport implements a server-side SSE transport
-//type SSEServerTransport struct {
-//	transport *sse2.SSETransport
-//}
-//
-//// NewSSEServerTransport creates a new SSE server transport
-//func NewSSEServerTransport(endpoint string, w http.ResponseWriter) (*SSEServerTransport, error) {
-//	transport, err := sse2.NewSSETransport(endpoint, w)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	return &SSEServerTransport{
-//		transport: transport,
-//	}, nil
-//}
-//
-//// Start initializes the SSE connection
-//func (s *SSEServerTransport) Start(ctx context.Context) error {
-//	return s.transport.Start(ctx)
-//}
-//
-//// HandlePostMessage processes an incoming POST request containing a JSON-RPC message
-//func (s *SSEServerTransport) HandlePostMessage(r *http.Request) error {
-//	if r.Method != http.MethodPost {
-//		return fmt.Errorf("method not allowed: %s", r.Method)
-//	}
-//
-//	contentType := r.Header.Get("Content-Type")
-//	if contentType != "application/json" {
-//		return fmt.Errorf("unsupported Content type: %s", contentType)
-//	}
-//
-//	body, err := io.ReadAll(io.LimitReader(r.Body, sse2.maxMessageSize))
-//	if err != nil {
-//		return fmt.Errorf("failed to read request body: %w", err)
-//	}
-//	defer r.Body.Close()
-//
-//	return s.transport.HandleMessage(body)
-//}
-//
-//// Send sends a message over the SSE connection
-//func (s *SSEServerTransport) Send(msg JSONRPCMessage) error {
-//	return s.transport.Send(msg)
-//}
-//
-//// Close closes the SSE connection
-//func (s *SSEServerTransport) Close() error {
-//	return s.transport.Close()
-//}
-//
-//// SetCloseHandler sets the callback for when the connection is closed
-//func (s *SSEServerTransport) SetCloseHandler(handler func()) {
-//	s.transport.SetCloseHandler(handler)
-//}
-//
-//// SetErrorHandler sets the callback for when an error occurs
-//func (s *SSEServerTransport) SetErrorHandler(handler func(error)) {
-//	s.transport.SetErrorHandler(handler)
-//}
-//
-//// SetMessageHandler sets the callback for when a message is received
-//func (s *SSEServerTransport) SetMessageHandler(handler func(JSONRPCMessage)) {
-//	s.transport.SetMessageHandler(handler)
-//}
+// Clients open a GET request to receive messages as Server-Sent Events. The first event on the stream is an "endpoint"
+// event carrying the URL, including a per-session sessionId query parameter, that the client must POST its messages to.
+// Responses are delivered on the stream of the session that sent the request.
 //
-//// SessionID returns the unique session identifier for this transport
-//func (s *SSEServerTransport) SessionID() string {
-//	return s.transport.SessionID()
-//}
+// SSEServerTransport is an http.Handler: GET requests open a stream and POST requests deliver messages, so it can be
+// mounted on any mux, either on a single path or on separate paths for the stream and the message endpoint.
+type SSEServerTransport struct {
+	messageEndpoint   string
+	keepAliveInterval time.Duration
+
+	messageHandler      func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler        func(error)
+	closeHandler        func()
+	sessionCloseHandler func(session string)
+	mu                  sync.RWMutex
+
+	stateMu       sync.Mutex
+	sessions      map[string]*sse2.SSETransport
+	pending       map[transport.RequestId]*pendingResponse
+	inflight      map[inflightKey]transport.RequestId
+	nextRequestId int64
+	closed        bool
+}
+
+// The transport rewrites the ids of incoming requests so that requests from different sessions can never collide.
+// pendingResponse remembers which session the response to a rewritten request has to go to.
+type pendingResponse struct {
+	sessionId  string
+	originalId transport.RequestId
+}
+
+type inflightKey struct {
+	sessionId  string
+	originalId transport.RequestId
+}
+
+// NewSSEServerTransport creates a new SSE server transport. messageEndpoint is the URL clients are told to POST their
+// messages to, e.g. "/messages".
+func NewSSEServerTransport(messageEndpoint string) *SSEServerTransport {
+	return &SSEServerTransport{
+		messageEndpoint:   messageEndpoint,
+		keepAliveInterval: DefaultKeepAliveInterval,
+		sessions:          make(map[string]*sse2.SSETransport),
+		pending:           make(map[transport.RequestId]*pendingResponse),
+		inflight:          make(map[inflightKey]transport.RequestId),
+	}
+}
+
+// WithKeepAliveInterval sets how often a keepalive comment is written to idle connections. Zero disables keepalives.
+func (t *SSEServerTransport) WithKeepAliveInterval(interval time.Duration) *SSEServerTransport {
+	t.keepAliveInterval = interval
+	return t
+}
+
+// Start implements Transport.Start
+// The transport is served by the mux it is mounted on, so Start only arranges for the transport to be closed with ctx.
+func (t *SSEServerTransport) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		t.Close()
+	}()
+	return nil
+}
+
+// Send implements Transport.Send
+// Responses are sent to the session that made the request. Other messages are sent to the session whose request is
+// being handled in ctx, or to every connected session otherwise.
+func (t *SSEServerTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType, transport.BaseMessageTypeJSONRPCErrorType:
+		return t.sendResponse(message)
+	}
+
+	if sessionId, ok := transport.SessionFromContext(ctx); ok {
+		session := t.session(sessionId)
+		if session == nil {
+			return fmt.Errorf("session not found: %s", sessionId)
+		}
+		return session.Send(message)
+	}
+
+	t.stateMu.Lock()
+	sessions := make([]*sse2.SSETransport, 0, len(t.sessions))
+	for _, session := range t.sessions {
+		sessions = append(sessions, session)
+	}
+	t.stateMu.Unlock()
+
+	var errs []error
+	for _, session := range sessions {
+		if err := session.Send(message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *SSEServerTransport) sendResponse(message *transport.BaseJsonRpcMessage) error {
+	var id transport.RequestId
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType {
+		id = message.JsonRpcResponse.Id
+	} else {
+		id = message.JsonRpcError.Id
+	}
+
+	t.stateMu.Lock()
+	pending := t.pending[id]
+	if pending != nil {
+		delete(t.pending, id)
+		delete(t.inflight, inflightKey{sessionId: pending.sessionId, originalId: pending.originalId})
+	}
+	session := t.sessions[pendingSessionId(pending)]
+	t.stateMu.Unlock()
+
+	if pending == nil {
+		return fmt.Errorf("no pending request found for id: %s", id)
+	}
+	if session == nil {
+		return fmt.Errorf("session not found: %s", pending.sessionId)
+	}
+
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType {
+		message.JsonRpcResponse.Id = pending.originalId
+	} else {
+		message.JsonRpcError.Id = pending.originalId
+	}
+	return session.Send(message)
+}
+
+// Close implements Transport.Close
+// Every open stream is ended.
+func (t *SSEServerTransport) Close() error {
+	t.stateMu.Lock()
+	if t.closed {
+		t.stateMu.Unlock()
+		return nil
+	}
+	t.closed = true
+	sessions := t.sessions
+	t.sessions = make(map[string]*sse2.SSETransport)
+	t.pending = make(map[transport.RequestId]*pendingResponse)
+	t.inflight = make(map[inflightKey]transport.RequestId)
+	t.stateMu.Unlock()
+
+	for _, session := range sessions {
+		session.Close()
+	}
+
+	t.mu.RLock()
+	handler := t.closeHandler
+	t.mu.RUnlock()
+	if handler != nil {
+		handler()
+	}
+	return nil
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *SSEServerTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *SSEServerTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetSessionCloseHandler implements transport.SessionCloseNotifier. The handler is called when the event stream of a session ends.
+func (t *SSEServerTransport) SetSessionCloseHandler(handler func(session string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessionCloseHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *SSEServerTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+// ServeHTTP implements http.Handler
+func (t *SSEServerTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		t.handleStream(w, r)
+	case http.MethodPost:
+		if err := t.HandlePostMessage(w, r); err != nil {
+			t.reportError(err)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, fmt.Sprintf("method not allowed: %s", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+// handleStream serves a new session's event stream until the client disconnects or the transport is closed
+func (t *SSEServerTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	session, err := sse2.NewSSETransport(t.messageEndpoint, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	t.stateMu.Lock()
+	closed := t.closed
+	t.stateMu.Unlock()
+	if closed {
+		http.Error(w, "transport closed", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := session.Start(r.Context()); err != nil {
+		t.reportError(fmt.Errorf("failed to start event stream: %w", err))
+		return
+	}
+
+	// The session is only registered once the endpoint event has been written, nothing can be sent to it before
+	t.stateMu.Lock()
+	if t.closed {
+		t.stateMu.Unlock()
+		session.Close()
+		return
+	}
+	t.sessions[session.SessionID()] = session
+	t.stateMu.Unlock()
+	defer t.removeSession(session.SessionID())
+
+	var keepAlive <-chan time.Time
+	if t.keepAliveInterval > 0 {
+		ticker := time.NewTicker(t.keepAliveInterval)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	for {
+		select {
+		case <-session.Done():
+			return
+		case <-keepAlive:
+			if err := session.SendKeepAlive(); err != nil {
+				session.Close()
+				return
+			}
+		}
+	}
+}
+
+// HandlePostMessage processes an incoming POST request containing a JSON-RPC message for the session named in the
+// sessionId query parameter
+func (t *SSEServerTransport) HandlePostMessage(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return fmt.Errorf("method not allowed: %s", r.Method)
+	}
+
+	sessionId := r.URL.Query().Get("sessionId")
+	if sessionId == "" {
+		http.Error(w, "missing sessionId", http.StatusBadRequest)
+		return fmt.Errorf("missing sessionId")
+	}
+	if t.session(sessionId) == nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return fmt.Errorf("session not found: %s", sessionId)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return fmt.Errorf("unsupported Content type: %s", r.Header.Get("Content-Type"))
+	}
+
+	defer r.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(r.Body, sse2.MaxMessageSize+1))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(body) > sse2.MaxMessageSize {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return fmt.Errorf("message exceeds %d bytes", sse2.MaxMessageSize)
+	}
+
+	var message transport.BaseJsonRpcMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		if jsonData, err := json.Marshal(transport.NewInvalidMessageError(body, err)); err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write(jsonData)
+		}
+		return fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCRequestType:
+		t.trackRequest(sessionId, message.JsonRpcRequest)
+	case transport.BaseMessageTypeJSONRPCNotificationType:
+		if message.JsonRpcNotification.Method == "notifications/cancelled" {
+			t.rewriteCancellation(sessionId, message.JsonRpcNotification)
+		}
+	}
+
+	// Responses are delivered on the event stream, so the POST is acknowledged straight away
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte("Accepted"))
+
+	t.mu.RLock()
+	handler := t.messageHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		// Requests are answered on the event stream after the POST has completed, so they must not be bound to its context
+		handler(transport.WithSession(context.Background(), sessionId), &message)
+	}
+	return nil
+}
+
+func (t *SSEServerTransport) session(sessionId string) *sse2.SSETransport {
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+	return t.sessions[sessionId]
+}
+
+// removeSession forgets a disconnected session and every request it has not received a response for
+func (t *SSEServerTransport) removeSession(sessionId string) {
+	t.stateMu.Lock()
+	delete(t.sessions, sessionId)
+	for id, pending := range t.pending {
+		if pending.sessionId == sessionId {
+			delete(t.pending, id)
+			delete(t.inflight, inflightKey{sessionId: sessionId, originalId: pending.originalId})
+		}
+	}
+	t.stateMu.Unlock()
+
+	t.mu.RLock()
+	handler := t.sessionCloseHandler
+	t.mu.RUnlock()
+	if handler != nil {
+		handler(sessionId)
+	}
+}
+
+// trackRequest rewrites the id of request to one that is unique across sessions and remembers where to send the response
+func (t *SSEServerTransport) trackRequest(sessionId string, request *transport.BaseJSONRPCRequest) {
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+
+	id := transport.NewRequestId(t.nextRequestId)
+	t.nextRequestId++
+	t.pending[id] = &pendingResponse{
+		sessionId:  sessionId,
+		originalId: request.Id,
+	}
+	t.inflight[inflightKey{sessionId: sessionId, originalId: request.Id}] = id
+	request.Id = id
+}
+
+// rewriteCancellation points a cancellation at the rewritten id of the request it cancels
+func (t *SSEServerTransport) rewriteCancellation(sessionId string, notification *transport.BaseJSONRPCNotification) {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(notification.Params, &params); err != nil {
+		return
+	}
+	var originalId transport.RequestId
+	if err := json.Unmarshal(params["requestId"], &originalId); err != nil {
+		return
+	}
+
+	t.stateMu.Lock()
+	id, ok := t.inflight[inflightKey{sessionId: sessionId, originalId: originalId}]
+	t.stateMu.Unlock()
+	if !ok {
+		return
+	}
+
+	rewritten, err := json.Marshal(id)
+	if err != nil {
+		return
+	}
+	params["requestId"] = rewritten
+	if marshalled, err := json.Marshal(params); err == nil {
+		notification.Params = marshalled
+	}
+}
+
+func (t *SSEServerTransport) reportError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+func pendingSessionId(pending *pendingResponse) string {
+	if pending == nil {
+		return ""
+	}
+	return pending.sessionId
+}
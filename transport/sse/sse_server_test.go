@@ -1,126 +1,201 @@
 package sse
 
-//
-//import (
-//	"bytes"
-//	"context"
-//	"encoding/json"
-//	"github.com/metoro-io/mcp-golang"
-//	"net/http"
-//	"net/http/httptest"
-//	"strings"
-//	"testing"
-//
-//	"github.com/stretchr/testify/assert"
-//)
-//
-//func TestSSEServerTransport(t *testing.T) {
-//	t.Run("basic message handling", func(t *testing.T) {
-//		w := httptest.NewRecorder()
-//		transport, err := NewSSEServerTransport("/messages", w)
-//		assert.NoError(t, err)
-//
-//		var receivedMsg JSONRPCMessage
-//		transport.SetMessageHandler(func(msg JSONRPCMessage) {
-//			receivedMsg = msg
-//		})
-//
-//		ctx := context.Background()
-//		err = transport.Start(ctx)
-//		assert.NoError(t, err)
-//
-//		// Verify SSE headers
-//		headers := w.Header()
-//		assert.Equal(t, "text/event-stream", headers.Get("Content-Type"))
-//		assert.Equal(t, "no-cache", headers.Get("Cache-Control"))
-//		assert.Equal(t, "keep-alive", headers.Get("Connection"))
-//
-//		// Verify endpoint event was sent
-//		body := w.Body.String()
-//		assert.Contains(t, body, "event: endpoint")
-//		assert.Contains(t, body, "/messages?sessionId=")
-//
-//		// Test message handling
-//		msg := JSONRPCRequest{
-//			Jsonrpc: "2.0",
-//			Method:  "test",
-//			Id:      1,
-//		}
-//		msgBytes, err := json.Marshal(msg)
-//		assert.NoError(t, err)
-//
-//		httpReq := httptest.NewRequest(http.MethodPost, "/messages", bytes.NewReader(msgBytes))
-//		httpReq.Header.Set("Content-Type", "application/json")
-//		err = transport.HandlePostMessage(httpReq)
-//		assert.NoError(t, err)
-//
-//		// Verify received message
-//		rpcReq, ok := receivedMsg.(*JSONRPCRequest)
-//		assert.True(t, ok)
-//		assert.Equal(t, "2.0", rpcReq.Jsonrpc)
-//		assert.Equal(t, mcp.RequestId(1), rpcReq.Id)
-//
-//		err = transport.Close()
-//		assert.NoError(t, err)
-//	})
-//
-//	t.Run("send message", func(t *testing.T) {
-//		w := httptest.NewRecorder()
-//		transport, err := NewSSEServerTransport("/messages", w)
-//		assert.NoError(t, err)
-//
-//		ctx := context.Background()
-//		err = transport.Start(ctx)
-//		assert.NoError(t, err)
-//
-//		msg := JSONRPCResponse{
-//			Jsonrpc: "2.0",
-//			Result:  Result{AdditionalProperties: map[string]interface{}{"status": "ok"}},
-//			Id:      1,
-//		}
-//
-//		err = transport.Send(msg)
-//		assert.NoError(t, err)
-//
-//		// Verify output contains the message
-//		body := w.Body.String()
-//		assert.Contains(t, body, `event: message`)
-//		assert.Contains(t, body, `"result":{"AdditionalProperties":{"status":"ok"}}`)
-//	})
-//
-//	t.Run("error handling", func(t *testing.T) {
-//		w := httptest.NewRecorder()
-//		transport, err := NewSSEServerTransport("/messages", w)
-//		assert.NoError(t, err)
-//
-//		var receivedErr error
-//		transport.SetErrorHandler(func(err error) {
-//			receivedErr = err
-//		})
-//
-//		ctx := context.Background()
-//		err = transport.Start(ctx)
-//		assert.NoError(t, err)
-//
-//		// Test invalid JSON
-//		req := httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader("invalid json"))
-//		req.Header.Set("Content-Type", "application/json")
-//		err = transport.HandlePostMessage(req)
-//		assert.Error(t, err)
-//		assert.NotNil(t, receivedErr)
-//		assert.Contains(t, receivedErr.Error(), "invalid")
-//
-//		// Test invalid Content type
-//		req = httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader("{}"))
-//		req.Header.Set("Content-Type", "text/plain")
-//		err = transport.HandlePostMessage(req)
-//		assert.Error(t, err)
-//		assert.Contains(t, err.Error(), "unsupported Content type")
-//
-//		// Test invalid method
-//		req = httptest.NewRequest(http.MethodGet, "/messages", nil)
-//		err = transport.HandlePostMessage(req)
-//		assert.Error(t, err)
-//		assert.Contains(t, err.Error(), "method not allowed")
-//	})
-//}
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	eventstream "github.com/metoro-io/mcp-golang/internal/sse"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEchoSSEServer serves tr and answers every request with its method name
+func newEchoSSEServer(t *testing.T, tr *SSEServerTransport) *httptest.Server {
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return
+		}
+		request := message.JsonRpcRequest
+		go func() {
+			result, _ := json.Marshal(map[string]string{"method": request.Method})
+			err := tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      request.Id,
+				Result:  result,
+			}))
+			assert.NoError(t, err)
+		}()
+	})
+	server := httptest.NewServer(tr)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// openStream opens an event stream and returns its reader and the message endpoint from the endpoint event
+func openStream(t *testing.T, ctx context.Context, url string) (*http.Response, *eventstream.Reader, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := eventstream.NewReader(resp.Body)
+	event, err := reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, "endpoint", event.Event)
+	return resp, reader, event.Data
+}
+
+func postMessage(t *testing.T, url string, contentType string, body string) *http.Response {
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestSSEServerTransport(t *testing.T) {
+	t.Run("responses are routed to the session that made the request", func(t *testing.T) {
+		tr := NewSSEServerTransport("/messages")
+		server := newEchoSSEServer(t, tr)
+
+		resp1, reader1, endpoint1 := openStream(t, context.Background(), server.URL+"/sse")
+		_, reader2, endpoint2 := openStream(t, context.Background(), server.URL+"/sse")
+
+		assert.Equal(t, "text/event-stream", resp1.Header.Get("Content-Type"))
+		assert.Equal(t, "no-cache", resp1.Header.Get("Cache-Control"))
+		assert.True(t, strings.HasPrefix(endpoint1, "/messages?sessionId="))
+		assert.NotEqual(t, endpoint1, endpoint2)
+
+		// Both sessions use the same request id
+		resp := postMessage(t, server.URL+endpoint2, "application/json", `{"jsonrpc":"2.0","id":1,"method":"second"}`)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		resp = postMessage(t, server.URL+endpoint1, "application/json", `{"jsonrpc":"2.0","id":1,"method":"first"}`)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		event, err := reader1.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "message", event.Event)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{"method":"first"}}`, event.Data)
+
+		event, err = reader2.Next()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{"method":"second"}}`, event.Data)
+	})
+
+	t.Run("notifications are broadcast to every session", func(t *testing.T) {
+		tr := NewSSEServerTransport("/messages")
+		server := newEchoSSEServer(t, tr)
+
+		_, reader1, _ := openStream(t, context.Background(), server.URL)
+		_, reader2, _ := openStream(t, context.Background(), server.URL)
+
+		err := tr.Send(context.Background(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: "2.0",
+			Method:  "notifications/tools/list_changed",
+		}))
+		require.NoError(t, err)
+
+		for _, reader := range []*eventstream.Reader{reader1, reader2} {
+			event, err := reader.Next()
+			require.NoError(t, err)
+			assert.Contains(t, event.Data, "notifications/tools/list_changed")
+		}
+	})
+
+	t.Run("invalid posts are rejected", func(t *testing.T) {
+		tr := NewSSEServerTransport("/messages")
+		server := newEchoSSEServer(t, tr)
+		var receivedErr error
+		errCh := make(chan error, 10)
+		tr.SetErrorHandler(func(err error) { errCh <- err })
+
+		_, _, endpoint := openStream(t, context.Background(), server.URL)
+
+		resp := postMessage(t, server.URL+"/messages", "application/json", `{"jsonrpc":"2.0","id":1,"method":"test"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		<-errCh
+
+		resp = postMessage(t, server.URL+"/messages?sessionId=unknown", "application/json", `{"jsonrpc":"2.0","id":1,"method":"test"}`)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		<-errCh
+
+		resp = postMessage(t, server.URL+endpoint, "text/plain", `{"jsonrpc":"2.0","id":1,"method":"test"}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		receivedErr = <-errCh
+		assert.Contains(t, receivedErr.Error(), "unsupported Content type")
+
+		resp = postMessage(t, server.URL+endpoint, "application/json", "invalid json")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		receivedErr = <-errCh
+		assert.Contains(t, receivedErr.Error(), "failed to parse message")
+
+		req, err := http.NewRequest(http.MethodPut, server.URL+endpoint, nil)
+		require.NoError(t, err)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("keepalive comments are written to idle streams", func(t *testing.T) {
+		tr := NewSSEServerTransport("/messages").WithKeepAliveInterval(10 * time.Millisecond)
+		server := newEchoSSEServer(t, tr)
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if scanner.Text() == ": keepalive" {
+				return
+			}
+		}
+		t.Fatal("stream ended without a keepalive comment")
+	})
+
+	t.Run("disconnected sessions are removed", func(t *testing.T) {
+		tr := NewSSEServerTransport("/messages")
+		server := newEchoSSEServer(t, tr)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		_, _, endpoint := openStream(t, ctx, server.URL)
+		sessionId := strings.TrimPrefix(endpoint, "/messages?sessionId=")
+		assert.NotNil(t, tr.session(sessionId))
+
+		cancel()
+		assert.Eventually(t, func() bool { return tr.session(sessionId) == nil }, time.Second, 10*time.Millisecond)
+
+		resp := postMessage(t, server.URL+endpoint, "application/json", `{"jsonrpc":"2.0","id":1,"method":"test"}`)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("close ends every stream", func(t *testing.T) {
+		tr := NewSSEServerTransport("/messages")
+		server := newEchoSSEServer(t, tr)
+		closed := make(chan struct{})
+		tr.SetCloseHandler(func() { close(closed) })
+
+		_, reader, _ := openStream(t, context.Background(), server.URL)
+
+		require.NoError(t, tr.Close())
+		<-closed
+
+		_, err := reader.Next()
+		assert.Equal(t, io.EOF, err)
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
@@ -0,0 +1,19 @@
+package stdio
+
+import (
+	"github.com/metoro-io/mcp-golang/transport/stdio/internal/stdio"
+)
+
+// Framing selects how messages are delimited on the stream
+type Framing = stdio.Framing
+
+const (
+	// FramingAuto detects the framing from the first bytes received and answers in kind. Until the framing has been
+	// detected, messages are sent newline-delimited. This is the default.
+	FramingAuto = stdio.FramingAuto
+	// FramingNewline delimits messages with a newline
+	FramingNewline = stdio.FramingNewline
+	// FramingContentLength prefixes every message with a "Content-Length: <bytes>" header followed by an empty line,
+	// as in the Language Server Protocol. Message bodies may contain newlines, e.g. pretty-printed JSON.
+	FramingContentLength = stdio.FramingContentLength
+)
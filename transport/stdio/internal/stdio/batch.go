@@ -0,0 +1,78 @@
+package stdio
+
+import (
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// Batches gathers the responses to the requests of incoming batches, so that they can be sent back as one batch as
+// JSON-RPC requires.
+type Batches struct {
+	mu sync.Mutex
+	// Batches waiting for responses, by the ids of their requests
+	pending map[transport.RequestId]*batch
+}
+
+type batch struct {
+	remaining int
+	responses []*transport.BaseJsonRpcMessage
+}
+
+// NewBatches creates an empty set of batches
+func NewBatches() *Batches {
+	return &Batches{pending: make(map[transport.RequestId]*batch)}
+}
+
+// Add registers the requests of an incoming batch, along with the errors that answer its invalid elements. Those errors
+// are returned straight away when the batch has no request to wait for, otherwise they are sent with the responses to
+// its requests. Batches of notifications and responses get no response.
+func (b *Batches) Add(messages []*transport.BaseJsonRpcMessage, invalid []*transport.BaseJsonRpcMessage) []*transport.BaseJsonRpcMessage {
+	pending := &batch{responses: invalid}
+	for _, message := range messages {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			pending.remaining++
+		}
+	}
+	if pending.remaining == 0 {
+		return invalid
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, message := range messages {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			b.pending[message.JsonRpcRequest.Id] = pending
+		}
+	}
+	return nil
+}
+
+// Collect takes a response or error that is about to be sent. It returns false if the message does not answer a
+// request of a batch and should be sent on its own. Otherwise the message is held back until the batch is complete, and
+// the last response of the batch returns all of them.
+func (b *Batches) Collect(message *transport.BaseJsonRpcMessage) ([]*transport.BaseJsonRpcMessage, bool) {
+	var id transport.RequestId
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType:
+		id = message.JsonRpcResponse.Id
+	case transport.BaseMessageTypeJSONRPCErrorType:
+		id = message.JsonRpcError.Id
+	default:
+		return nil, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending, ok := b.pending[id]
+	if !ok {
+		return nil, false
+	}
+	delete(b.pending, id)
+	pending.responses = append(pending.responses, message)
+	pending.remaining--
+	if pending.remaining > 0 {
+		return nil, true
+	}
+	return pending.responses, true
+}
@@ -0,0 +1,117 @@
+package stdio
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Framing selects how messages are delimited on a stream
+type Framing int
+
+const (
+	// FramingAuto detects the framing from the first bytes received: a JSON value means newline-delimited messages,
+	// anything else means Content-Length headers. Until the framing has been detected, messages are sent
+	// newline-delimited.
+	FramingAuto Framing = iota
+	// FramingNewline delimits messages with a newline, a message must not contain unescaped newlines
+	FramingNewline
+	// FramingContentLength prefixes every message with a Content-Length header, as in the Language Server Protocol
+	FramingContentLength
+)
+
+const contentLengthHeader = "content-length"
+
+// FrameMessage frames a serialized message for writing to a stream with the given framing
+func FrameMessage(framing Framing, data []byte) []byte {
+	if framing == FramingContentLength {
+		framed := make([]byte, 0, len(data)+32)
+		framed = append(framed, fmt.Sprintf("Content-Length: %d\r\n\r\n", len(data))...)
+		return append(framed, data...)
+	}
+	framed := make([]byte, 0, len(data)+1)
+	framed = append(framed, data...)
+	return append(framed, '\n')
+}
+
+// detectFraming looks at the first non-whitespace byte of buf, it returns FramingAuto if there is none yet
+func detectFraming(buf []byte) Framing {
+	trimmed := bytes.TrimLeft(buf, " \t\r\n")
+	if len(trimmed) == 0 {
+		return FramingAuto
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		return FramingNewline
+	}
+	return FramingContentLength
+}
+
+// readNewlineMessage returns the first line of buf and the number of bytes consumed, including the newline.
+// It consumes nothing if buf does not hold a complete line, and returns a nil message for a blank line.
+func readNewlineMessage(buf []byte) ([]byte, int, error) {
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 {
+		return nil, 0, nil
+	}
+	line := bytes.TrimSpace(buf[:i])
+	if len(line) == 0 {
+		return nil, i + 1, nil
+	}
+	return line, i + 1, nil
+}
+
+// readContentLengthMessage returns the body of the first message in buf and the number of bytes consumed.
+// The header block ends with an empty line and must contain a Content-Length header; other headers are ignored.
+// It consumes nothing if buf does not hold a complete message yet, which may be partway through the headers.
+func readContentLengthMessage(buf []byte) ([]byte, int, error) {
+	// Leading blank lines before the headers are skipped
+	start := len(buf) - len(bytes.TrimLeft(buf, "\r\n"))
+	if start == len(buf) {
+		return nil, start, nil
+	}
+
+	headerEnd, separatorLength := findHeaderEnd(buf[start:])
+	if headerEnd < 0 {
+		return nil, 0, nil
+	}
+	headers := string(buf[start : start+headerEnd])
+	bodyStart := start + headerEnd + separatorLength
+
+	length := -1
+	for _, line := range strings.Split(headers, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSuffix(line, "\r"), ":")
+		if !ok || strings.ToLower(strings.TrimSpace(name)) != contentLengthHeader {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			// Drop the broken header block so the stream can recover at the next message
+			return nil, bodyStart, fmt.Errorf("invalid Content-Length header: %q", value)
+		}
+		length = n
+	}
+	if length < 0 {
+		return nil, bodyStart, fmt.Errorf("missing Content-Length header in %q", headers)
+	}
+
+	if len(buf)-bodyStart < length {
+		return nil, 0, nil
+	}
+	return buf[bodyStart : bodyStart+length], bodyStart + length, nil
+}
+
+// findHeaderEnd returns the position of the empty line that ends a header block and the length of the separator,
+// accepting both "\r\n\r\n" and "\n\n". It returns -1 if the header block is incomplete.
+func findHeaderEnd(buf []byte) (int, int) {
+	crlf := bytes.Index(buf, []byte("\r\n\r\n"))
+	lf := bytes.Index(buf, []byte("\n\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return crlf, 4
+	case lf >= 0:
+		return lf, 2
+	default:
+		return -1, 0
+	}
+}
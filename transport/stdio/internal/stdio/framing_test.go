@@ -0,0 +1,97 @@
+package stdio
+
+import (
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prettyRequest = `{
+  "jsonrpc": "2.0",
+  "id": 1,
+  "method": "tools/list"
+}`
+
+// readAll reads every complete message from rb
+func readAll(t *testing.T, rb *ReadBuffer) []*transport.BaseJsonRpcMessage {
+	var messages []*transport.BaseJsonRpcMessage
+	for {
+		msg, err := rb.ReadMessage()
+		require.NoError(t, err)
+		if msg == nil {
+			return messages
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func TestContentLengthFraming(t *testing.T) {
+	t.Run("messages split at every byte", func(t *testing.T) {
+		stream := append(FrameMessage(FramingContentLength, []byte(prettyRequest)),
+			FrameMessage(FramingContentLength, []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))...)
+
+		rb := NewReadBufferWithFraming(FramingContentLength)
+		var messages []*transport.BaseJsonRpcMessage
+		for i := range stream {
+			rb.Append(stream[i : i+1])
+			messages = append(messages, readAll(t, rb)...)
+		}
+
+		require.Len(t, messages, 2)
+		assert.Equal(t, "tools/list", messages[0].JsonRpcRequest.Method)
+		assert.Equal(t, "notifications/initialized", messages[1].JsonRpcNotification.Method)
+	})
+
+	t.Run("other headers and bare newlines", func(t *testing.T) {
+		rb := NewReadBufferWithFraming(FramingContentLength)
+		rb.Append([]byte("Content-Type: application/vscode-jsonrpc; charset=utf-8\ncontent-length: 54\n\n" +
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+
+		messages := readAll(t, rb)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "notifications/initialized", messages[0].JsonRpcNotification.Method)
+	})
+
+	t.Run("a missing header is reported and skipped", func(t *testing.T) {
+		rb := NewReadBufferWithFraming(FramingContentLength)
+		rb.Append([]byte("X-Other: 1\r\n\r\n"))
+		rb.Append(FrameMessage(FramingContentLength, []byte(prettyRequest)))
+
+		_, err := rb.ReadMessage()
+		assert.ErrorContains(t, err, "missing Content-Length header")
+		messages := readAll(t, rb)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "tools/list", messages[0].JsonRpcRequest.Method)
+	})
+}
+
+func TestAutoFraming(t *testing.T) {
+	t.Run("newline", func(t *testing.T) {
+		rb := NewReadBufferWithFraming(FramingAuto)
+		rb.Append([]byte("\n  "))
+		assert.Nil(t, readAll(t, rb))
+		assert.Equal(t, FramingAuto, rb.Framing())
+
+		rb.Append([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n\n"))
+		assert.Len(t, readAll(t, rb), 1)
+		assert.Equal(t, FramingNewline, rb.Framing())
+	})
+
+	t.Run("content length", func(t *testing.T) {
+		rb := NewReadBufferWithFraming(FramingAuto)
+		rb.Append([]byte("Cont"))
+		assert.Nil(t, readAll(t, rb))
+		assert.Equal(t, FramingContentLength, rb.Framing())
+
+		rb.Append(FrameMessage(FramingContentLength, []byte(prettyRequest))[4:])
+		assert.Len(t, readAll(t, rb), 1)
+	})
+}
+
+func TestFrameMessage(t *testing.T) {
+	assert.Equal(t, "{}\n", string(FrameMessage(FramingNewline, []byte("{}"))))
+	assert.Equal(t, "{}\n", string(FrameMessage(FramingAuto, []byte("{}"))))
+	assert.Equal(t, "Content-Length: 2\r\n\r\n{}", string(FrameMessage(FramingContentLength, []byte("{}"))))
+}
@@ -7,7 +7,7 @@
 // 1. ReadBuffer:
 //   - Buffers continuous stdio stream into discrete JSON-RPC messages
 //   - Thread-safe with mutex protection
-//   - Handles message framing using newline delimiters
+//   - Handles message framing using newline delimiters or Content-Length headers, see framing.go
 //   - Methods: Append (add data), ReadMessage (read complete message), Clear (reset buffer)
 //
 // 2. StdioTransport:
@@ -58,57 +58,105 @@
 package stdio
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/metoro-io/mcp-golang/transport"
 	"sync"
 )
 
 // ReadBuffer buffers a continuous stdio stream into discrete JSON-RPC messages.
 type ReadBuffer struct {
-	mu     sync.Mutex
-	buffer []byte
+	mu      sync.Mutex
+	buffer  []byte
+	framing Framing
 }
 
-// NewReadBuffer creates a new ReadBuffer.
+// NewReadBuffer creates a new ReadBuffer for newline-delimited messages.
 func NewReadBuffer() *ReadBuffer {
-	return &ReadBuffer{}
+	return NewReadBufferWithFraming(FramingNewline)
+}
+
+// NewReadBufferWithFraming creates a new ReadBuffer for messages framed with framing.
+// With FramingAuto the framing is detected from the first bytes of the stream.
+func NewReadBufferWithFraming(framing Framing) *ReadBuffer {
+	return &ReadBuffer{framing: framing}
+}
+
+// Framing returns the framing of the stream, FramingAuto if it has not been detected yet.
+func (rb *ReadBuffer) Framing() Framing {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.framing
 }
 
 // Append adds a chunk of data to the buffer.
+// The chunk is copied, so the caller may reuse it.
 func (rb *ReadBuffer) Append(chunk []byte) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
-	if rb.buffer == nil {
-		rb.buffer = chunk
-	} else {
-		rb.buffer = append(rb.buffer, chunk...)
-	}
+	rb.buffer = append(rb.buffer, chunk...)
 }
 
 // ReadMessage reads a complete JSON-RPC message from the buffer.
 // Returns nil if no complete message is available.
 func (rb *ReadBuffer) ReadMessage() (*transport.BaseJsonRpcMessage, error) {
+	body, err := rb.readBody()
+	if body == nil || err != nil {
+		return nil, err
+	}
+	return deserializeMessage(string(body))
+}
+
+// ReadMessages reads a complete JSON-RPC message or batch of messages from the buffer.
+// The returned bool reports whether the messages were sent as a batch. Returns nil if no complete message is available.
+// A message, or element of a batch, that is not valid JSON-RPC is consumed and answered by one of the returned invalid
+// errors instead, see transport.NewInvalidMessageError.
+func (rb *ReadBuffer) ReadMessages() (messages []*transport.BaseJsonRpcMessage, invalid []*transport.BaseJsonRpcMessage, isBatch bool, err error) {
+	body, err := rb.readBody()
+	if body == nil || err != nil {
+		return nil, nil, false, err
+	}
+	messages, invalid, isBatch = deserializeMessages(body)
+	return messages, invalid, isBatch, nil
+}
+
+// readBody returns the body of the next complete message in the buffer, nil if there is none
+func (rb *ReadBuffer) readBody() ([]byte, error) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
-	if rb.buffer == nil {
-		return nil, nil
-	}
+	for {
+		if rb.framing == FramingAuto {
+			rb.framing = detectFraming(rb.buffer)
+			if rb.framing == FramingAuto {
+				return nil, nil
+			}
+		}
 
-	// Find newline
-	for i := 0; i < len(rb.buffer); i++ {
-		if rb.buffer[i] == '\n' {
-			// Extract line
-			line := string(rb.buffer[:i])
-			//println("read line: ", line)
-			rb.buffer = rb.buffer[i+1:]
-			return deserializeMessage(line)
+		var body []byte
+		var consumed int
+		var err error
+		if rb.framing == FramingContentLength {
+			body, consumed, err = readContentLengthMessage(rb.buffer)
+		} else {
+			body, consumed, err = readNewlineMessage(rb.buffer)
 		}
+		rb.buffer = rb.buffer[consumed:]
+		if err != nil {
+			return nil, err
+		}
+		if consumed == 0 {
+			return nil, nil
+		}
+		if body == nil {
+			// Blank line between messages
+			continue
+		}
+		return body, nil
 	}
-
-	return nil, nil
 }
 
 // Clear clears the buffer.
@@ -118,37 +166,47 @@ func (rb *ReadBuffer) Clear() {
 	rb.buffer = nil
 }
 
-// deserializeMessage deserializes a JSON-RPC message from a string.
-func deserializeMessage(line string) (*transport.BaseJsonRpcMessage, error) {
-	var request transport.BaseJSONRPCRequest
-	if err := json.Unmarshal([]byte(line), &request); err == nil {
-		//println("unmarshaled request:", spew.Sdump(request))
-		return transport.NewBaseMessageRequest(&request), nil
-	} else {
-		//println("unmarshaled request error:", err.Error())
+// deserializeMessages deserializes a JSON-RPC message or a batch (JSON array) of messages, along with the errors that
+// answer the invalid ones. The elements of a batch are deserialized one by one, so that an invalid element does not
+// invalidate the others. The returned bool reports whether body was a batch.
+func deserializeMessages(body []byte) ([]*transport.BaseJsonRpcMessage, []*transport.BaseJsonRpcMessage, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		message, err := deserializeMessage(string(trimmed))
+		if err != nil {
+			return nil, []*transport.BaseJsonRpcMessage{transport.NewInvalidMessageError(trimmed, err)}, false
+		}
+		return []*transport.BaseJsonRpcMessage{message}, nil, false
 	}
 
-	var notification transport.BaseJSONRPCNotification
-	if err := json.Unmarshal([]byte(line), &notification); err == nil {
-		return transport.NewBaseMessageNotification(&notification), nil
-	} else {
-		//println("unmarshaled notification error:", err.Error())
+	var elements []json.RawMessage
+	if err := json.Unmarshal(trimmed, &elements); err != nil {
+		err = fmt.Errorf("failed to unmarshal JSON-RPC batch: %w", err)
+		return nil, []*transport.BaseJsonRpcMessage{transport.NewInvalidMessageError(trimmed, err)}, false
 	}
-
-	var response transport.BaseJSONRPCResponse
-	if err := json.Unmarshal([]byte(line), &response); err == nil {
-		return transport.NewBaseMessageResponse(&response), nil
-	} else {
-		//println("unmarshaled response error:", err.Error())
+	if len(elements) == 0 {
+		// An empty batch is answered with a single error, not a batch
+		err := errors.New("JSON-RPC batch must not be empty")
+		return nil, []*transport.BaseJsonRpcMessage{transport.NewInvalidMessageError(trimmed, err)}, false
 	}
-
-	var errorResponse transport.BaseJSONRPCError
-	if err := json.Unmarshal([]byte(line), &errorResponse); err == nil {
-		return transport.NewBaseMessageError(&errorResponse), nil
-	} else {
-		//println("unmarshaled error response error:", err.Error())
+	var messages, invalid []*transport.BaseJsonRpcMessage
+	for i, element := range elements {
+		message, err := deserializeMessage(string(element))
+		if err != nil {
+			invalid = append(invalid, transport.NewInvalidMessageError(element, fmt.Errorf("batch element %d: %w", i, err)))
+			continue
+		}
+		messages = append(messages, message)
 	}
+	return messages, invalid, true
+}
 
-	// Must be a response
-	return nil, errors.New("failed to unmarshal JSON-RPC message, unrecognized type")
+// deserializeMessage deserializes a JSON-RPC message from a string.
+// Objects that are none of a request, a notification, a response or an error are rejected.
+func deserializeMessage(line string) (*transport.BaseJsonRpcMessage, error) {
+	var message transport.BaseJsonRpcMessage
+	if err := json.Unmarshal([]byte(line), &message); err != nil {
+		return nil, errors.New("failed to unmarshal JSON-RPC message, unrecognized type")
+	}
+	return &message, nil
 }
@@ -117,7 +117,7 @@ func TestMessageDeserialization(t *testing.T) {
 		assert.Equal(t, transport.BaseMessageTypeJSONRPCRequestType, msg.Type)
 		assert.Equal(t, "2.0", msg.JsonRpcRequest.Jsonrpc)
 		assert.Equal(t, "test", msg.JsonRpcRequest.Method)
-		assert.Equal(t, transport.RequestId(1), msg.JsonRpcRequest.Id)
+		assert.Equal(t, transport.NewRequestId(1), msg.JsonRpcRequest.Id)
 	})
 
 	t.Run("notification", func(t *testing.T) {
@@ -137,3 +137,37 @@ func TestMessageDeserialization(t *testing.T) {
 		assert.Equal(t, "Parse error", msg.JsonRpcError.Error.Message)
 	})
 }
+
+func TestBatchDeserialization(t *testing.T) {
+	t.Run("invalid elements do not invalidate the batch", func(t *testing.T) {
+		messages, invalid, isBatch := deserializeMessages([]byte(`[{"jsonrpc":"2.0","id":1,"method":"a"},{"jsonrpc":"2.0","id":2},5,{"jsonrpc":"2.0","method":"n"}]`))
+		assert.True(t, isBatch)
+		if assert.Len(t, messages, 2) {
+			assert.Equal(t, "a", messages[0].JsonRpcRequest.Method)
+			assert.Equal(t, "n", messages[1].JsonRpcNotification.Method)
+		}
+		if assert.Len(t, invalid, 2) {
+			assert.Equal(t, transport.ErrorCodeInvalidRequest, invalid[0].JsonRpcError.Error.Code)
+			assert.Equal(t, transport.NewRequestId(2), invalid[0].JsonRpcError.Id)
+			assert.Equal(t, transport.ErrorCodeInvalidRequest, invalid[1].JsonRpcError.Error.Code)
+			assert.True(t, invalid[1].JsonRpcError.Id.IsNull())
+		}
+	})
+
+	t.Run("batches without requests answer their invalid elements straight away", func(t *testing.T) {
+		messages, invalid, _ := deserializeMessages([]byte(`[{"jsonrpc":"2.0","method":"n"},{}]`))
+		assert.Len(t, invalid, 1)
+		assert.Equal(t, invalid, NewBatches().Add(messages, invalid))
+	})
+
+	t.Run("malformed and empty batches get a single error", func(t *testing.T) {
+		for body, code := range map[string]int{`[{"jsonrpc":`: transport.ErrorCodeParseError, `[]`: transport.ErrorCodeInvalidRequest} {
+			messages, invalid, isBatch := deserializeMessages([]byte(body))
+			assert.False(t, isBatch, body)
+			assert.Nil(t, messages, body)
+			if assert.Len(t, invalid, 1, body) {
+				assert.Equal(t, code, invalid[0].JsonRpcError.Error.Code, body)
+			}
+		}
+	})
+}
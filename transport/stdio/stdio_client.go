@@ -0,0 +1,356 @@
+package stdio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/metoro-io/mcp-golang/transport/stdio/internal/stdio"
+)
+
+// DefaultShutdownTimeout is how long Close waits at each step of the shutdown sequence
+// (after closing stdin, and after sending SIGTERM) before escalating.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// StdioClientTransport implements client-side transport for stdio communication.
+// It spawns the MCP server as a subprocess and talks to it over the subprocess's stdin and stdout.
+type StdioClientTransport struct {
+	mu sync.Mutex
+	// Serializes writes to stdin. It is separate from mu so that a write blocked on a full pipe does not stop readLoop
+	// from delivering messages, which the subprocess may be waiting on before it reads more of stdin.
+	writeMu         sync.Mutex
+	command         string
+	args            []string
+	env             []string
+	dir             string
+	stderr          io.Writer
+	shutdownTimeout time.Duration
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	readBuf *stdio.ReadBuffer
+	batches *stdio.Batches
+	started bool
+	closing bool
+	exited  chan struct{}
+	exitErr error
+
+	closeOnce sync.Once
+	onClose   func()
+	onError   func(error)
+	onMessage func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+}
+
+// NewStdioClientTransport creates a new StdioClientTransport that will run command with the given args when started.
+// env holds additional "KEY=VALUE" entries that are added to the current process environment,
+// and dir is the working directory of the subprocess (the current directory if empty).
+func NewStdioClientTransport(command string, args []string, env []string, dir string) *StdioClientTransport {
+	return &StdioClientTransport{
+		command:         command,
+		args:            args,
+		env:             env,
+		dir:             dir,
+		stderr:          os.Stderr,
+		shutdownTimeout: DefaultShutdownTimeout,
+		readBuf:         stdio.NewReadBufferWithFraming(stdio.FramingAuto),
+		batches:         stdio.NewBatches(),
+		exited:          make(chan struct{}),
+	}
+}
+
+// WithStderr sets where the subprocess's stderr is forwarded to. Defaults to os.Stderr.
+// Pass io.Discard to drop it.
+func (t *StdioClientTransport) WithStderr(w io.Writer) *StdioClientTransport {
+	t.stderr = w
+	return t
+}
+
+// WithShutdownTimeout sets how long Close waits for the subprocess to exit at each step
+// of the shutdown sequence before escalating. Defaults to DefaultShutdownTimeout.
+func (t *StdioClientTransport) WithShutdownTimeout(timeout time.Duration) *StdioClientTransport {
+	t.shutdownTimeout = timeout
+	return t
+}
+
+// WithFraming sets how messages are delimited on the stream. Defaults to FramingAuto, which sends newline-delimited
+// messages and follows the framing of the server's replies; use FramingContentLength for servers that only accept it.
+func (t *StdioClientTransport) WithFraming(framing Framing) *StdioClientTransport {
+	t.readBuf = stdio.NewReadBufferWithFraming(framing)
+	return t
+}
+
+// Start spawns the subprocess and begins listening for messages on its stdout
+func (t *StdioClientTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.started {
+		return fmt.Errorf("StdioClientTransport already started")
+	}
+
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Dir = t.dir
+	cmd.Env = append(os.Environ(), t.env...)
+	cmd.Stderr = t.stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", t.command, err)
+	}
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.stdout = stdout
+	t.started = true
+
+	go t.readLoop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.exited:
+		}
+	}()
+	return nil
+}
+
+// Close shuts the subprocess down gracefully. It closes the subprocess's stdin and waits for it to exit.
+// If it has not exited after the shutdown timeout it is sent SIGTERM, and if it still has not exited
+// after another shutdown timeout it is killed.
+func (t *StdioClientTransport) Close() error {
+	t.mu.Lock()
+	if !t.started {
+		t.mu.Unlock()
+		t.handleClose()
+		return nil
+	}
+	alreadyClosing := t.closing
+	t.closing = true
+	t.mu.Unlock()
+
+	if alreadyClosing {
+		<-t.exited
+		return nil
+	}
+
+	_ = t.stdin.Close()
+	if !t.waitForExit(t.shutdownTimeout) {
+		if err := t.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			// Not every platform supports SIGTERM, fall straight through to killing the process
+			_ = t.cmd.Process.Kill()
+		}
+		if !t.waitForExit(t.shutdownTimeout) {
+			_ = t.cmd.Process.Kill()
+		}
+	}
+	<-t.exited
+
+	t.handleClose()
+	return nil
+}
+
+// Send sends a JSON-RPC message to the subprocess's stdin
+// Responses to the requests of a batch are held back and sent as one batch once all of them have been sent.
+func (t *StdioClientTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	if responses, ok := t.batches.Collect(message); ok {
+		if responses == nil {
+			return nil
+		}
+		return t.SendBatch(ctx, responses)
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return t.write(data)
+}
+
+// SendBatch implements transport.BatchSender
+func (t *StdioClientTransport) SendBatch(ctx context.Context, messages []*transport.BaseJsonRpcMessage) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	return t.write(data)
+}
+
+func (t *StdioClientTransport) write(data []byte) error {
+	data = stdio.FrameMessage(t.readBuf.Framing(), data)
+
+	t.mu.Lock()
+	if !t.started {
+		t.mu.Unlock()
+		return fmt.Errorf("StdioClientTransport not started")
+	}
+	if t.closing {
+		t.mu.Unlock()
+		return fmt.Errorf("StdioClientTransport is closed")
+	}
+	stdin := t.stdin
+	t.mu.Unlock()
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := stdin.Write(data)
+	return err
+}
+
+// SetCloseHandler sets the handler for close events
+func (t *StdioClientTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onClose = handler
+}
+
+// SetErrorHandler sets the handler for error events
+func (t *StdioClientTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onError = handler
+}
+
+// SetMessageHandler sets the handler for incoming messages
+func (t *StdioClientTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMessage = handler
+}
+
+// ExitError returns the error the subprocess exited with, or nil if it is still running or exited cleanly.
+func (t *StdioClientTransport) ExitError() error {
+	select {
+	case <-t.exited:
+		return t.exitErr
+	default:
+		return nil
+	}
+}
+
+// readLoop reads from the subprocess's stdout until it is closed and then reaps the subprocess.
+// If the subprocess exits without Close having been called, the close handler is fired.
+func (t *StdioClientTransport) readLoop() {
+	buffer := make([]byte, 4096)
+	for {
+		n, err := t.stdout.Read(buffer)
+		if n > 0 {
+			t.readBuf.Append(buffer[:n])
+			t.processReadBuffer()
+		}
+		if err != nil {
+			if err != io.EOF {
+				t.handleError(fmt.Errorf("read error: %w", err))
+			}
+			break
+		}
+	}
+
+	// Wait must only be called once all reads from stdout have completed
+	t.exitErr = t.cmd.Wait()
+	t.readBuf.Clear()
+	close(t.exited)
+
+	t.mu.Lock()
+	closing := t.closing
+	t.closing = true
+	t.mu.Unlock()
+
+	if closing {
+		// Close is responsible for firing the close handler
+		return
+	}
+	if t.exitErr != nil {
+		t.handleError(fmt.Errorf("server process exited: %w", t.exitErr))
+	}
+	t.handleClose()
+}
+
+func (t *StdioClientTransport) waitForExit(timeout time.Duration) bool {
+	select {
+	case <-t.exited:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *StdioClientTransport) processReadBuffer() {
+	for {
+		messages, invalid, isBatch, err := t.readBuf.ReadMessages()
+		if err != nil {
+			t.handleError(err)
+			continue
+		}
+		if messages == nil && invalid == nil {
+			return
+		}
+		if isBatch {
+			// The errors of invalid elements are sent with the responses to the requests of the batch, if it has any
+			if responses := t.batches.Add(messages, invalid); len(responses) > 0 {
+				if err := t.SendBatch(context.Background(), responses); err != nil {
+					t.handleError(err)
+				}
+			}
+		}
+		for _, response := range invalid {
+			t.handleError(errors.New(response.JsonRpcError.Error.Message))
+			if !isBatch {
+				if err := t.Send(context.Background(), response); err != nil {
+					t.handleError(err)
+				}
+			}
+		}
+		for _, msg := range messages {
+			t.handleMessage(msg)
+		}
+	}
+}
+
+func (t *StdioClientTransport) handleClose() {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		handler := t.onClose
+		t.mu.Unlock()
+
+		if handler != nil {
+			handler()
+		}
+	})
+}
+
+func (t *StdioClientTransport) handleError(err error) {
+	t.mu.Lock()
+	handler := t.onError
+	t.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+func (t *StdioClientTransport) handleMessage(msg *transport.BaseJsonRpcMessage) {
+	t.mu.Lock()
+	handler := t.onMessage
+	t.mu.Unlock()
+
+	ctx := context.Background()
+
+	if handler != nil {
+		handler(ctx, msg)
+	}
+}
@@ -0,0 +1,245 @@
+package stdio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const helperProcessEnv = "MCP_GOLANG_STDIO_HELPER_PROCESS"
+
+// TestStdioClientHelperProcess is not a real test. It is run as the subprocess spawned by the
+// StdioClientTransport tests, and behaves according to the mode in helperProcessEnv.
+func TestStdioClientHelperProcess(t *testing.T) {
+	mode := os.Getenv(helperProcessEnv)
+	if mode == "" {
+		return
+	}
+
+	switch mode {
+	case "echo":
+		fmt.Fprintln(os.Stderr, "helper process started")
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			fmt.Println(scanner.Text())
+		}
+		os.Exit(0)
+	case "exit":
+		os.Exit(3)
+	case "stubborn":
+		// Ignore both stdin closing and SIGTERM so that only SIGKILL stops us
+		signal.Ignore(syscall.SIGTERM)
+		fmt.Println(`{"jsonrpc":"2.0","method":"ready"}`)
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	os.Exit(1)
+}
+
+func newHelperTransport(mode string) *StdioClientTransport {
+	return NewStdioClientTransport(
+		os.Args[0],
+		[]string{"-test.run=TestStdioClientHelperProcess"},
+		[]string{helperProcessEnv + "=" + mode},
+		"",
+	)
+}
+
+func TestStdioClientTransport(t *testing.T) {
+	t.Run("round trip and stderr forwarding", func(t *testing.T) {
+		stderr := &syncBuffer{}
+		tr := newHelperTransport("echo").WithStderr(stderr)
+
+		received := make(chan *transport.BaseJsonRpcMessage, 1)
+		tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+			received <- msg
+		})
+		closed := make(chan struct{})
+		tr.SetCloseHandler(func() {
+			close(closed)
+		})
+
+		require.NoError(t, tr.Start(context.Background()))
+
+		err := tr.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  "test",
+			Id:      transport.NewRequestId(7),
+		}))
+		require.NoError(t, err)
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, transport.BaseMessageTypeJSONRPCRequestType, msg.Type)
+			assert.Equal(t, "test", msg.JsonRpcRequest.Method)
+			assert.Equal(t, transport.NewRequestId(7), msg.JsonRpcRequest.Id)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for echoed message")
+		}
+
+		require.NoError(t, tr.Close())
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("close handler was not called")
+		}
+		assert.NoError(t, tr.ExitError())
+		assert.Contains(t, stderr.String(), "helper process started")
+
+		err = tr.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  "test",
+			Id:      transport.NewRequestId(8),
+		}))
+		assert.Error(t, err)
+	})
+
+	t.Run("large messages echoed back do not deadlock", func(t *testing.T) {
+		tr := newHelperTransport("echo").WithStderr(&syncBuffer{})
+
+		const count = 5
+		received := make(chan *transport.BaseJsonRpcMessage, count)
+		tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+			received <- msg
+		})
+		require.NoError(t, tr.Start(context.Background()))
+		defer tr.Close()
+
+		// Each message is far larger than the pipe buffers, so the subprocess is still writing the echo of one while
+		// the next is sent
+		payload := fmt.Sprintf(`{"data":%q}`, strings.Repeat("x", 1024*1024))
+		sent := make(chan error, 1)
+		go func() {
+			for i := 0; i < count; i++ {
+				err := tr.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+					Jsonrpc: "2.0",
+					Method:  "large",
+					Params:  []byte(payload),
+					Id:      transport.NewRequestId(int64(i)),
+				}))
+				if err != nil {
+					sent <- err
+					return
+				}
+			}
+			sent <- nil
+		}()
+
+		for i := 0; i < count; i++ {
+			select {
+			case msg := <-received:
+				assert.Equal(t, transport.NewRequestId(int64(i)), msg.JsonRpcRequest.Id)
+				assert.Len(t, msg.JsonRpcRequest.Params, len(payload))
+			case <-time.After(10 * time.Second):
+				t.Fatalf("timeout waiting for echoed message %d", i)
+			}
+		}
+		require.NoError(t, <-sent)
+	})
+
+	t.Run("process exit fires close handler", func(t *testing.T) {
+		tr := newHelperTransport("exit")
+
+		var receivedErr error
+		var mu sync.Mutex
+		tr.SetErrorHandler(func(err error) {
+			mu.Lock()
+			receivedErr = err
+			mu.Unlock()
+		})
+		closed := make(chan struct{})
+		tr.SetCloseHandler(func() {
+			close(closed)
+		})
+
+		require.NoError(t, tr.Start(context.Background()))
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("close handler was not called after the process exited")
+		}
+
+		mu.Lock()
+		assert.Error(t, receivedErr)
+		mu.Unlock()
+		assert.Error(t, tr.ExitError())
+		assert.NoError(t, tr.Close())
+	})
+
+	t.Run("close escalates to kill", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("signals are not supported on windows")
+		}
+		tr := newHelperTransport("stubborn").WithShutdownTimeout(100 * time.Millisecond)
+
+		ready := make(chan struct{}, 1)
+		tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+			ready <- struct{}{}
+		})
+		closeCount := 0
+		tr.SetCloseHandler(func() {
+			closeCount++
+		})
+
+		require.NoError(t, tr.Start(context.Background()))
+		select {
+		case <-ready:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for helper process")
+		}
+
+		start := time.Now()
+		require.NoError(t, tr.Close())
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Error(t, tr.ExitError())
+		assert.Equal(t, 1, closeCount)
+
+		require.NoError(t, tr.Close())
+		assert.Equal(t, 1, closeCount)
+	})
+
+	t.Run("double start error", func(t *testing.T) {
+		tr := newHelperTransport("echo").WithStderr(&syncBuffer{})
+		require.NoError(t, tr.Start(context.Background()))
+
+		err := tr.Start(context.Background())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "already started")
+
+		assert.NoError(t, tr.Close())
+	})
+}
+
+// syncBuffer is a bytes.Buffer that can be written to from the goroutine copying the subprocess's stderr
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -20,6 +21,7 @@ type StdioServerTransport struct {
 	reader    *bufio.Reader
 	writer    io.Writer
 	readBuf   *stdio.ReadBuffer
+	batches   *stdio.Batches
 	onClose   func()
 	onError   func(error)
 	onMessage func(ctx context.Context, message *transport.BaseJsonRpcMessage)
@@ -35,10 +37,17 @@ func NewStdioServerTransportWithIO(in io.Reader, out io.Writer) *StdioServerTran
 	return &StdioServerTransport{
 		reader:  bufio.NewReader(in),
 		writer:  out,
-		readBuf: stdio.NewReadBuffer(),
+		readBuf: stdio.NewReadBufferWithFraming(stdio.FramingAuto),
+		batches: stdio.NewBatches(),
 	}
 }
 
+// WithFraming sets how messages are delimited on the stream. Defaults to FramingAuto.
+func (t *StdioServerTransport) WithFraming(framing Framing) *StdioServerTransport {
+	t.readBuf = stdio.NewReadBufferWithFraming(framing)
+	return t
+}
+
 // Start begins listening for messages on stdin
 func (t *StdioServerTransport) Start(ctx context.Context) error {
 	t.mu.Lock()
@@ -67,19 +76,40 @@ func (t *StdioServerTransport) Close() error {
 }
 
 // Send sends a JSON-RPC message
+// Responses to the requests of a batch are held back and sent as one batch once all of them have been sent.
 func (t *StdioServerTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	if responses, ok := t.batches.Collect(message); ok {
+		if responses == nil {
+			return nil
+		}
+		return t.SendBatch(ctx, responses)
+	}
+
 	data, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
-	data = append(data, '\n')
+	return t.write(data)
+}
+
+// SendBatch implements transport.BatchSender
+func (t *StdioServerTransport) SendBatch(ctx context.Context, messages []*transport.BaseJsonRpcMessage) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	return t.write(data)
+}
+
+func (t *StdioServerTransport) write(data []byte) error {
+	data = stdio.FrameMessage(t.readBuf.Framing(), data)
 
 	//println("serialized message:", string(data))
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	_, err = t.writer.Write(data)
+	_, err := t.writer.Write(data)
 	return err
 }
 
@@ -135,18 +165,37 @@ func (t *StdioServerTransport) readLoop(ctx context.Context) {
 
 func (t *StdioServerTransport) processReadBuffer() {
 	for {
-		msg, err := t.readBuf.ReadMessage()
+		messages, invalid, isBatch, err := t.readBuf.ReadMessages()
 		if err != nil {
 			//println("error reading message:", err.Error())
+			// The broken message has been dropped, the messages after it can still be read
 			t.handleError(err)
-			return
+			continue
 		}
-		if msg == nil {
+		if messages == nil && invalid == nil {
 			//println("no message")
 			return
 		}
-		//println("received message:", spew.Sprint(msg))
-		t.handleMessage(msg)
+		//println("received message:", spew.Sprint(messages))
+		if isBatch {
+			// The errors of invalid elements are sent with the responses to the requests of the batch, if it has any
+			if responses := t.batches.Add(messages, invalid); len(responses) > 0 {
+				if err := t.SendBatch(context.Background(), responses); err != nil {
+					t.handleError(err)
+				}
+			}
+		}
+		for _, response := range invalid {
+			t.handleError(errors.New(response.JsonRpcError.Error.Message))
+			if !isBatch {
+				if err := t.Send(context.Background(), response); err != nil {
+					t.handleError(err)
+				}
+			}
+		}
+		for _, msg := range messages {
+			t.handleMessage(msg)
+		}
 	}
 }
 
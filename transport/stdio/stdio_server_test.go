@@ -1,8 +1,13 @@
 package stdio
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -56,7 +61,7 @@ func TestStdioServerTransport(t *testing.T) {
 		assert.True(t, ok)
 		assert.True(t, req.Type == transport.BaseMessageTypeJSONRPCRequestType)
 		assert.Equal(t, "test", req.JsonRpcRequest.Method)
-		assert.Equal(t, transport.RequestId(1), req.JsonRpcRequest.Id)
+		assert.Equal(t, transport.NewRequestId(1), req.JsonRpcRequest.Id)
 
 		err = tr.Close()
 		assert.NoError(t, err)
@@ -86,7 +91,7 @@ func TestStdioServerTransport(t *testing.T) {
 		msg := &transport.BaseJSONRPCResponse{
 			Jsonrpc: "2.0",
 			Result:  result,
-			Id:      1,
+			Id:      transport.NewRequestId(1),
 		}
 
 		err := tr.Send(context.Background(), transport.NewBaseMessageResponse(msg))
@@ -165,3 +170,215 @@ func TestStdioServerTransport(t *testing.T) {
 		assert.True(t, closed, "transport should be closed after context cancellation")
 	})
 }
+
+func TestStdioServerTransportFraming(t *testing.T) {
+	t.Run("replies in the framing detected on input", func(t *testing.T) {
+		// The input is written before Start, the read loop stops at the end of it
+		body := `{"jsonrpc": "2.0", "method": "test", "params": {}, "id": 1}`
+		in := bytes.NewBufferString(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body))
+		out := &bytes.Buffer{}
+		tr := NewStdioServerTransportWithIO(in, out)
+
+		received := make(chan *transport.BaseJsonRpcMessage, 1)
+		tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+			received <- msg
+		})
+		assert.NoError(t, tr.Start(context.Background()))
+		defer tr.Close()
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "test", msg.JsonRpcRequest.Method)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+
+		err := tr.Send(context.Background(), transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Result:  []byte(`{}`),
+			Id:      transport.NewRequestId(1),
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, "Content-Length: 36\r\n\r\n"+`{"id":1,"jsonrpc":"2.0","result":{}}`, out.String())
+	})
+
+	t.Run("recovers after a broken header block", func(t *testing.T) {
+		body := `{"jsonrpc": "2.0", "method": "test", "params": {}, "id": 1}`
+		in := bytes.NewBufferString(fmt.Sprintf("Content-Length: abc\r\n\r\nContent-Length: %d\r\n\r\n%s", len(body), body))
+		tr := NewStdioServerTransportWithIO(in, &bytes.Buffer{})
+
+		received := make(chan *transport.BaseJsonRpcMessage, 1)
+		tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+			received <- msg
+		})
+		errs := make(chan error, 1)
+		tr.SetErrorHandler(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		})
+		assert.NoError(t, tr.Start(context.Background()))
+		defer tr.Close()
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "test", msg.JsonRpcRequest.Method)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+		select {
+		case err := <-errs:
+			assert.Contains(t, err.Error(), "invalid Content-Length header")
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for error")
+		}
+	})
+
+	t.Run("fixed framing", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		tr := NewStdioServerTransportWithIO(&bytes.Buffer{}, out).WithFraming(FramingContentLength)
+
+		err := tr.Send(context.Background(), transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Result:  []byte(`{}`),
+			Id:      transport.NewRequestId(1),
+		}))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(out.String(), "Content-Length: 36\r\n\r\n"))
+	})
+}
+
+func TestStdioServerTransportInvalidMessages(t *testing.T) {
+	in := bytes.NewBufferString(`{"invalid json` + "\n" +
+		`{"jsonrpc":"2.0","id":7}` + "\n" +
+		`[]` + "\n" +
+		`{"jsonrpc":"2.0","id":8,"method":"test"}` + "\n")
+	outReader, out := io.Pipe()
+	defer outReader.Close()
+	tr := NewStdioServerTransportWithIO(in, out)
+
+	received := make(chan *transport.BaseJsonRpcMessage, 1)
+	tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+		received <- msg
+	})
+	assert.NoError(t, tr.Start(context.Background()))
+	defer tr.Close()
+
+	// Every invalid message is answered with its own error, and the messages after it are still read
+	scanner := bufio.NewScanner(outReader)
+	for _, expected := range []struct {
+		code int
+		id   string
+	}{
+		{transport.ErrorCodeParseError, "null"},
+		{transport.ErrorCodeInvalidRequest, "7"},
+		{transport.ErrorCodeInvalidRequest, "null"},
+	} {
+		if !scanner.Scan() {
+			t.Fatalf("missing error response: %v", scanner.Err())
+		}
+		var response struct {
+			Id    json.RawMessage `json:"id"`
+			Error struct {
+				Code int `json:"code"`
+			} `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &response))
+		assert.Equal(t, expected.code, response.Error.Code, scanner.Text())
+		assert.Equal(t, expected.id, string(response.Id), scanner.Text())
+	}
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "test", msg.JsonRpcRequest.Method)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+func TestStdioServerTransportBatchWithInvalidElements(t *testing.T) {
+	in := bytes.NewBufferString(`[{"jsonrpc":"2.0","id":1,"method":"a"},{"jsonrpc":"2.0","id":2},{"jsonrpc":"2.0","method":"n"}]` + "\n")
+	out := &bytes.Buffer{}
+	tr := NewStdioServerTransportWithIO(in, out)
+
+	received := make(chan *transport.BaseJsonRpcMessage, 2)
+	tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+		received <- msg
+	})
+	assert.NoError(t, tr.Start(context.Background()))
+	defer tr.Close()
+
+	// The valid elements are delivered
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+
+	// The invalid element is answered in the batch of responses
+	err := tr.Send(context.Background(), transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+		Jsonrpc: "2.0",
+		Result:  []byte(`{}`),
+		Id:      transport.NewRequestId(1),
+	}))
+	assert.NoError(t, err)
+	var responses []*transport.BaseJsonRpcMessage
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &responses))
+	if assert.Len(t, responses, 2) {
+		assert.Equal(t, transport.ErrorCodeInvalidRequest, responses[0].JsonRpcError.Error.Code)
+		assert.Equal(t, transport.NewRequestId(2), responses[0].JsonRpcError.Id)
+		assert.Equal(t, transport.NewRequestId(1), responses[1].JsonRpcResponse.Id)
+	}
+}
+
+func TestStdioServerTransportBatch(t *testing.T) {
+	in := bytes.NewBufferString(`[{"jsonrpc":"2.0","id":1,"method":"a"},{"jsonrpc":"2.0","method":"n"},{"jsonrpc":"2.0","id":2,"method":"b"}]` + "\n")
+	out := &bytes.Buffer{}
+	tr := NewStdioServerTransportWithIO(in, out)
+
+	received := make(chan *transport.BaseJsonRpcMessage, 3)
+	tr.SetMessageHandler(func(ctx context.Context, msg *transport.BaseJsonRpcMessage) {
+		received <- msg
+	})
+	assert.NoError(t, tr.Start(context.Background()))
+	defer tr.Close()
+
+	// The messages of the batch are delivered one at a time
+	var methods []string
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-received:
+			if msg.Type == transport.BaseMessageTypeJSONRPCRequestType {
+				methods = append(methods, msg.JsonRpcRequest.Method)
+			} else {
+				methods = append(methods, msg.JsonRpcNotification.Method)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+	assert.Equal(t, []string{"a", "n", "b"}, methods)
+
+	// The responses are held back until the whole batch has been answered
+	err := tr.Send(context.Background(), transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+		Jsonrpc: "2.0",
+		Result:  []byte(`{}`),
+		Id:      transport.NewRequestId(2),
+	}))
+	assert.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	err = tr.Send(context.Background(), transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+		Jsonrpc: "2.0",
+		Error:   transport.BaseJSONRPCErrorInner{Code: -32601, Message: "method not found"},
+		Id:      transport.NewRequestId(1),
+	}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id":2,"jsonrpc":"2.0","result":{}},
+		{"id":1,"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"}}
+	]`, out.String())
+}
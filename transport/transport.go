@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"errors"
 )
 
 // Transport describes the minimal contract for a MCP transport that a client or server can communicate over.
@@ -32,3 +33,29 @@ type Transport interface {
 	// Partially deserializes the messages to pass a BaseJsonRpcMessage
 	SetMessageHandler(handler func(ctx context.Context, message *BaseJsonRpcMessage))
 }
+
+// Shutdowner is implemented by transports that can close gracefully, delivering the messages that have already been
+// sent before the connection is closed. If ctx is done first, the connection is closed straight away.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// BatchSender is implemented by transports that can send several messages as one JSON-RPC batch. Transports that
+// receive a batch deliver its messages to the message handler one at a time and send the responses to its requests back
+// as one batch.
+type BatchSender interface {
+	SendBatch(ctx context.Context, messages []*BaseJsonRpcMessage) error
+}
+
+// ErrListenerClosed is returned by Listener.Accept once the listener has been closed.
+var ErrListenerClosed = errors.New("listener closed")
+
+// Listener describes a source of connections for a server that serves many clients at once, each over its own Transport.
+type Listener interface {
+	// Accept blocks until a client connects and returns a transport for the connection, which has not been started yet.
+	// It returns ErrListenerClosed once the listener has been closed.
+	Accept() (Transport, error)
+
+	// Close stops the listener. Connections that have already been accepted are not closed.
+	Close() error
+}
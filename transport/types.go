@@ -1,13 +1,112 @@
 package transport
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
 )
 
 type JSONRPCMessage interface{}
 
-type RequestId int64
+// RequestId is the id of a JSON-RPC request, either a number or a string. Ids round-trip exactly in the form the peer
+// sent them, so 1 and "1" are different ids. The zero value is the number 0. Errors that answer a message whose id could
+// not be read have a null id, see NullRequestId.
+//
+// RequestId is comparable and can be used as a map key.
+type RequestId struct {
+	num      int64
+	str      string
+	isString bool
+	isNull   bool
+}
+
+// NewRequestId returns a numeric request id
+func NewRequestId(id int64) RequestId {
+	return RequestId{num: id}
+}
+
+// NewStringRequestId returns a string request id
+func NewStringRequestId(id string) RequestId {
+	return RequestId{str: id, isString: true}
+}
+
+// NullRequestId returns the null id of the errors that answer a message whose id could not be read
+func NullRequestId() RequestId {
+	return RequestId{isNull: true}
+}
+
+// IsNull reports whether the id is null
+func (id RequestId) IsNull() bool {
+	return id.isNull
+}
+
+// IsString reports whether the id is a string
+func (id RequestId) IsString() bool {
+	return id.isString
+}
+
+// Int64 returns the value of a numeric id, and false for a string id
+func (id RequestId) Int64() (int64, bool) {
+	return id.num, !id.isString
+}
+
+// String returns the id as it appears in JSON, with string ids quoted
+func (id RequestId) String() string {
+	if id.isNull {
+		return "null"
+	}
+	if id.isString {
+		return strconv.Quote(id.str)
+	}
+	return strconv.FormatInt(id.num, 10)
+}
+
+// LogValue implements slog.LogValuer, ids are logged as a plain number or string
+func (id RequestId) LogValue() slog.Value {
+	if id.isNull {
+		return slog.AnyValue(nil)
+	}
+	if id.isString {
+		return slog.StringValue(id.str)
+	}
+	return slog.Int64Value(id.num)
+}
+
+func (id RequestId) MarshalJSON() ([]byte, error) {
+	if id.isNull {
+		return []byte("null"), nil
+	}
+	if id.isString {
+		return json.Marshal(id.str)
+	}
+	return []byte(strconv.FormatInt(id.num, 10)), nil
+}
+
+// UnmarshalJSON accepts a string, an integer or null
+func (id *RequestId) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*id = NullRequestId()
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*id = NewStringRequestId(str)
+		return nil
+	}
+	num, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("request id must be a string or an integer, got %s", data)
+	}
+	*id = NewRequestId(num)
+	return nil
+}
 
 type BaseJSONRPCErrorInner struct {
 	// The error type that occurred.
@@ -98,9 +197,10 @@ type BaseJSONRPCNotification struct {
 // Requires a Jsonrpc and Method
 func (m *BaseJSONRPCNotification) UnmarshalJSON(data []byte) error {
 	required := struct {
-		Jsonrpc *string `json:"jsonrpc" yaml:"jsonrpc" mapstructure:"jsonrpc"`
-		Method  *string `json:"method" yaml:"method" mapstructure:"method"`
-		Id      *int64  `json:"id" yaml:"id" mapstructure:"id"`
+		Jsonrpc *string          `json:"jsonrpc" yaml:"jsonrpc" mapstructure:"jsonrpc"`
+		Method  *string          `json:"method" yaml:"method" mapstructure:"method"`
+		Id      *int64           `json:"id" yaml:"id" mapstructure:"id"`
+		Params  *json.RawMessage `json:"params" yaml:"params" mapstructure:"params"`
 	}{}
 	err := json.Unmarshal(data, &required)
 	if err != nil {
@@ -115,8 +215,12 @@ func (m *BaseJSONRPCNotification) UnmarshalJSON(data []byte) error {
 	if required.Id != nil {
 		return errors.New("field id in BaseJSONRPCNotification: not allowed")
 	}
+	if required.Params == nil {
+		required.Params = new(json.RawMessage)
+	}
 	m.Jsonrpc = *required.Jsonrpc
 	m.Method = *required.Method
+	m.Params = *required.Params
 	return nil
 }
 
@@ -193,6 +297,42 @@ func (m *BaseJsonRpcMessage) MarshalJSON() ([]byte, error) {
 	}
 }
 
+// Custom message unmarshaling
+// The type of the message is determined by trying, in order, a request, a notification, a response and an error
+func (m *BaseJsonRpcMessage) UnmarshalJSON(data []byte) error {
+	var request BaseJSONRPCRequest
+	if err := json.Unmarshal(data, &request); err == nil {
+		*m = *NewBaseMessageRequest(&request)
+		return nil
+	}
+
+	var notification BaseJSONRPCNotification
+	if err := json.Unmarshal(data, &notification); err == nil {
+		*m = *NewBaseMessageNotification(&notification)
+		return nil
+	}
+
+	var response BaseJSONRPCResponse
+	if err := json.Unmarshal(data, &response); err == nil {
+		*m = *NewBaseMessageResponse(&response)
+		return nil
+	}
+
+	// BaseJSONRPCError has no required fields of its own, so make sure there is an error member before accepting it
+	errorMember := struct {
+		Error *json.RawMessage `json:"error"`
+	}{}
+	if err := json.Unmarshal(data, &errorMember); err == nil && errorMember.Error != nil {
+		var errorResponse BaseJSONRPCError
+		if err := json.Unmarshal(data, &errorResponse); err == nil {
+			*m = *NewBaseMessageError(&errorResponse)
+			return nil
+		}
+	}
+
+	return errors.New("failed to unmarshal JSON-RPC message, unrecognized type")
+}
+
 func NewBaseMessageNotification(notification *BaseJSONRPCNotification) *BaseJsonRpcMessage {
 	return &BaseJsonRpcMessage{
 		Type:                BaseMessageTypeJSONRPCNotificationType,
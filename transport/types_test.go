@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestId(t *testing.T) {
+	t.Run("round trips numbers and strings exactly", func(t *testing.T) {
+		for _, raw := range []string{`0`, `42`, `-7`, `9007199254740993`, `"abc-1"`, `"1"`, `""`} {
+			var id RequestId
+			require.NoError(t, json.Unmarshal([]byte(raw), &id), raw)
+			data, err := json.Marshal(id)
+			require.NoError(t, err)
+			assert.Equal(t, raw, string(data))
+		}
+	})
+
+	t.Run("numbers and strings are different ids", func(t *testing.T) {
+		assert.NotEqual(t, NewRequestId(1), NewStringRequestId("1"))
+		assert.Equal(t, NewRequestId(0), RequestId{})
+
+		ids := map[RequestId]string{NewRequestId(1): "number", NewStringRequestId("1"): "string"}
+		assert.Len(t, ids, 2)
+		assert.Equal(t, `"1"`, NewStringRequestId("1").String())
+		assert.Equal(t, `1`, NewRequestId(1).String())
+	})
+
+	t.Run("null ids round trip", func(t *testing.T) {
+		var id RequestId
+		require.NoError(t, json.Unmarshal([]byte(`null`), &id))
+		assert.True(t, id.IsNull())
+		assert.NotEqual(t, NewRequestId(0), id)
+		data, err := json.Marshal(id)
+		require.NoError(t, err)
+		assert.Equal(t, `null`, string(data))
+	})
+
+	t.Run("fractional and other ids are rejected", func(t *testing.T) {
+		for _, raw := range []string{`1.5`, `true`, `{}`, `[1]`} {
+			var id RequestId
+			assert.Error(t, json.Unmarshal([]byte(raw), &id), raw)
+		}
+	})
+
+	t.Run("string ids in messages", func(t *testing.T) {
+		var message BaseJsonRpcMessage
+		require.NoError(t, json.Unmarshal([]byte(`{"jsonrpc":"2.0","id":"abc-1","method":"ping"}`), &message))
+		require.Equal(t, BaseMessageTypeJSONRPCRequestType, message.Type)
+		assert.Equal(t, NewStringRequestId("abc-1"), message.JsonRpcRequest.Id)
+		assert.True(t, message.JsonRpcRequest.Id.IsString())
+
+		data, err := json.Marshal(NewBaseMessageResponse(&BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  json.RawMessage(`{}`),
+		}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"abc-1","result":{}}`, string(data))
+	})
+}
@@ -0,0 +1,316 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+const (
+	// Subprotocol is the WebSocket subprotocol negotiated by both sides of the transport
+	Subprotocol = "mcp"
+	// DefaultPingInterval is how often a ping is sent to the peer
+	DefaultPingInterval = 30 * time.Second
+	// DefaultMaxMessageSize is the largest message accepted from the peer
+	DefaultMaxMessageSize = 4 * 1024 * 1024 // 4MB
+
+	writeWait = 10 * time.Second
+)
+
+// CloseError is reported to the error handler when the peer closes the connection with a code other than
+// normal closure or going away
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// WebSocketTransport implements a transport over a single WebSocket connection. Each text frame carries one
+// JSON-RPC message.
+//
+// The transport sends a ping every ping interval and closes the connection if the peer has not answered within two
+// intervals. When the connection ends the close handler is called; if the peer closed it with an error code the error
+// handler is called with a *CloseError first.
+type WebSocketTransport struct {
+	conn *ws.Conn
+
+	// Client side only, used to dial the connection in Start
+	url     string
+	headers http.Header
+	dialer  *ws.Dialer
+
+	pingInterval   time.Duration
+	maxMessageSize int64
+
+	messageHandler func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	errorHandler   func(error)
+	closeHandler   func()
+	mu             sync.RWMutex
+	writeMu        sync.Mutex
+
+	started   bool
+	closeErr  *CloseError
+	done      chan struct{}
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newWebSocketTransport(conn *ws.Conn) *WebSocketTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebSocketTransport{
+		conn:           conn,
+		headers:        make(http.Header),
+		pingInterval:   DefaultPingInterval,
+		maxMessageSize: DefaultMaxMessageSize,
+		done:           make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// WithPingInterval sets how often a ping is sent to the peer. Zero disables keepalives.
+func (t *WebSocketTransport) WithPingInterval(interval time.Duration) *WebSocketTransport {
+	t.pingInterval = interval
+	return t
+}
+
+// WithMaxMessageSize sets the largest message accepted from the peer. A larger message closes the connection.
+func (t *WebSocketTransport) WithMaxMessageSize(size int64) *WebSocketTransport {
+	t.maxMessageSize = size
+	return t
+}
+
+// Start implements Transport.Start
+// On the client side it dials the server. The transport is closed when ctx is done.
+func (t *WebSocketTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	if t.started {
+		t.mu.Unlock()
+		return fmt.Errorf("websocket transport already started")
+	}
+	t.started = true
+	t.mu.Unlock()
+
+	if t.conn == nil {
+		conn, err := t.dial(ctx)
+		t.mu.Lock()
+		if err != nil {
+			t.started = false
+			t.mu.Unlock()
+			return err
+		}
+		t.conn = conn
+		t.mu.Unlock()
+	}
+
+	t.conn.SetReadLimit(t.maxMessageSize)
+	if t.pingInterval > 0 {
+		_ = t.conn.SetReadDeadline(time.Now().Add(2 * t.pingInterval))
+		t.conn.SetPongHandler(func(string) error {
+			return t.conn.SetReadDeadline(time.Now().Add(2 * t.pingInterval))
+		})
+		go t.pingLoop()
+	}
+
+	go t.readLoop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-t.done:
+		}
+	}()
+	return nil
+}
+
+// Send implements Transport.Send
+func (t *WebSocketTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	select {
+	case <-t.done:
+		return errors.New("websocket transport closed")
+	default:
+	}
+	if !t.isStarted() {
+		return errors.New("websocket transport not started")
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_ = t.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := t.conn.WriteMessage(ws.TextMessage, data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// Close implements Transport.Close
+// A normal closure frame is sent to the peer before the connection is closed.
+func (t *WebSocketTransport) Close() error {
+	if t.isStarted() {
+		t.writeMu.Lock()
+		_ = t.conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""), time.Now().Add(writeWait))
+		t.writeMu.Unlock()
+	}
+	t.finish()
+	return nil
+}
+
+// Done returns a channel that is closed when the connection has ended
+func (t *WebSocketTransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// CloseError returns the close frame sent by the peer if it closed the connection with an error code, nil otherwise
+func (t *WebSocketTransport) CloseError() *CloseError {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.closeErr
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (t *WebSocketTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (t *WebSocketTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorHandler = handler
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (t *WebSocketTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageHandler = handler
+}
+
+func (t *WebSocketTransport) readLoop() {
+	defer t.finish()
+
+	for {
+		messageType, data, err := t.conn.ReadMessage()
+		if err != nil {
+			t.handleReadError(err)
+			return
+		}
+		// Any traffic shows the peer is alive
+		if t.pingInterval > 0 {
+			_ = t.conn.SetReadDeadline(time.Now().Add(2 * t.pingInterval))
+		}
+
+		if messageType != ws.TextMessage {
+			t.handleError(errors.New("received binary message, messages must be sent as text frames"))
+			continue
+		}
+
+		var message transport.BaseJsonRpcMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.handleError(fmt.Errorf("failed to unmarshal message: %w", err))
+			continue
+		}
+
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			handler(t.ctx, &message)
+		}
+	}
+}
+
+func (t *WebSocketTransport) handleReadError(err error) {
+	select {
+	case <-t.done:
+		// Closed locally
+		return
+	default:
+	}
+
+	var closeErr *ws.CloseError
+	if errors.As(err, &closeErr) {
+		if closeErr.Code == ws.CloseNormalClosure || closeErr.Code == ws.CloseGoingAway {
+			return
+		}
+		e := &CloseError{Code: closeErr.Code, Reason: closeErr.Text}
+		t.mu.Lock()
+		t.closeErr = e
+		t.mu.Unlock()
+		t.handleError(e)
+		return
+	}
+	t.handleError(fmt.Errorf("failed to read message: %w", err))
+}
+
+func (t *WebSocketTransport) pingLoop() {
+	ticker := time.NewTicker(t.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.writeMu.Lock()
+			err := t.conn.WriteControl(ws.PingMessage, nil, time.Now().Add(writeWait))
+			t.writeMu.Unlock()
+			if err != nil {
+				t.finish()
+				return
+			}
+		}
+	}
+}
+
+// finish closes the connection and calls the close handler, once
+func (t *WebSocketTransport) finish() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		t.cancel()
+		if t.conn != nil {
+			t.conn.Close()
+		}
+
+		t.mu.RLock()
+		handler := t.closeHandler
+		t.mu.RUnlock()
+		if handler != nil {
+			handler()
+		}
+	})
+}
+
+func (t *WebSocketTransport) isStarted() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.started && t.conn != nil
+}
+
+func (t *WebSocketTransport) handleError(err error) {
+	t.mu.RLock()
+	handler := t.errorHandler
+	t.mu.RUnlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
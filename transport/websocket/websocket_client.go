@@ -0,0 +1,46 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// NewWebSocketClientTransport creates a new WebSocket transport that connects to the server at url,
+// e.g. "ws://localhost:8080/ws". The connection is dialed when the transport is started.
+func NewWebSocketClientTransport(url string) *WebSocketTransport {
+	t := newWebSocketTransport(nil)
+	t.url = url
+	t.dialer = &ws.Dialer{
+		Proxy:            ws.DefaultDialer.Proxy,
+		HandshakeTimeout: ws.DefaultDialer.HandshakeTimeout,
+		Subprotocols:     []string{Subprotocol},
+	}
+	return t
+}
+
+// WithHeader adds a header to the opening handshake
+func (t *WebSocketTransport) WithHeader(key, value string) *WebSocketTransport {
+	t.headers.Set(key, value)
+	return t
+}
+
+func (t *WebSocketTransport) dial(ctx context.Context) (*ws.Conn, error) {
+	if t.dialer == nil {
+		return nil, fmt.Errorf("websocket transport has no connection")
+	}
+
+	conn, resp, err := t.dialer.DialContext(ctx, t.url, t.headers)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect (status: %d): %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	if conn.Subprotocol() != Subprotocol {
+		conn.Close()
+		return nil, fmt.Errorf("server did not negotiate the %q subprotocol", Subprotocol)
+	}
+	return conn, nil
+}
@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// WebSocketHandler is an http.Handler that upgrades every request to a WebSocket connection and hands a fresh
+// WebSocketTransport for it to a callback. The callback typically creates a new Server for the connection and serves it:
+//
+//	handler := websocket.NewWebSocketHandler(func(t *websocket.WebSocketTransport) {
+//		server := mcp_golang.NewServer(t)
+//		// Register tools, prompts and resources
+//		if err := server.Serve(); err != nil {
+//			log.Println(err)
+//		}
+//	})
+//	http.Handle("/ws", handler)
+//
+// To share one Server between all connections, call Server.ServeSession with the transport instead.
+//
+// The callback must start the transport before it returns, the connection is closed otherwise. ServeHTTP returns once
+// the connection has ended.
+type WebSocketHandler struct {
+	onConnect      func(t *WebSocketTransport)
+	upgrader       ws.Upgrader
+	pingInterval   time.Duration
+	maxMessageSize int64
+}
+
+// NewWebSocketHandler creates a new WebSocketHandler that calls onConnect for every new connection
+func NewWebSocketHandler(onConnect func(t *WebSocketTransport)) *WebSocketHandler {
+	return &WebSocketHandler{
+		onConnect: onConnect,
+		upgrader: ws.Upgrader{
+			Subprotocols: []string{Subprotocol},
+		},
+		pingInterval:   DefaultPingInterval,
+		maxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// WithPingInterval sets how often a ping is sent to each client. Zero disables keepalives.
+func (h *WebSocketHandler) WithPingInterval(interval time.Duration) *WebSocketHandler {
+	h.pingInterval = interval
+	return h
+}
+
+// WithMaxMessageSize sets the largest message accepted from a client
+func (h *WebSocketHandler) WithMaxMessageSize(size int64) *WebSocketHandler {
+	h.maxMessageSize = size
+	return h
+}
+
+// WithCheckOrigin sets the function that decides whether to accept a request from a browser based on its Origin header.
+// By default only requests from the same host are accepted.
+func (h *WebSocketHandler) WithCheckOrigin(checkOrigin func(r *http.Request) bool) *WebSocketHandler {
+	h.upgrader.CheckOrigin = checkOrigin
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Clients that ask for subprotocols must ask for ours
+	if offered := ws.Subprotocols(r); len(offered) > 0 && !slices.Contains(offered, Subprotocol) {
+		http.Error(w, "unsupported subprotocol, expected "+Subprotocol, http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied to the client
+		return
+	}
+
+	t := newWebSocketTransport(conn).
+		WithPingInterval(h.pingInterval).
+		WithMaxMessageSize(h.maxMessageSize)
+	h.onConnect(t)
+
+	if !t.isStarted() {
+		_ = conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseInternalServerErr, "server failed to start"), time.Now().Add(writeWait))
+		t.finish()
+		return
+	}
+	<-t.Done()
+}
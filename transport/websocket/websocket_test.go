@@ -0,0 +1,180 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEchoWebSocketServer serves a WebSocketHandler whose connections answer every request with its method name
+func newEchoWebSocketServer(t *testing.T, configure func(h *WebSocketHandler)) (*httptest.Server, string) {
+	handler := NewWebSocketHandler(func(tr *WebSocketTransport) {
+		tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+			if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+				return
+			}
+			request := message.JsonRpcRequest
+			if request.Method == "fail" {
+				tr.writeMu.Lock()
+				_ = tr.conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(4000, "failed on purpose"), time.Now().Add(time.Second))
+				tr.writeMu.Unlock()
+				return
+			}
+			result, _ := json.Marshal(map[string]string{"method": request.Method})
+			err := tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      request.Id,
+				Result:  result,
+			}))
+			assert.NoError(t, err)
+		})
+		assert.NoError(t, tr.Start(context.Background()))
+	})
+	if configure != nil {
+		configure(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func newRequest(method string, id int64) *transport.BaseJsonRpcMessage {
+	return transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Id:      transport.NewRequestId(id),
+	})
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		_, url := newEchoWebSocketServer(t, func(h *WebSocketHandler) {
+			h.WithPingInterval(10 * time.Millisecond)
+		})
+
+		client := NewWebSocketClientTransport(url).WithPingInterval(10 * time.Millisecond)
+		received := make(chan *transport.BaseJsonRpcMessage, 1)
+		client.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+			received <- message
+		})
+		require.NoError(t, client.Start(context.Background()))
+		defer client.Close()
+		assert.Equal(t, Subprotocol, client.conn.Subprotocol())
+
+		// Several keepalive rounds pass before the request
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, client.Send(context.Background(), newRequest("hello", 1)))
+
+		select {
+		case message := <-received:
+			require.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, message.Type)
+			assert.Equal(t, transport.NewRequestId(1), message.JsonRpcResponse.Id)
+			assert.JSONEq(t, `{"method":"hello"}`, string(message.JsonRpcResponse.Result))
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the response")
+		}
+	})
+
+	t.Run("close codes are reported before the close handler", func(t *testing.T) {
+		_, url := newEchoWebSocketServer(t, nil)
+
+		client := NewWebSocketClientTransport(url)
+		errs := make(chan error, 1)
+		client.SetErrorHandler(func(err error) { errs <- err })
+		closed := make(chan struct{})
+		client.SetCloseHandler(func() { close(closed) })
+		require.NoError(t, client.Start(context.Background()))
+
+		require.NoError(t, client.Send(context.Background(), newRequest("fail", 1)))
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("transport was not closed")
+		}
+		var closeErr *CloseError
+		require.ErrorAs(t, <-errs, &closeErr)
+		assert.Equal(t, 4000, closeErr.Code)
+		assert.Equal(t, "failed on purpose", closeErr.Reason)
+		assert.Equal(t, closeErr, client.CloseError())
+		assert.Error(t, client.Send(context.Background(), newRequest("hello", 2)))
+	})
+
+	t.Run("normal closure only calls the close handler", func(t *testing.T) {
+		serverClosed := make(chan struct{})
+		var serverErr error
+		handler := NewWebSocketHandler(func(tr *WebSocketTransport) {
+			tr.SetErrorHandler(func(err error) { serverErr = err })
+			tr.SetCloseHandler(func() { close(serverClosed) })
+			assert.NoError(t, tr.Start(context.Background()))
+		})
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		client := NewWebSocketClientTransport("ws" + strings.TrimPrefix(server.URL, "http"))
+		require.NoError(t, client.Start(context.Background()))
+		require.NoError(t, client.Close())
+
+		select {
+		case <-serverClosed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("server transport was not closed")
+		}
+		assert.NoError(t, serverErr)
+	})
+
+	t.Run("messages over the size limit close the connection", func(t *testing.T) {
+		_, url := newEchoWebSocketServer(t, func(h *WebSocketHandler) {
+			h.WithMaxMessageSize(64)
+		})
+
+		client := NewWebSocketClientTransport(url)
+		closed := make(chan struct{})
+		client.SetCloseHandler(func() { close(closed) })
+		require.NoError(t, client.Start(context.Background()))
+
+		require.NoError(t, client.Send(context.Background(), newRequest(strings.Repeat("x", 100), 1)))
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("transport was not closed")
+		}
+		require.NotNil(t, client.CloseError())
+		assert.Equal(t, ws.CloseMessageTooBig, client.CloseError().Code)
+	})
+
+	t.Run("subprotocol negotiation", func(t *testing.T) {
+		_, url := newEchoWebSocketServer(t, nil)
+
+		dialer := &ws.Dialer{Subprotocols: []string{"other"}}
+		_, resp, err := dialer.Dial(url, nil)
+		require.Error(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		// A server that does not negotiate the subprotocol is rejected by the client
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := ws.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err == nil {
+				conn.Close()
+			}
+		}))
+		defer plain.Close()
+
+		client := NewWebSocketClientTransport("ws" + strings.TrimPrefix(plain.URL, "http"))
+		err = client.Start(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "subprotocol")
+	})
+}